@@ -0,0 +1,186 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package adaptive provides a concurrency limiter adjusting its limit from the latency and the errors observed,
+// so the concurrency follows what the dependency is able to handle as the load shifts, instead of being fixed in advance.
+//
+//	limiter := adaptive.New(adaptive.Gradient(), adaptive.WithMaxLimit(200))
+//	release, err := limiter.Acquire(ctx)
+//	if err != nil {
+//		return err
+//	}
+//	err = client.Call(ctx)
+//	release(err)
+//
+// The Limiter implements async.ConcurrencyLimiter, so it can gate the jobs executed by an async.Pool:
+//
+//	pool := async.NewPool(100, 1000, async.WithConcurrencyLimiter(limiter))
+package adaptive
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+)
+
+// Sample is the outcome of a call, given to the Algorithm to compute the new limit.
+type Sample struct {
+	// RTT is the duration of the call.
+	RTT time.Duration
+	// InFlight is the number of calls running when this one ended, including it.
+	InFlight int
+	// Dropped is true when the call failed, which is considered as a sign of overload.
+	Dropped bool
+}
+
+// Algorithm computes the new limit of a Limiter each time a call ends. It is called with the mutex of the Limiter held, so it doesn't need to be safe for concurrent use.
+type Algorithm interface {
+	Update(limit float64, sample Sample) float64
+}
+
+// Option configures a Limiter.
+type Option func(l *Limiter)
+
+// WithInitialLimit sets the limit used before any call ended. By default, it is 10.
+func WithInitialLimit(limit int) Option {
+	return func(l *Limiter) {
+		l.limit = float64(limit)
+	}
+}
+
+// WithMinLimit sets the lowest limit the Algorithm can set. By default, it is 1.
+func WithMinLimit(limit int) Option {
+	return func(l *Limiter) {
+		l.minLimit = float64(limit)
+	}
+}
+
+// WithMaxLimit sets the highest limit the Algorithm can set. By default, it is 1000.
+func WithMaxLimit(limit int) Option {
+	return func(l *Limiter) {
+		l.maxLimit = float64(limit)
+	}
+}
+
+// Limiter caps the number of calls running at the same time, the cap being adjusted by an Algorithm after each call. It is safe for concurrent use.
+type Limiter struct {
+	algorithm Algorithm
+	mutex     sync.Mutex
+	limit     float64
+	minLimit  float64
+	maxLimit  float64
+	inFlight  int
+	// changed is closed, then replaced, each time a call ends or the limit changes, to wake up the callers waiting for room.
+	changed chan struct{}
+}
+
+// New returns a Limiter whose limit is adjusted by the given Algorithm, see AIMD and Gradient.
+func New(algorithm Algorithm, opts ...Option) *Limiter {
+	l := &Limiter{
+		algorithm: algorithm,
+		limit:     10,
+		minLimit:  1,
+		maxLimit:  1000,
+		changed:   make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if l.minLimit < 1 {
+		l.minLimit = 1
+	}
+	l.limit = l.bound(l.limit)
+	return l
+}
+
+// bound keeps the limit between the minimum and the maximum.
+func (l *Limiter) bound(limit float64) float64 {
+	return math.Max(l.minLimit, math.Min(l.maxLimit, limit))
+}
+
+// Acquire waits for the number of calls running to be lower than the limit, then reserves a slot for the call.
+// The function returned must be called once the call ended, with its error, so the limit is adjusted.
+// It returns the error of the context if the context is done while waiting.
+func (l *Limiter) Acquire(ctx context.Context) (func(err error), error) {
+	for {
+		l.mutex.Lock()
+		if l.inFlight < int(l.limit) {
+			l.inFlight++
+			l.mutex.Unlock()
+			return l.releaser(), nil
+		}
+		changed := l.changed
+		l.mutex.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+// TryAcquire is the equivalent of Acquire returning false right away if there is no room for the call.
+func (l *Limiter) TryAcquire() (func(err error), bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.inFlight >= int(l.limit) {
+		return nil, false
+	}
+	l.inFlight++
+	return l.releaser(), true
+}
+
+// releaser returns the function ending a call started now.
+func (l *Limiter) releaser() func(err error) {
+	start := time.Now()
+	var once sync.Once
+	return func(err error) {
+		once.Do(func() {
+			rtt := time.Since(start)
+			l.mutex.Lock()
+			defer l.mutex.Unlock()
+			sample := Sample{RTT: rtt, InFlight: l.inFlight, Dropped: err != nil}
+			l.inFlight--
+			l.limit = l.bound(l.algorithm.Update(l.limit, sample))
+			close(l.changed)
+			l.changed = make(chan struct{})
+		})
+	}
+}
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return int(l.limit)
+}
+
+// InFlight returns the number of calls running.
+func (l *Limiter) InFlight() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.inFlight
+}
+
+// Execute calls f once the limiter has room for it, and adjusts the limit with its outcome.
+func Execute[T any](ctx context.Context, l *Limiter, f func(ctx context.Context) (T, error)) (T, error) {
+	release, err := l.Acquire(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	result, err := f(ctx)
+	release(err)
+	return result, err
+}
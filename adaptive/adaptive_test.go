@@ -0,0 +1,82 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// constant is an Algorithm keeping the limit as it is.
+type constant struct{}
+
+func (constant) Update(limit float64, _ Sample) float64 {
+	return limit
+}
+
+func TestLimiter_Acquire(t *testing.T) {
+	limiter := New(constant{}, WithInitialLimit(2))
+	first, err := limiter.Acquire(context.Background())
+	assert.NoError(t, err)
+	_, err = limiter.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 2, limiter.InFlight())
+	_, ok := limiter.TryAcquire()
+	assert.False(t, ok)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = limiter.Acquire(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	acquired := make(chan struct{})
+	go func() {
+		if _, acquireErr := limiter.Acquire(context.Background()); acquireErr == nil {
+			close(acquired)
+		}
+	}()
+	first(nil)
+	// releasing twice has no effect
+	first(nil)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("the slot released has not been acquired")
+	}
+	assert.Equal(t, 2, limiter.InFlight())
+}
+
+func TestLimiter_Bounds(t *testing.T) {
+	limiter := New(AIMD(0.5, 0), WithInitialLimit(4), WithMinLimit(2), WithMaxLimit(5))
+	for i := 0; i < 3; i++ {
+		assert.Error(t, executeWith(limiter, errors.New("failure")))
+	}
+	assert.Equal(t, 2, limiter.Limit())
+	for i := 0; i < 10; i++ {
+		release, _ := limiter.Acquire(context.Background())
+		other, _ := limiter.Acquire(context.Background())
+		release(nil)
+		other(nil)
+	}
+	assert.Equal(t, 5, limiter.Limit())
+}
+
+func executeWith(limiter *Limiter, err error) error {
+	_, err = Execute(context.Background(), limiter, func(_ context.Context) (int, error) {
+		return 0, err
+	})
+	return err
+}
@@ -0,0 +1,80 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"math"
+	"time"
+)
+
+type aimd struct {
+	backoffRatio float64
+	timeout      time.Duration
+}
+
+// AIMD returns an Algorithm increasing the limit by one after each successful call, and multiplying it by backoffRatio (between 0 and 1)
+// after each call that failed or lasted longer than timeout. A timeout lower or equal to 0 means only the failures decrease the limit.
+// The limit is increased only when the calls use at least half of it, so it doesn't grow forever when the load is low.
+func AIMD(backoffRatio float64, timeout time.Duration) Algorithm {
+	if backoffRatio <= 0 || backoffRatio >= 1 {
+		backoffRatio = 0.9
+	}
+	return &aimd{backoffRatio: backoffRatio, timeout: timeout}
+}
+
+func (a *aimd) Update(limit float64, sample Sample) float64 {
+	if sample.Dropped || (a.timeout > 0 && sample.RTT > a.timeout) {
+		return limit * a.backoffRatio
+	}
+	if float64(sample.InFlight)*2 >= limit {
+		return limit + 1
+	}
+	return limit
+}
+
+type gradient struct {
+	// longRTT is the exponential moving average of the RTT, it is the reference of a healthy latency.
+	longRTT float64
+	// samples is the number of samples averaged, so the average warms up quickly.
+	samples   int
+	smoothing float64
+	tolerance float64
+}
+
+// Gradient returns an Algorithm comparing the latency of the last call to the average latency:
+// while the latency stays close to the average, the limit grows, and it shrinks as soon as the latency rises, a sign a queue builds up in the dependency.
+// It is inspired by the gradient2 algorithm of Netflix concurrency-limits. The failures are not considered, only the latency is.
+func Gradient() Algorithm {
+	return &gradient{smoothing: 0.2, tolerance: 1.5}
+}
+
+func (g *gradient) Update(limit float64, sample Sample) float64 {
+	rtt := float64(sample.RTT)
+	if rtt <= 0 {
+		return limit
+	}
+	g.samples++
+	// the average is smoothed over a long window once it warmed up.
+	window := math.Min(float64(g.samples), 600)
+	g.longRTT += (rtt - g.longRTT) / window
+	if float64(sample.InFlight)*2 < limit {
+		// the limit is not used enough to tell if it is too high
+		return limit
+	}
+	ratio := math.Max(0.5, math.Min(1, g.tolerance*g.longRTT/rtt))
+	// the queue size gives room to grow when the latency is stable.
+	queueSize := math.Sqrt(limit)
+	newLimit := limit*ratio + queueSize
+	return limit*(1-g.smoothing) + newLimit*g.smoothing
+}
@@ -0,0 +1,44 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package adaptive
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAIMD(t *testing.T) {
+	algorithm := AIMD(0.5, 100*time.Millisecond)
+	assert.Equal(t, 11.0, algorithm.Update(10, Sample{RTT: time.Millisecond, InFlight: 5}))
+	// the limit is not used enough to grow
+	assert.Equal(t, 10.0, algorithm.Update(10, Sample{RTT: time.Millisecond, InFlight: 2}))
+	assert.Equal(t, 5.0, algorithm.Update(10, Sample{RTT: time.Millisecond, InFlight: 5, Dropped: true}))
+	assert.Equal(t, 5.0, algorithm.Update(10, Sample{RTT: time.Second, InFlight: 5}))
+}
+
+func TestGradient(t *testing.T) {
+	algorithm := Gradient()
+	limit := 20.0
+	for i := 0; i < 50; i++ {
+		limit = algorithm.Update(limit, Sample{RTT: 10 * time.Millisecond, InFlight: int(limit)})
+	}
+	assert.Greater(t, limit, 20.0)
+	grown := limit
+	for i := 0; i < 10; i++ {
+		limit = algorithm.Update(limit, Sample{RTT: 100 * time.Millisecond, InFlight: int(limit)})
+	}
+	assert.Less(t, limit, grown)
+}
@@ -84,6 +84,9 @@ type cron struct {
 	duration time.Duration
 }
 
+// Runner is the graceful shutdown coordinator of an application.
+// It runs every task registered, listens to SIGINT and SIGTERM to cancel the context shared by the tasks, and then waits for them to stop.
+// If a task didn't stop once the grace period (see SetTimeout) is elapsed, the application is forced to exit with a non-zero code.
 type Runner struct {
 	// waitTimeout is the amount of time to wait before killing the application once it received a cancellation order.
 	waitTimeout time.Duration
@@ -161,6 +164,7 @@ func (r *Runner) HTTPServerBuilder() *echo.Builder {
 }
 
 // Start will start the application. It is a blocking method and will give back the end once every tasks handled are done.
+// If some tasks are still running once the grace period is elapsed, the application exits immediately with the code 1.
 func (r *Runner) Start() {
 	level, err := logrus.ParseLevel(logLevel)
 	if err != nil {
@@ -187,7 +191,9 @@ func (r *Runner) Start() {
 		taskhelper.Run(ctx, cancel, runner)
 	}
 	// Wait for context to be canceled or tasks to be ended and wait for graceful stop
-	taskhelper.JoinAll(ctx, r.waitTimeout, r.helpers)
+	if !taskhelper.JoinAll(ctx, r.waitTimeout, r.helpers) {
+		logrus.Fatalf("some tasks didn't stop within the grace period of %s, forcing the exit", r.waitTimeout)
+	}
 }
 
 func (r *Runner) printBannerOrMainHeader() {
@@ -0,0 +1,125 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package actor provides a lightweight actor: a mailbox of typed messages processed one by one by a single go-routine.
+// As the messages are never processed concurrently, the state owned by the actor can be mutated without any lock.
+//
+//	counts := map[string]int{}
+//	counter := actor.New("counter", 100, func(ctx context.Context, word string) error {
+//		counts[word]++
+//		return nil
+//	})
+//	app.NewRunner().WithTasks(async.NewSupervisor(counter)).Start()
+//	// from any go-routine
+//	err := counter.Send(ctx, "hello")
+//
+// The actor is an async.SimpleTask. Run it through an async.Supervisor to restart it when a message makes it fail:
+// the mailbox is kept across the restarts, only the message that failed is lost.
+package actor
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+
+	"github.com/perses/common/async"
+)
+
+// Actor processes the messages of type M sent to its mailbox, one at a time.
+type Actor[M any] struct {
+	name    string
+	mailbox chan M
+	handler func(ctx context.Context, msg M) error
+}
+
+// New creates an actor whose mailbox holds up to mailboxSize messages waiting to be processed by the handler.
+// When the handler returns an error or panics, the actor stops and Execute returns the error, so a supervisor is able to restart it.
+func New[M any](name string, mailboxSize int, handler func(ctx context.Context, msg M) error) *Actor[M] {
+	if mailboxSize < 0 {
+		mailboxSize = 0
+	}
+	return &Actor[M]{
+		name:    name,
+		mailbox: make(chan M, mailboxSize),
+		handler: handler,
+	}
+}
+
+func (a *Actor[M]) String() string {
+	return a.name
+}
+
+// Send puts the message in the mailbox. It blocks while the mailbox is full, until the context is done.
+func (a *Actor[M]) Send(ctx context.Context, msg M) error {
+	select {
+	case a.mailbox <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// TrySend puts the message in the mailbox only if it is not full. It returns true if the message has been put.
+func (a *Actor[M]) TrySend(msg M) bool {
+	select {
+	case a.mailbox <- msg:
+		return true
+	default:
+		return false
+	}
+}
+
+// Pending returns the number of messages waiting in the mailbox.
+func (a *Actor[M]) Pending() int {
+	return len(a.mailbox)
+}
+
+// Execute processes the messages until the context is done or the handler fails.
+// It must not be called concurrently: the guarantee that the messages are processed one by one relies on it.
+func (a *Actor[M]) Execute(ctx context.Context, _ context.CancelFunc) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case msg := <-a.mailbox:
+			if err := a.handle(ctx, msg); err != nil {
+				return fmt.Errorf("actor '%s' failed to process a message: %w", a.name, err)
+			}
+		}
+	}
+}
+
+func (a *Actor[M]) handle(ctx context.Context, msg M) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &async.ErrPanicked{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return a.handler(ctx, msg)
+}
+
+// Ask sends a request to an actor and waits for its response. The message is built by newMsg with the channel on which
+// the handler must send the response. The channel is buffered, so the handler never blocks when sending the response.
+func Ask[M any, R any](ctx context.Context, a *Actor[M], newMsg func(reply chan<- R) M) (R, error) {
+	reply := make(chan R, 1)
+	var zero R
+	if err := a.Send(ctx, newMsg(reply)); err != nil {
+		return zero, err
+	}
+	select {
+	case response := <-reply:
+		return response, nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
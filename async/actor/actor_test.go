@@ -0,0 +1,113 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package actor
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/perses/common/async"
+	"github.com/perses/common/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+type counterMsg struct {
+	increment int
+	// reply is set when the current value is requested.
+	reply chan<- int
+}
+
+func TestActor(t *testing.T) {
+	count := 0
+	a := New("counter", 10, func(_ context.Context, msg counterMsg) error {
+		count += msg.increment
+		if msg.reply != nil {
+			msg.reply <- count
+		}
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- a.Execute(ctx, cancel) }()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(100)
+	for i := 0; i < 100; i++ {
+		go func() {
+			defer wg.Done()
+			assert.NoError(t, a.Send(context.Background(), counterMsg{increment: 1}))
+		}()
+	}
+	wg.Wait()
+	value, err := Ask(context.Background(), a, func(reply chan<- int) counterMsg {
+		return counterMsg{reply: reply}
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 100, value)
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestActor_Supervised(t *testing.T) {
+	processed := make(chan string, 10)
+	a := New("words", 10, func(_ context.Context, word string) error {
+		switch word {
+		case "panic":
+			panic("unexpected word")
+		case "error":
+			return errors.New("invalid word")
+		}
+		processed <- word
+		return nil
+	})
+	for _, word := range []string{"a", "panic", "b", "error", "c"} {
+		assert.True(t, a.TrySend(word))
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	restarts := make(chan async.Event, 10)
+	s := async.NewSupervisor(a,
+		async.WithRestartPolicy(async.RestartOnError),
+		async.WithRestartBackoff(retry.Constant(0)),
+		async.WithSupervisorLogger(async.LoggerFunc(func(event async.Event) {
+			if event.Type == async.EventRestarted {
+				restarts <- event
+			}
+		})),
+	)
+	done := make(chan error)
+	go func() { done <- s.Execute(ctx, cancel) }()
+
+	// the actor is restarted after each failure and the following messages are still processed, in order.
+	for _, expected := range []string{"a", "b", "c"} {
+		assert.Equal(t, expected, <-processed)
+	}
+	var panicked *async.ErrPanicked
+	assert.ErrorAs(t, (<-restarts).Err, &panicked)
+	assert.EqualError(t, (<-restarts).Err, "actor 'words' failed to process a message: invalid word")
+	cancel()
+	assert.NoError(t, <-done)
+}
+
+func TestActor_Send(t *testing.T) {
+	a := New("full", 1, func(_ context.Context, _ int) error { return nil })
+	assert.True(t, a.TrySend(1))
+	assert.False(t, a.TrySend(2))
+	assert.Equal(t, 1, a.Pending())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, a.Send(ctx, 2))
+}
@@ -23,16 +23,25 @@
 //	}
 //
 //  func synchronousTask() {
-//  	next := async.Async(func() interface{} {
+//  	next := async.Go(func() int {
 //			return doneAsync()
 //  	})
 //		// do some other stuff
-//  	// then wait for the end of the asynchronous task and get back the result
+//  	// then wait for the end of the asynchronous task and get back the result. Here result is an int, no cast needed.
 //  	result := next.Await()
 //  	// do something with the result
 //  }
 //
+// Go is generic, the type of the result is inferred from the function given. Async, taking a function returning an interface{} and giving back a Future, is kept for compatibility.
+//
+// When the asynchronous function can fail, use AsyncErr instead. The ErrFuture returned gives back the error separately from the result:
+//  next := async.AsyncErr(func() (*http.Response, error) {
+//  	return http.Get("https://perses.dev")
+//  })
+//  response, err := next.Await()
+//
 // It is useful to use this implementation when you want to paralyze quickly some short function like paralyzing multiple HTTP request.
+// The package httpx provides helpers doing that, with a concurrency limit and a timeout per request.
 // You definitely won't use this implementation if you want to create a cron or a long task. Instead you should implement the interface SimpleTask or Task for doing that.
 //
 // As a Task is designed to run in a long term, you will have to take care about a context and a cancel function passed in the parameter of the main method Execute.
@@ -81,42 +90,433 @@
 //	app.NewRunner().WithTasks(&myInfiniteTask).Start()
 package async
 
-import "context"
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// TypedFuture is the result of an asynchronous function. T is the type of the value returned by the function.
+type TypedFuture[T any] interface {
+	// Await blocks until the asynchronous function ends and returns its result.
+	Await() T
+	// AwaitWithContext blocks until the asynchronous function ends or until the context is done.
+	// When the context is done first, it returns ctx.Err() if T is able to hold an error (like interface{}), otherwise the zero value of T.
+	AwaitWithContext(ctx context.Context) T
+	// AwaitWithTimeout blocks until the asynchronous function ends or until the timeout expires.
+	// When the timeout expires first, it returns ErrAwaitTimeout if T is able to hold an error (like interface{}), otherwise the zero value of T.
+	AwaitWithTimeout(timeout time.Duration) T
+	// AwaitResult blocks until the asynchronous function ends or until the context is done, like AwaitWithContext,
+	// except the failure of the future (the error of the context, ErrCancelled or an *ErrPanicked) is given back as an error, never mixed with the result.
+	// It must be preferred when T is able to hold an error, since a function returning an error is otherwise indistinguishable from a failed await.
+	AwaitResult(ctx context.Context) (T, error)
+	// Cancel cancels the context given to the asynchronous function and marks the future as cancelled.
+	// Once cancelled, Await returns ErrCancelled when T is able to hold it, whatever the function returns afterwards.
+	// Calling Cancel on a future already ended has no effect.
+	Cancel()
+	// IsDone returns true when the result is available. It never blocks.
+	IsDone() bool
+	// TryAwait returns the result and true if the asynchronous function has ended, otherwise the zero value of T and false.
+	// Unlike Await, it never blocks.
+	TryAwait() (T, bool)
+	// OnComplete registers f to be called once the asynchronous function ends, with its result and the failure of the future, if any.
+	// f is called by the go-routine completing the future, so it must be fast. If the future is already done, f is called right away.
+	OnComplete(f func(result T, err error))
+	// Then returns a new TypedFuture holding the result of f applied to the result of this one.
+	// f is not called if this future failed (cancelled or panicked), in which case the failure is propagated to the new TypedFuture.
+	Then(f func(result T) T) TypedFuture[T]
+	// Catch returns a new TypedFuture holding the result of f applied to the failure of this one (cancelled or panicked).
+	// If this future didn't fail, f is not called and its result is propagated to the new TypedFuture.
+	Catch(f func(err error) T) TypedFuture[T]
+}
+
+// ErrFuture is the result of an asynchronous function that can fail.
+// Unlike TypedFuture, the error is never mixed with the result.
+type ErrFuture[T any] interface {
+	// Await blocks until the asynchronous function ends and returns its result and its error.
+	Await() (T, error)
+	// AwaitWithContext blocks until the asynchronous function ends or until the context is done.
+	// When the context is done first, it returns the zero value of T and ctx.Err().
+	AwaitWithContext(ctx context.Context) (T, error)
+	// AwaitWithTimeout blocks until the asynchronous function ends or until the timeout expires.
+	// When the timeout expires first, it returns the zero value of T and ErrAwaitTimeout.
+	AwaitWithTimeout(timeout time.Duration) (T, error)
+	// Cancel cancels the context given to the asynchronous function and marks the future as cancelled.
+	// Once cancelled, Await returns ErrCancelled, whatever the function returns afterwards.
+	// Calling Cancel on a future already ended has no effect.
+	Cancel()
+	// IsDone returns true when the result is available. It never blocks.
+	IsDone() bool
+	// TryAwait returns the result, the error and true if the asynchronous function has ended, otherwise the zero value of T, nil and false.
+	// Unlike Await, it never blocks.
+	TryAwait() (T, error, bool)
+	// OnComplete registers f to be called once the asynchronous function ends, with its result and its error.
+	// f is called by the go-routine completing the future, so it must be fast. If the future is already done, f is called right away.
+	// It is the way to react to the end of a fire-and-forget function, like recording its error, without dedicating a go-routine to Await.
+	OnComplete(f func(result T, err error))
+	// Then returns a new ErrFuture holding the result of f applied to the result of this one.
+	// f is not called if this future failed, in which case the error is propagated to the new ErrFuture.
+	Then(f func(result T) T) ErrFuture[T]
+	// Catch returns a new ErrFuture holding the result of f applied to the error of this one. The new ErrFuture doesn't fail.
+	// If this future didn't fail, f is not called and its result is propagated to the new ErrFuture.
+	Catch(f func(err error) T) ErrFuture[T]
+}
+
+const (
+	statePending uint32 = iota
+	stateCompleting
+	stateDone
+)
+
+// closedChan is the channel given to the waiters of a state already done, so it doesn't need to allocate its own.
+var closedChan = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+// state is holding the result of an asynchronous function. It is shared by every kind of future.
+// The result is published through the atomic status, so checking a future is done never allocates.
+// The channel waking up the waiters is only created when a go-routine actually blocks on it.
+type state[T any] struct {
+	// status is statePending until the first call to complete, then stateDone once the result and the error are set.
+	status uint32
+	// mutex protects done and callbacks.
+	mutex sync.Mutex
+	// done is closed once the result and the error are set. It is nil until a waiter needs it.
+	done chan struct{}
+	// callbacks are the functions registered by OnComplete before the state is done.
+	callbacks []func(result T, err error)
+
+	result T
+	err    error
+	// cancel cancels the context given to the asynchronous function.
+	cancel context.CancelFunc
+	// start launches the asynchronous function of a lazy future the first time the result is awaited. It is nil otherwise.
+	start    func()
+	starting sync.Once
+	// name and labels identify the asynchronous function. They are set only by AsyncNamed, WithName and WithLabels.
+	name   string
+	labels Labels
+	// noRecover is true when a panic of the asynchronous function must not be recovered, see WithRecover.
+	noRecover bool
+	// submitter is the function that started the asynchronous function, for the profiling labels. It is empty if they are disabled.
+	submitter string
+	// id is the execution ID given by WithExecutionID or WithJobID. Otherwise, the ID is generated from seq only when needed.
+	id  string
+	seq uint64
+}
+
+func newState[T any](cancel context.CancelFunc) *state[T] {
+	return &state[T]{
+		cancel: cancel,
+		seq:    nextExecutionSeq(),
+	}
+}
+
+func run[T any](ctx context.Context, f func(ctx context.Context) (T, error)) *state[T] {
+	return runNamed(ctx, "", nil, f)
+}
+
+func runNamed[T any](ctx context.Context, name string, labels Labels, f func(ctx context.Context) (T, error)) *state[T] {
+	return runWith(ctx, &asyncConfig{name: name, labels: labels}, f)
+}
+
+// execute calls the function, completes the state with its result and reports the execution to the MetricsHook.
+// It returns the error of the function, or the *ErrPanicked if it panicked.
+func (s *state[T]) execute(ctx context.Context, kind Kind, name string, f func(ctx context.Context) (T, error)) (err error) {
+	var result T
+	defer func() {
+		s.complete(result, err)
+		// once the function ended, the context is no longer needed.
+		s.cancel()
+	}()
+	// a panic must not kill the whole process, it is given back to the awaiting go-routine instead, unless WithRecover(false) is used.
+	return Execute(ctx, kind, name, s.ExecutionID(), !s.noRecover, func(ctx context.Context) error {
+		var fErr error
+		result, fErr = s.profile(ctx, kind, name, f)
+		return fErr
+	})
+}
+
+// complete sets the result and the error of the future. Only the first call has an effect.
+func (s *state[T]) complete(result T, err error) {
+	if !atomic.CompareAndSwapUint32(&s.status, statePending, stateCompleting) {
+		return
+	}
+	s.result = result
+	s.err = err
+	s.mutex.Lock()
+	atomic.StoreUint32(&s.status, stateDone)
+	if s.done != nil {
+		close(s.done)
+	}
+	callbacks := s.callbacks
+	s.callbacks = nil
+	s.mutex.Unlock()
+	for _, callback := range callbacks {
+		callback(result, err)
+	}
+}
+
+// OnComplete is shared by every kind of future. A lazy future is started by the registration.
+func (s *state[T]) OnComplete(f func(result T, err error)) {
+	s.trigger()
+	s.mutex.Lock()
+	if atomic.LoadUint32(&s.status) != stateDone {
+		s.callbacks = append(s.callbacks, f)
+		s.mutex.Unlock()
+		return
+	}
+	s.mutex.Unlock()
+	f(s.result, s.err)
+}
+
+// doneChan returns the channel closed once the result and the error are set, creating it if needed.
+func (s *state[T]) doneChan() <-chan struct{} {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.done != nil {
+		return s.done
+	}
+	if atomic.LoadUint32(&s.status) == stateDone {
+		return closedChan
+	}
+	s.done = make(chan struct{})
+	return s.done
+}
+
+func (s *state[T]) Cancel() {
+	s.cancel()
+	var zero T
+	s.complete(zero, ErrCancelled)
+}
+
+// trigger launches the asynchronous function of a lazy future if it is not launched yet.
+func (s *state[T]) trigger() {
+	if s.start != nil {
+		s.starting.Do(s.start)
+	}
+}
+
+func (s *state[T]) wait(ctx context.Context) (T, error) {
+	s.trigger()
+	if s.IsDone() {
+		return s.result, s.err
+	}
+	select {
+	case <-ctx.Done():
+		var zero T
+		return zero, ctx.Err()
+	case <-s.doneChan():
+		return s.result, s.err
+	}
+}
+
+func (s *state[T]) waitWithTimeout(timeout time.Duration) (T, error) {
+	s.trigger()
+	if s.IsDone() {
+		return s.result, s.err
+	}
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		var zero T
+		return zero, ErrAwaitTimeout
+	case <-s.doneChan():
+		return s.result, s.err
+	}
+}
+
+// chain returns a new state completed once this one is completed, using onSuccess or onFailure to compute the new result.
+// Cancelling the new state doesn't cancel the current one.
+func (s *state[T]) chain(onSuccess func(result T) (T, error), onFailure func(err error) (T, error)) *state[T] {
+	return run(context.Background(), func(ctx context.Context) (T, error) {
+		result, err := s.wait(ctx)
+		if ctx.Err() != nil {
+			// the chained future has been cancelled, the result is not needed anymore.
+			return result, err
+		}
+		if err != nil {
+			return onFailure(err)
+		}
+		return onSuccess(result)
+	})
+}
+
+func (s *state[T]) IsDone() bool {
+	return atomic.LoadUint32(&s.status) == stateDone
+}
+
+type next[T any] struct {
+	*state[T]
+}
+
+func (n *next[T]) Await() T {
+	return n.AwaitWithContext(context.Background())
+}
+
+func (n *next[T]) AwaitWithContext(ctx context.Context) T {
+	result, err := n.wait(ctx)
+	if err != nil {
+		return errorAsResult[T](err)
+	}
+	return result
+}
+
+func (n *next[T]) AwaitWithTimeout(timeout time.Duration) T {
+	result, err := n.waitWithTimeout(timeout)
+	if err != nil {
+		return errorAsResult[T](err)
+	}
+	return result
+}
+
+func (n *next[T]) AwaitResult(ctx context.Context) (T, error) {
+	return n.wait(ctx)
+}
+
+func (n *next[T]) TryAwait() (T, bool) {
+	if !n.IsDone() {
+		var zero T
+		return zero, false
+	}
+	return n.Await(), true
+}
+
+func (n *next[T]) Then(f func(result T) T) TypedFuture[T] {
+	return &next[T]{
+		state: n.chain(
+			func(result T) (T, error) {
+				return f(result), nil
+			},
+			func(err error) (T, error) {
+				var zero T
+				return zero, err
+			},
+		),
+	}
+}
+
+func (n *next[T]) Catch(f func(err error) T) TypedFuture[T] {
+	return &next[T]{
+		state: n.chain(
+			func(result T) (T, error) {
+				return result, nil
+			},
+			func(err error) (T, error) {
+				return f(err), nil
+			},
+		),
+	}
+}
+
+type errNext[T any] struct {
+	*state[T]
+}
 
+func (n *errNext[T]) Await() (T, error) {
+	return n.wait(context.Background())
+}
+
+func (n *errNext[T]) AwaitWithContext(ctx context.Context) (T, error) {
+	return n.wait(ctx)
+}
+
+func (n *errNext[T]) AwaitWithTimeout(timeout time.Duration) (T, error) {
+	return n.waitWithTimeout(timeout)
+}
+
+func (n *errNext[T]) TryAwait() (T, error, bool) {
+	if !n.IsDone() {
+		var zero T
+		return zero, nil, false
+	}
+	result, err := n.Await()
+	return result, err, true
+}
+
+func (n *errNext[T]) Then(f func(result T) T) ErrFuture[T] {
+	return &errNext[T]{
+		state: n.chain(
+			func(result T) (T, error) {
+				return f(result), nil
+			},
+			func(err error) (T, error) {
+				var zero T
+				return zero, err
+			},
+		),
+	}
+}
+
+func (n *errNext[T]) Catch(f func(err error) T) ErrFuture[T] {
+	return &errNext[T]{
+		state: n.chain(
+			func(result T) (T, error) {
+				return result, nil
+			},
+			func(err error) (T, error) {
+				return f(err), nil
+			},
+		),
+	}
+}
+
+// Future is the result of an asynchronous function started by Async. It is kept for compatibility, TypedFuture gives back a typed result.
 type Future interface {
 	Await() interface{}
 	AwaitWithContext(ctx context.Context) interface{}
 }
 
-type next struct {
-	await func(ctx context.Context) interface{}
+// Async executes the asynchronous function. It is kept for compatibility, Go is its typed equivalent.
+// If the function panics, the panic is recovered and Await returns an *ErrPanicked.
+func Async(f func() interface{}) Future {
+	return Go(f)
 }
 
-func (n *next) Await() interface{} {
-	return n.await(context.Background())
+// Go executes the asynchronous function.
+// If the function panics, the panic is recovered and Await returns an *ErrPanicked when T is able to hold it (like interface{}).
+// The options tune how the function is executed, see AsyncOption.
+func Go[T any](f func() T, opts ...AsyncOption) TypedFuture[T] {
+	return AsyncWithContext(context.Background(), func(_ context.Context) T {
+		return f()
+	}, opts...)
 }
 
-func (n *next) AwaitWithContext(ctx context.Context) interface{} {
-	return n.await(ctx)
+// AsyncErr executes the asynchronous function that can fail.
+// The error returned by the function is given back by the method Await of the ErrFuture.
+// If the function panics, the panic is recovered and Await returns an *ErrPanicked.
+func AsyncErr[T any](f func() (T, error), opts ...AsyncOption) ErrFuture[T] {
+	return AsyncErrWithContext(context.Background(), func(_ context.Context) (T, error) {
+		return f()
+	}, opts...)
 }
 
-// Async executes the asynchronous function
-func Async(f func() interface{}) Future {
-	var result interface{}
-	// c is going to be used to catch only the signal when the channel is closed.
-	c := make(chan struct{})
-	go func() {
-		defer close(c)
-		result = f()
-	}()
-	return &next{
-		await: func(ctx context.Context) interface{} {
-			select {
-			case <-ctx.Done():
-				return ctx.Err()
-			case <-c:
-				return result
-			}
-		},
+// AsyncWithContext executes the asynchronous function with a context derived from the given one.
+// Unlike AwaitWithContext that only stops the waiting, the function itself is able to observe the cancellation of the context and to stop its work.
+// The context given to the function is also cancelled when the method Cancel of the TypedFuture is called.
+func AsyncWithContext[T any](ctx context.Context, f func(ctx context.Context) T, opts ...AsyncOption) TypedFuture[T] {
+	return &next[T]{
+		state: runWith(ctx, newAsyncConfig(opts), func(ctx context.Context) (T, error) {
+			return f(ctx), nil
+		}),
+	}
+}
+
+// AsyncErrWithContext is the equivalent of AsyncWithContext for a function that can fail.
+func AsyncErrWithContext[T any](ctx context.Context, f func(ctx context.Context) (T, error), opts ...AsyncOption) ErrFuture[T] {
+	return &errNext[T]{state: runWith(ctx, newAsyncConfig(opts), f)}
+}
+
+// errorAsResult returns the error as a T when T is able to hold it (which is the case for interface{} or error).
+// Otherwise, it returns the zero value of T.
+func errorAsResult[T any](err error) T {
+	if result, ok := interface{}(err).(T); ok {
+		return result
 	}
+	var zero T
+	return zero
 }
@@ -15,6 +15,9 @@ package async
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -42,3 +45,243 @@ func TestNextImpl_AwaitWithContext(t *testing.T) {
 	result := next.AwaitWithContext(ctx)
 	assert.Equal(t, 1, result)
 }
+
+func TestAsync_Typed(t *testing.T) {
+	next := Go(func() string {
+		return "hello"
+	})
+	// result is a string, no type assertion is required
+	result := next.Await()
+	assert.Equal(t, "hello", result)
+}
+
+func TestAsync_AwaitWithContextCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	typed := Go(func() int {
+		return doneAsync()
+	})
+	assert.Equal(t, 0, typed.AwaitWithContext(ctx))
+	untyped := Async(func() interface{} {
+		return doneAsync()
+	})
+	assert.Equal(t, context.Canceled, untyped.AwaitWithContext(ctx))
+}
+
+func TestAsync_AwaitResult(t *testing.T) {
+	failure := errors.New("failure")
+	// the function legitimately returns an error as its result
+	next := Go(func() error {
+		return failure
+	})
+	result, err := next.AwaitResult(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, failure, result)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	blocked := Go(func() error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	result, err = blocked.AwaitResult(ctx)
+	assert.Nil(t, result)
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestAsyncErr(t *testing.T) {
+	success := AsyncErr(func() (int, error) {
+		return doneAsync(), nil
+	})
+	result, err := success.Await()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result)
+
+	failure := AsyncErr(func() (interface{}, error) {
+		return nil, fmt.Errorf("failure")
+	})
+	result2, err := failure.Await()
+	assert.EqualError(t, err, "failure")
+	assert.Nil(t, result2)
+}
+
+func TestAsyncErr_Panic(t *testing.T) {
+	next := AsyncErr(func() (int, error) {
+		panic("boom")
+	})
+	_, err := next.Await()
+	var panicked *ErrPanicked
+	assert.True(t, errors.As(err, &panicked))
+	assert.Equal(t, "boom", panicked.Value)
+	assert.NotEmpty(t, panicked.Stack)
+
+	untyped := Async(func() interface{} {
+		panic("boom")
+	})
+	assert.IsType(t, &ErrPanicked{}, untyped.Await())
+}
+
+func TestAsyncErrWithContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	next := AsyncErrWithContext(ctx, func(ctx context.Context) (int, error) {
+		defer close(stopped)
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	cancel()
+	// the function must have observed the cancellation and stopped
+	<-stopped
+	_, err := next.Await()
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestErrFuture_Cancel(t *testing.T) {
+	stopped := make(chan struct{})
+	next := AsyncErrWithContext(context.Background(), func(ctx context.Context) (int, error) {
+		defer close(stopped)
+		<-ctx.Done()
+		return 1, nil
+	})
+	next.Cancel()
+	<-stopped
+	result, err := next.Await()
+	assert.Equal(t, ErrCancelled, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, result)
+}
+
+func TestFuture_TryAwait(t *testing.T) {
+	release := make(chan struct{})
+	next := Go(func() int {
+		<-release
+		return 1
+	})
+	assert.False(t, next.IsDone())
+	result, ok := next.TryAwait()
+	assert.False(t, ok)
+	assert.Equal(t, 0, result)
+	close(release)
+	next.Await()
+	assert.True(t, next.IsDone())
+	result, ok = next.TryAwait()
+	assert.True(t, ok)
+	assert.Equal(t, 1, result)
+}
+
+func TestErrFuture_AwaitWithTimeout(t *testing.T) {
+	next := AsyncErr(func() (int, error) {
+		return doneAsync(), nil
+	})
+	_, err := next.AwaitWithTimeout(10 * time.Millisecond)
+	assert.Equal(t, ErrAwaitTimeout, err)
+	result, err := next.AwaitWithTimeout(5 * time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result)
+}
+
+func TestErrFuture_ThenCatch(t *testing.T) {
+	double := func(v int) int {
+		return v * 2
+	}
+	result, err := AsyncErr(func() (int, error) {
+		return 2, nil
+	}).Then(double).Then(double).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, 8, result)
+
+	result, err = AsyncErr(func() (int, error) {
+		return 0, fmt.Errorf("failure")
+	}).Then(double).Catch(func(err error) int {
+		return -1
+	}).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, -1, result)
+}
+
+func TestErrFuture_OnComplete(t *testing.T) {
+	failure := errors.New("failure")
+	release := make(chan struct{})
+	future := AsyncErr(func() (int, error) {
+		<-release
+		return 1, failure
+	})
+	called := make(chan error, 2)
+	future.OnComplete(func(result int, err error) {
+		assert.Equal(t, 1, result)
+		called <- err
+	})
+	close(release)
+	assert.Equal(t, failure, <-called)
+	// the callback registered once the future is done is called right away
+	future.OnComplete(func(_ int, err error) {
+		called <- err
+	})
+	assert.Equal(t, failure, <-called)
+}
+
+func TestFuture_OnComplete(t *testing.T) {
+	future := Go(func() int {
+		return 1
+	})
+	future.Cancel()
+	done := make(chan struct{})
+	future.OnComplete(func(_ int, err error) {
+		assert.Equal(t, ErrCancelled, err)
+		close(done)
+	})
+	<-done
+}
+
+func TestFuture_AwaitDoneAllocs(t *testing.T) {
+	future := Go(func() int {
+		return 1
+	})
+	assert.Equal(t, 1, future.Await())
+	// once done, the result is read without taking a lock nor creating the channel
+	allocs := testing.AllocsPerRun(100, func() {
+		_ = future.Await()
+	})
+	assert.Equal(t, float64(0), allocs)
+}
+
+func TestFuture_ConcurrentAwaitAndComplete(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		promise := NewPromise[int]()
+		future := promise.Future()
+		wg := &sync.WaitGroup{}
+		wg.Add(10)
+		for j := 0; j < 10; j++ {
+			go func() {
+				defer wg.Done()
+				result, err := future.Await()
+				assert.NoError(t, err)
+				assert.Equal(t, 42, result)
+			}()
+		}
+		promise.Resolve(42)
+		wg.Wait()
+	}
+}
+
+func BenchmarkFuture_AwaitDone(b *testing.B) {
+	future := Go(func() int {
+		return 1
+	})
+	future.Await()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = future.Await()
+	}
+}
+
+func BenchmarkFuture_AwaitPending(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		promise := NewPromise[int]()
+		future := promise.Future()
+		go promise.Resolve(1)
+		_, _ = future.Await()
+	}
+}
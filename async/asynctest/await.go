@@ -0,0 +1,47 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package asynctest
+
+import (
+	"testing"
+	"time"
+)
+
+// Awaitable is implemented by every kind of future of the package async.
+type Awaitable interface {
+	IsDone() bool
+}
+
+// AwaitAll waits for every future given to end. If they didn't all end within the timeout, the test fails.
+// It returns true when every future ended.
+func AwaitAll(t testing.TB, timeout time.Duration, futures ...Awaitable) bool {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		pending := 0
+		for _, future := range futures {
+			if !future.IsDone() {
+				pending++
+			}
+		}
+		if pending == 0 {
+			return true
+		}
+		if time.Now().After(deadline) {
+			t.Errorf("%d future(s) out of %d didn't end within %s", pending, len(futures), timeout)
+			return false
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,184 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package asynctest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/perses/common/clock"
+)
+
+// FakeClock is a clock.Clock whose time only moves when Advance is called, so the time-based features can be tested without sleeping:
+//
+//	c := asynctest.NewFakeClock(time.Now())
+//	debounced := async.Debounce(time.Second, f, async.WithClock(c))
+//	future := debounced()
+//	c.Advance(time.Second)
+//	result, err := future.Await()
+//
+// The functions given to AfterFunc are called synchronously by Advance, so their effects are visible once it returns.
+type FakeClock struct {
+	mutex sync.Mutex
+	// cond is signaled every time a timer is added, for BlockUntil.
+	cond   *sync.Cond
+	now    time.Time
+	timers map[*fakeTimer]bool
+}
+
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now, timers: make(map[*fakeTimer]bool)}
+	c.cond = sync.NewCond(&c.mutex)
+	return c
+}
+
+func (c *FakeClock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.now
+}
+
+func (c *FakeClock) Since(t time.Time) time.Duration {
+	return c.Now().Sub(t)
+}
+
+func (c *FakeClock) After(d time.Duration) <-chan time.Time {
+	return c.NewTimer(d).C()
+}
+
+// Sleep blocks until the time has been advanced by the duration.
+func (c *FakeClock) Sleep(d time.Duration) {
+	<-c.NewTimer(d).C()
+}
+
+func (c *FakeClock) NewTimer(d time.Duration) clock.Timer {
+	return c.add(&fakeTimer{clock: c, c: make(chan time.Time, 1)}, d)
+}
+
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) clock.Timer {
+	return c.add(&fakeTimer{clock: c, f: f}, d)
+}
+
+func (c *FakeClock) NewTicker(d time.Duration) clock.Ticker {
+	return &fakeTicker{fakeTimer: c.add(&fakeTimer{clock: c, c: make(chan time.Time, 1), period: d}, d)}
+}
+
+// Advance moves the time forward by the duration, firing in order every timer and ticker due in the meantime.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	target := c.now.Add(d)
+	c.mutex.Unlock()
+	for {
+		c.mutex.Lock()
+		var next *fakeTimer
+		for t := range c.timers {
+			if !t.deadline.After(target) && (next == nil || t.deadline.Before(next.deadline)) {
+				next = t
+			}
+		}
+		if next == nil {
+			c.now = target
+			c.mutex.Unlock()
+			return
+		}
+		c.now = next.deadline
+		now := c.now
+		if next.period > 0 {
+			next.deadline = next.deadline.Add(next.period)
+		} else {
+			delete(c.timers, next)
+		}
+		c.mutex.Unlock()
+		next.fire(now)
+	}
+}
+
+// BlockUntil waits for at least n timers or tickers to be waiting. It is useful to be sure a go-routine has created
+// its timer before advancing the time.
+func (c *FakeClock) BlockUntil(n int) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	for len(c.timers) < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *FakeClock) add(t *fakeTimer, d time.Duration) *fakeTimer {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	t.deadline = c.now.Add(d)
+	c.timers[t] = true
+	c.cond.Broadcast()
+	return t
+}
+
+// fakeTimer is the clock.Timer and the clock.Ticker of the FakeClock.
+type fakeTimer struct {
+	clock    *FakeClock
+	deadline time.Time
+	c        chan time.Time
+	f        func()
+	// period is set for a ticker.
+	period time.Duration
+}
+
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.c
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	active := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	return active
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+	active := t.clock.timers[t]
+	t.deadline = t.clock.now.Add(d)
+	t.clock.timers[t] = true
+	t.clock.cond.Broadcast()
+	return active
+}
+
+func (t *fakeTimer) fire(now time.Time) {
+	if t.f != nil {
+		t.f()
+		return
+	}
+	// like the real timers, a tick is dropped when the previous one has not been received yet.
+	select {
+	case t.c <- now:
+	default:
+	}
+}
+
+// fakeTicker is the clock.Ticker of the FakeClock.
+type fakeTicker struct {
+	*fakeTimer
+}
+
+func (t *fakeTicker) Stop() {
+	t.fakeTimer.Stop()
+}
+
+func (t *fakeTicker) Reset(d time.Duration) {
+	t.clock.mutex.Lock()
+	t.period = d
+	t.clock.mutex.Unlock()
+	t.fakeTimer.Reset(d)
+}
@@ -0,0 +1,85 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package asynctest
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/perses/common/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFakeClock(t *testing.T) {
+	start := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := NewFakeClock(start)
+	timer := c.NewTimer(time.Second)
+	ticker := c.NewTicker(400 * time.Millisecond)
+	var calls int32
+	c.AfterFunc(500*time.Millisecond, func() { atomic.AddInt32(&calls, 1) })
+
+	c.Advance(500 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+	assert.Equal(t, start.Add(400*time.Millisecond), <-ticker.C())
+	select {
+	case <-timer.C():
+		t.Fatal("the timer must not have fired")
+	default:
+	}
+	c.Advance(500 * time.Millisecond)
+	assert.Equal(t, start.Add(time.Second), <-timer.C())
+	assert.Equal(t, start.Add(800*time.Millisecond), <-ticker.C())
+	assert.False(t, timer.Stop())
+	ticker.Stop()
+	assert.Equal(t, time.Second, c.Since(start))
+}
+
+func TestFakeClock_Debounce(t *testing.T) {
+	c := NewFakeClock(time.Now())
+	var calls int32
+	debounced := async.Debounce(time.Second, func() (int32, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	}, async.WithClock(c))
+	first := debounced()
+	c.Advance(500 * time.Millisecond)
+	second := debounced()
+	c.Advance(500 * time.Millisecond)
+	assert.False(t, second.IsDone())
+	c.Advance(500 * time.Millisecond)
+	AwaitAll(t, time.Second, first, second)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestFakeClock_Retry(t *testing.T) {
+	c := NewFakeClock(time.Now())
+	var attempts int32
+	future := async.AsyncErrWithContext(context.Background(), retry.Wrap(func(_ context.Context) (int32, error) {
+		attempt := atomic.AddInt32(&attempts, 1)
+		if attempt < 3 {
+			return 0, assert.AnError
+		}
+		return attempt, nil
+	}, retry.WithBackoff(retry.Constant(time.Hour)), retry.WithClock(c)))
+	// each failed attempt waits for an hour before the next one
+	for i := 0; i < 2; i++ {
+		c.BlockUntil(1)
+		c.Advance(time.Hour)
+	}
+	result, err := future.Await()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(3), result)
+}
@@ -0,0 +1,98 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package asynctest provides helpers to test the code using the package async.
+package asynctest
+
+import (
+	"bytes"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+const asyncPackage = "github.com/perses/common/async"
+
+// gracePeriod is the time given to the go-routines to end once the test is over, before considering them as leaked.
+var gracePeriod = time.Second
+
+// VerifyNoLeaks fails the test if go-routines started by the package async (futures, pools, tasks, ...) during the test
+// are still running once the test ends. It must be called at the beginning of the test:
+//
+//	func TestSomething(t *testing.T) {
+//		asynctest.VerifyNoLeaks(t)
+//		// the test
+//	}
+//
+// The go-routines running before the call are ignored. The others are given a grace period of one second to end.
+func VerifyNoLeaks(t testing.TB) {
+	t.Helper()
+	before := make(map[string]bool)
+	for _, g := range goroutines() {
+		before[g.id] = true
+	}
+	t.Cleanup(func() {
+		deadline := time.Now().Add(gracePeriod)
+		for {
+			leaks := leakedGoroutines(before)
+			if len(leaks) == 0 {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Errorf("%d go-routine(s) started by the package async are still running:\n\n%s", len(leaks), strings.Join(leaks, "\n\n"))
+				return
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	})
+}
+
+type goroutine struct {
+	id    string
+	stack string
+}
+
+func leakedGoroutines(before map[string]bool) []string {
+	var leaks []string
+	for _, g := range goroutines() {
+		if before[g.id] || !strings.Contains(g.stack, asyncPackage) {
+			continue
+		}
+		leaks = append(leaks, g.stack)
+	}
+	return leaks
+}
+
+// goroutines returns the stack of every go-routine running.
+func goroutines() []goroutine {
+	buf := make([]byte, 64*1024)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+	var result []goroutine
+	for _, stack := range bytes.Split(buf, []byte("\n\n")) {
+		// the first line looks like "goroutine 12 [chan receive]:"
+		header := strings.SplitN(string(stack), " ", 3)
+		if len(header) < 3 || header[0] != "goroutine" {
+			continue
+		}
+		result = append(result, goroutine{id: header[1], stack: string(stack)})
+	}
+	return result
+}
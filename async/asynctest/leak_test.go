@@ -0,0 +1,65 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package asynctest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/stretchr/testify/assert"
+)
+
+// recorder is a testing.TB keeping the cleanup functions and the failures, so the result of VerifyNoLeaks can be checked.
+type recorder struct {
+	testing.TB
+	cleanups []func()
+	failed   bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Cleanup(f func()) {
+	r.cleanups = append(r.cleanups, f)
+}
+
+func (r *recorder) Errorf(_ string, _ ...interface{}) {
+	r.failed = true
+}
+
+func (r *recorder) end() {
+	for _, f := range r.cleanups {
+		f()
+	}
+}
+
+func TestVerifyNoLeaks(t *testing.T) {
+	gracePeriod = 50 * time.Millisecond
+	r := &recorder{TB: t}
+	VerifyNoLeaks(r)
+	async.Go(func() int { return 1 }).Await()
+	r.end()
+	assert.False(t, r.failed)
+
+	r = &recorder{TB: t}
+	VerifyNoLeaks(r)
+	block := make(chan struct{})
+	defer close(block)
+	async.Go(func() int {
+		<-block
+		return 1
+	})
+	r.end()
+	assert.True(t, r.failed)
+}
@@ -0,0 +1,87 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// Autoscaling defines how a Pool created with WithAutoscaling adjusts its number of workers.
+type Autoscaling struct {
+	// MaxWorkers is the maximum number of workers. The number of workers given to NewPool is the minimum.
+	MaxWorkers int
+	// TargetLatency is how long the jobs can wait in the queue before a worker is added, according to Pool.QueueLatency.
+	// By default, a worker is added as soon as there are more jobs waiting than workers available.
+	TargetLatency time.Duration
+	// IdleTimeout is how long a worker added waits for a job before being stopped. By default, it is 30 seconds.
+	IdleTimeout time.Duration
+}
+
+func (a *Autoscaling) idleTimeout() time.Duration {
+	if a.IdleTimeout <= 0 {
+		return 30 * time.Second
+	}
+	return a.IdleTimeout
+}
+
+// WithAutoscaling lets the pool add workers when the jobs are piling up in the queue, up to Autoscaling.MaxWorkers,
+// and stop them once they have been idle for Autoscaling.IdleTimeout, so the pool doesn't have to be sized for the peak.
+// The workers are added when the jobs are submitted.
+func WithAutoscaling(scaling Autoscaling) PoolOption {
+	return func(p *Pool) {
+		p.scaling = &scaling
+	}
+}
+
+// Workers returns the number of workers running.
+func (p *Pool) Workers() int {
+	return int(atomic.LoadInt32(&p.running))
+}
+
+// scaleUp starts a new worker if there are more jobs waiting in the queue than workers available to execute them. It must be called while the pool is not closed.
+func (p *Pool) scaleUp() {
+	if p.scaling == nil {
+		return
+	}
+	available := atomic.LoadInt32(&p.running) - atomic.LoadInt32(&p.busy)
+	if int32(p.jobs.len()) <= available {
+		return
+	}
+	if p.scaling.TargetLatency > 0 && p.latency.get() < p.scaling.TargetLatency {
+		return
+	}
+	p.scaleMutex.Lock()
+	defer p.scaleMutex.Unlock()
+	if len(p.free) == 0 {
+		return
+	}
+	worker := p.free[len(p.free)-1]
+	p.free = p.free[:len(p.free)-1]
+	atomic.AddInt32(&p.running, 1)
+	p.workers.Add(1)
+	go p.work(worker)
+}
+
+// retire stops counting the worker that has been idle for too long. It returns false if the worker must keep running because jobs are waiting.
+func (p *Pool) retire(worker int) bool {
+	p.scaleMutex.Lock()
+	defer p.scaleMutex.Unlock()
+	if p.jobs.len() > 0 {
+		return false
+	}
+	p.free = append(p.free, worker)
+	atomic.AddInt32(&p.running, -1)
+	return true
+}
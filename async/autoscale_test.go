@@ -0,0 +1,68 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPool_Autoscaling(t *testing.T) {
+	for name, opts := range map[string][]PoolOption{
+		"shared queue":  nil,
+		"work stealing": {WithWorkStealing()},
+	} {
+		t.Run(name, func(t *testing.T) {
+			opts = append(opts, WithAutoscaling(Autoscaling{MaxWorkers: 3, IdleTimeout: 20 * time.Millisecond}))
+			pool := NewPool(1, 10, opts...)
+			defer pool.Shutdown(context.Background())
+			assert.Equal(t, 1, pool.Workers())
+			release := make(chan struct{})
+			var futures []ErrFuture[int]
+			for i := 0; i < 5; i++ {
+				value := i
+				futures = append(futures, Submit(pool, func(_ context.Context) (int, error) {
+					<-release
+					return value, nil
+				}))
+			}
+			// the jobs are waiting while every worker is busy, so the pool grows up to the maximum
+			assert.Eventually(t, func() bool { return pool.Workers() == 3 }, time.Second, time.Millisecond)
+			close(release)
+			for i, future := range futures {
+				result, err := future.Await()
+				assert.NoError(t, err)
+				assert.Equal(t, i, result)
+			}
+			// the workers added are stopped once idle, down to the minimum
+			assert.Eventually(t, func() bool { return pool.Workers() == 1 }, time.Second, time.Millisecond)
+			result, err := Submit(pool, func(_ context.Context) (int, error) { return 42, nil }).Await()
+			assert.NoError(t, err)
+			assert.Equal(t, 42, result)
+		})
+	}
+}
+
+func TestPool_Autoscaling_TargetLatency(t *testing.T) {
+	pool := NewPool(1, 10, WithAutoscaling(Autoscaling{MaxWorkers: 3, TargetLatency: time.Hour}))
+	defer pool.Shutdown(context.Background())
+	release := blockPool(t, pool)
+	defer release()
+	Submit(pool, func(_ context.Context) (int, error) { return 1, nil })
+	// the jobs have not waited long enough to add a worker
+	assert.Equal(t, 1, pool.Workers())
+}
@@ -0,0 +1,166 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/perses/common/clock"
+)
+
+// ErrBatcherClosed is returned when adding an item to a Batcher already closed.
+var ErrBatcherClosed = fmt.Errorf("batcher closed")
+
+// Batcher accumulates items and flushes them in batch, once the batch reaches its maximum size
+// or once the oldest item of the batch reaches the maximum age.
+// The flush function can be called concurrently when the batches are filled faster than they are flushed.
+type Batcher[T any] struct {
+	maxSize int
+	maxAge  time.Duration
+	flush   func(ctx context.Context, batch []T) error
+	clock   clock.Clock
+	name    string
+	logger  Logger
+	mutex   sync.Mutex
+	items   []T
+	timer   clock.Timer
+	// generation is increased every time a batch is taken, so a timer that fired for a batch already flushed is ignored.
+	generation uint64
+	closed     bool
+	flushes    sync.WaitGroup
+}
+
+// NewBatcher creates a Batcher calling flush for every batch.
+// maxSize <= 0 disables the flush on size and maxAge <= 0 disables the flush on age.
+// An error returned by a flush triggered by the age is given to the Logger, since there is no caller to return it to.
+func NewBatcher[T any](maxSize int, maxAge time.Duration, flush func(ctx context.Context, batch []T) error, opts ...ClockOption) *Batcher[T] {
+	return &Batcher[T]{
+		maxSize: maxSize,
+		maxAge:  maxAge,
+		flush:   flush,
+		clock:   newClock(opts),
+		name:    "batcher",
+		logger:  NewLogrusLogger(nil),
+	}
+}
+
+// SetLogger sets the name identifying the batcher and the Logger receiving an EventFailed, or an EventPanicked,
+// for each flush triggered by the age that failed. By default, the name is "batcher" and the logger uses logrus.
+// It must be called before the first item is added.
+func (b *Batcher[T]) SetLogger(name string, logger Logger) *Batcher[T] {
+	b.name = name
+	b.logger = logger
+	return b
+}
+
+// Add appends the item to the current batch. When the batch is full, it is flushed by the caller,
+// so the producers are slowed down when the flush cannot keep up. The error returned is the one of this flush.
+func (b *Batcher[T]) Add(ctx context.Context, item T) error {
+	b.mutex.Lock()
+	if b.closed {
+		b.mutex.Unlock()
+		return ErrBatcherClosed
+	}
+	b.items = append(b.items, item)
+	if len(b.items) == 1 && b.maxAge > 0 {
+		generation := b.generation
+		b.timer = b.clock.AfterFunc(b.maxAge, func() {
+			b.flushAged(generation)
+		})
+	}
+	if b.maxSize <= 0 || len(b.items) < b.maxSize {
+		b.mutex.Unlock()
+		return nil
+	}
+	batch := b.take()
+	b.mutex.Unlock()
+	return b.doFlush(ctx, batch)
+}
+
+// Flush flushes the current batch immediately, whatever its size or age.
+func (b *Batcher[T]) Flush(ctx context.Context) error {
+	b.mutex.Lock()
+	batch := b.take()
+	b.mutex.Unlock()
+	return b.doFlush(ctx, batch)
+}
+
+// Close flushes the current batch and waits for all the flushes in progress to end or for the context to be done.
+// Once closed, the batcher refuses new items.
+func (b *Batcher[T]) Close(ctx context.Context) error {
+	b.mutex.Lock()
+	b.closed = true
+	batch := b.take()
+	b.mutex.Unlock()
+	err := b.doFlush(ctx, batch)
+	done := make(chan struct{})
+	go func() {
+		b.flushes.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (b *Batcher[T]) flushAged(generation uint64) {
+	b.mutex.Lock()
+	if generation != b.generation {
+		// the batch has already been flushed
+		b.mutex.Unlock()
+		return
+	}
+	batch := b.take()
+	b.mutex.Unlock()
+	if err := b.doFlush(context.Background(), batch); err != nil {
+		eventType := EventFailed
+		var panicked *ErrPanicked
+		if errors.As(err, &panicked) {
+			eventType = EventPanicked
+		}
+		b.logger.Log(Event{Type: eventType, Kind: KindAsync, Name: b.name, Err: fmt.Errorf("unable to flush a batch of %d items: %w", len(batch), err)})
+	}
+}
+
+// take returns the current batch and resets it. It must be called with the mutex held.
+func (b *Batcher[T]) take() []T {
+	batch := b.items
+	b.items = nil
+	b.generation++
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+	if len(batch) > 0 {
+		b.flushes.Add(1)
+	}
+	return batch
+}
+
+func (b *Batcher[T]) doFlush(ctx context.Context, batch []T) error {
+	if len(batch) == 0 {
+		return nil
+	}
+	defer b.flushes.Done()
+	return protect(func() error {
+		return b.flush(ctx, batch)
+	})
+}
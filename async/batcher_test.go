@@ -0,0 +1,85 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type batchRecorder struct {
+	mutex   sync.Mutex
+	batches [][]int
+}
+
+func (r *batchRecorder) flush(_ context.Context, batch []int) error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.batches = append(r.batches, batch)
+	return nil
+}
+
+func (r *batchRecorder) get() [][]int {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.batches
+}
+
+func TestBatcher_Size(t *testing.T) {
+	r := &batchRecorder{}
+	b := NewBatcher(3, 0, r.flush)
+	ctx := context.Background()
+	for i := 1; i <= 7; i++ {
+		assert.NoError(t, b.Add(ctx, i))
+	}
+	assert.Equal(t, [][]int{{1, 2, 3}, {4, 5, 6}}, r.get())
+	assert.NoError(t, b.Close(ctx))
+	assert.Equal(t, [][]int{{1, 2, 3}, {4, 5, 6}, {7}}, r.get())
+	assert.ErrorIs(t, b.Add(ctx, 8), ErrBatcherClosed)
+}
+
+func TestBatcher_Age(t *testing.T) {
+	r := &batchRecorder{}
+	b := NewBatcher(100, 20*time.Millisecond, r.flush)
+	assert.NoError(t, b.Add(context.Background(), 1))
+	assert.NoError(t, b.Add(context.Background(), 2))
+	assert.Eventually(t, func() bool { return len(r.get()) == 1 }, time.Second, 5*time.Millisecond)
+	assert.Equal(t, [][]int{{1, 2}}, r.get())
+	assert.NoError(t, b.Close(context.Background()))
+	assert.Len(t, r.get(), 1)
+}
+
+func TestBatcher_Logger(t *testing.T) {
+	events := make(chan Event, 1)
+	b := NewBatcher(100, 10*time.Millisecond, func(_ context.Context, _ []int) error {
+		return fmt.Errorf("unavailable")
+	}).SetLogger("metrics", LoggerFunc(func(event Event) {
+		events <- event
+	}))
+	assert.NoError(t, b.Add(context.Background(), 1))
+	select {
+	case event := <-events:
+		assert.Equal(t, EventFailed, event.Type)
+		assert.Equal(t, "metrics", event.Name)
+		assert.EqualError(t, event.Err, "unable to flush a batch of 1 items: unavailable")
+	case <-time.After(time.Second):
+		t.Fatal("the failed flush has not been logged")
+	}
+	assert.NoError(t, b.Close(context.Background()))
+}
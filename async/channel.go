@@ -0,0 +1,158 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"sync"
+)
+
+// FanOut distributes the items of the channel in between n channels, so they can be consumed concurrently.
+// Each item is sent to only one of the channels returned, the first one ready to receive it.
+// The channels returned are closed once in is closed or once the context is done.
+func FanOut[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+	if n < 1 {
+		n = 1
+	}
+	outs := make([]<-chan T, 0, n)
+	for i := 0; i < n; i++ {
+		out := make(chan T)
+		outs = append(outs, out)
+		go func() {
+			defer close(out)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case item, ok := <-in:
+					if !ok {
+						return
+					}
+					select {
+					case <-ctx.Done():
+						return
+					case out <- item:
+					}
+				}
+			}
+		}()
+	}
+	return outs
+}
+
+// FanIn merges the items of every channel given into a single one.
+// The channel returned is closed once every channel given is closed or once the context is done.
+func FanIn[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	out := make(chan T)
+	wg := &sync.WaitGroup{}
+	wg.Add(len(ins))
+	for _, in := range ins {
+		go func(in <-chan T) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case item, ok := <-in:
+					if !ok {
+						return
+					}
+					select {
+					case <-ctx.Done():
+						return
+					case out <- item:
+					}
+				}
+			}
+		}(in)
+	}
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+	return out
+}
+
+// Merge is merging the items of every channel given into a single one. It is the same as FanIn.
+func Merge[T any](ctx context.Context, ins ...<-chan T) <-chan T {
+	return FanIn(ctx, ins...)
+}
+
+// BackpressurePolicy defines what Broadcast does with an item when a consumer is not ready to receive it.
+type BackpressurePolicy int
+
+const (
+	// BackpressureBlock waits for every consumer to receive the item, so the slowest consumer sets the pace for all of them.
+	BackpressureBlock BackpressurePolicy = iota
+	// BackpressureDrop skips the item for the consumers whose buffer is full, so a slow consumer doesn't slow down the others.
+	BackpressureDrop
+)
+
+// Tee duplicates every item of the channel into n channels. Each item is received by every channel returned.
+// The items are sent without buffering, so the slowest consumer sets the pace for all of them. Use Broadcast to change that.
+// The channels returned are closed once in is closed or once the context is done.
+func Tee[T any](ctx context.Context, in <-chan T, n int) []<-chan T {
+	return Broadcast(ctx, in, n, 0, BackpressureBlock)
+}
+
+// Broadcast duplicates every item of the channel into n channels having a buffer of the size given.
+// The policy decides what happens when the buffer of a consumer is full.
+// The channels returned are closed once in is closed or once the context is done.
+func Broadcast[T any](ctx context.Context, in <-chan T, n int, buffer int, policy BackpressurePolicy) []<-chan T {
+	if n < 1 {
+		n = 1
+	}
+	if buffer < 0 {
+		buffer = 0
+	}
+	outs := make([]chan T, 0, n)
+	results := make([]<-chan T, 0, n)
+	for i := 0; i < n; i++ {
+		out := make(chan T, buffer)
+		outs = append(outs, out)
+		results = append(results, out)
+	}
+	go func() {
+		defer func() {
+			for _, out := range outs {
+				close(out)
+			}
+		}()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case item, ok := <-in:
+				if !ok {
+					return
+				}
+				for _, out := range outs {
+					if policy == BackpressureDrop {
+						select {
+						case out <- item:
+						default:
+						}
+						continue
+					}
+					select {
+					case <-ctx.Done():
+						return
+					case out <- item:
+					}
+				}
+			}
+		}
+	}()
+	return results
+}
@@ -0,0 +1,96 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func generate(n int) <-chan int {
+	c := make(chan int)
+	go func() {
+		defer close(c)
+		for i := 0; i < n; i++ {
+			c <- i
+		}
+	}()
+	return c
+}
+
+func collect[T any](c <-chan T) []T {
+	var result []T
+	for item := range c {
+		result = append(result, item)
+	}
+	return result
+}
+
+func TestFanOutFanIn(t *testing.T) {
+	ctx := context.Background()
+	outs := FanOut(ctx, generate(100), 4)
+	assert.Len(t, outs, 4)
+	result := collect(FanIn(ctx, outs...))
+	sort.Ints(result)
+	assert.Len(t, result, 100)
+	for i, item := range result {
+		assert.Equal(t, i, item)
+	}
+}
+
+func TestFanIn_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	never := make(chan int)
+	out := FanIn(ctx, never)
+	cancel()
+	// the channel must be closed even though the input is never closed
+	_, ok := <-out
+	assert.False(t, ok)
+}
+
+func TestTee(t *testing.T) {
+	outs := Tee(context.Background(), generate(10), 3)
+	assert.Len(t, outs, 3)
+	results := make([][]int, len(outs))
+	wg := &sync.WaitGroup{}
+	wg.Add(len(outs))
+	for i, out := range outs {
+		go func(i int, out <-chan int) {
+			defer wg.Done()
+			results[i] = collect(out)
+		}(i, out)
+	}
+	wg.Wait()
+	for _, result := range results {
+		assert.Equal(t, []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}, result)
+	}
+}
+
+func TestBroadcast_Drop(t *testing.T) {
+	in := make(chan int)
+	outs := Broadcast(context.Background(), in, 2, 2, BackpressureDrop)
+	for i := 0; i < 5; i++ {
+		in <- i
+		// the first channel is read every time, so it doesn't miss any item
+		assert.Equal(t, i, <-outs[0])
+	}
+	close(in)
+	// nobody reads the second channel while sending, so it only keeps what its buffer could hold
+	assert.Equal(t, []int{0, 1}, collect(outs[1]))
+	assert.Empty(t, collect(outs[0]))
+}
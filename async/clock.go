@@ -0,0 +1,40 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"github.com/perses/common/clock"
+)
+
+type clockConfig struct {
+	clock clock.Clock
+}
+
+// ClockOption configures the Clock used by a feature of the package depending on the time, like Debounce, Throttle or Batcher.
+type ClockOption func(c *clockConfig)
+
+// WithClock sets the Clock to use instead of clock.Real.
+func WithClock(c clock.Clock) ClockOption {
+	return func(cfg *clockConfig) {
+		cfg.clock = clock.OrReal(c)
+	}
+}
+
+func newClock(opts []ClockOption) clock.Clock {
+	cfg := &clockConfig{clock: clock.Real}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	return cfg.clock
+}
@@ -0,0 +1,205 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+)
+
+// awaitable is the part of TypedFuture and ErrFuture used by the combinators.
+type awaitable[T any] interface {
+	AwaitResult(ctx context.Context) (T, error)
+	Cancel()
+}
+
+// errAwaitable gives the AwaitResult of an ErrFuture: its failure is the error it returned.
+type errAwaitable[T any] struct {
+	ErrFuture[T]
+}
+
+func (f errAwaitable[T]) AwaitResult(ctx context.Context) (T, error) {
+	return f.AwaitWithContext(ctx)
+}
+
+func typedAwaitables[T any](futures []TypedFuture[T]) []awaitable[T] {
+	result := make([]awaitable[T], 0, len(futures))
+	for _, future := range futures {
+		result = append(result, future)
+	}
+	return result
+}
+
+func errAwaitables[T any](futures []ErrFuture[T]) []awaitable[T] {
+	result := make([]awaitable[T], 0, len(futures))
+	for _, future := range futures {
+		result = append(result, errAwaitable[T]{ErrFuture: future})
+	}
+	return result
+}
+
+// AwaitAll waits for every future given and returns their results in the same order.
+// If a future failed (cancelled or panicked), it stops waiting and returns this failure, ErrCancelled or an *ErrPanicked.
+// If the context is done before every future ends, it stops waiting and returns the error of the context.
+func AwaitAll[T any](ctx context.Context, futures ...TypedFuture[T]) ([]T, error) {
+	return awaitAll(ctx, typedAwaitables(futures))
+}
+
+// AwaitAllErr is AwaitAll for the ErrFuture: it stops waiting and returns the error of the first future that failed.
+func AwaitAllErr[T any](ctx context.Context, futures ...ErrFuture[T]) ([]T, error) {
+	return awaitAll(ctx, errAwaitables(futures))
+}
+
+func awaitAll[T any](ctx context.Context, futures []awaitable[T]) ([]T, error) {
+	results := make([]T, 0, len(futures))
+	for _, future := range futures {
+		result, err := future.AwaitResult(ctx)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Race waits for the first future to succeed and returns its result.
+// Once a future has succeeded, the others are cancelled. The futures that failed (cancelled or panicked) are skipped,
+// an error aggregating their failures in a MultiError is returned only when every future failed.
+// If the context is done before any future succeeds, it returns the error of the context.
+func Race[T any](ctx context.Context, futures ...TypedFuture[T]) (T, error) {
+	return race(ctx, typedAwaitables(futures))
+}
+
+// RaceErr is Race for the ErrFuture: the futures returning an error are skipped.
+func RaceErr[T any](ctx context.Context, futures ...ErrFuture[T]) (T, error) {
+	return race(ctx, errAwaitables(futures))
+}
+
+func race[T any](ctx context.Context, futures []awaitable[T]) (T, error) {
+	var zero T
+	if len(futures) == 0 {
+		return zero, fmt.Errorf("no future to race")
+	}
+	raceCtx, cancel := context.WithCancel(ctx)
+	// cancel stops every go-routine still waiting for a future once the race is over.
+	defer cancel()
+	type raceResult struct {
+		index  int
+		result T
+		err    error
+	}
+	// results is buffered so the go-routines waiting for the losers never block.
+	results := make(chan raceResult, len(futures))
+	for i, future := range futures {
+		go func(index int, f awaitable[T]) {
+			result, err := f.AwaitResult(raceCtx)
+			if raceCtx.Err() == nil {
+				results <- raceResult{index: index, result: result, err: err}
+			}
+		}(i, future)
+	}
+	var errs MultiError
+	for len(errs) < len(futures) {
+		select {
+		case <-ctx.Done():
+			return zero, ctx.Err()
+		case r := <-results:
+			if r.err != nil {
+				errs = append(errs, r.err)
+				continue
+			}
+			for i, future := range futures {
+				if i != r.index {
+					future.Cancel()
+				}
+			}
+			return r.result, nil
+		}
+	}
+	return zero, errs
+}
+
+// AwaitN waits for the first n futures to succeed and returns their results, in the order they ended.
+// Once n futures have succeeded, the others are cancelled since their results are not needed anymore.
+// The futures that failed (cancelled or panicked) don't count, once too many failed for n of them to succeed,
+// it returns an error aggregating their failures in a MultiError.
+// If the context is done before n futures succeed, it returns the error of the context.
+func AwaitN[T any](ctx context.Context, n int, futures ...TypedFuture[T]) ([]T, error) {
+	return awaitN(ctx, n, typedAwaitables(futures))
+}
+
+// AwaitNErr is AwaitN for the ErrFuture: the futures returning an error don't count.
+func AwaitNErr[T any](ctx context.Context, n int, futures ...ErrFuture[T]) ([]T, error) {
+	return awaitN(ctx, n, errAwaitables(futures))
+}
+
+func awaitN[T any](ctx context.Context, n int, futures []awaitable[T]) ([]T, error) {
+	if n < 0 || n > len(futures) {
+		return nil, fmt.Errorf("cannot wait for %d futures out of %d", n, len(futures))
+	}
+	if n == 0 {
+		for _, future := range futures {
+			future.Cancel()
+		}
+		return []T{}, nil
+	}
+	awaitCtx, cancel := context.WithCancel(ctx)
+	// cancel stops every go-routine still waiting for a future once enough futures ended.
+	defer cancel()
+	type indexedResult struct {
+		index  int
+		result T
+		err    error
+	}
+	// results is buffered so the go-routines waiting for the futures never block.
+	results := make(chan indexedResult, len(futures))
+	for i, future := range futures {
+		go func(index int, f awaitable[T]) {
+			result, err := f.AwaitResult(awaitCtx)
+			if awaitCtx.Err() == nil {
+				results <- indexedResult{index: index, result: result, err: err}
+			}
+		}(i, future)
+	}
+	ended := make(map[int]bool, len(futures))
+	values := make([]T, 0, n)
+	var errs MultiError
+	for len(values) < n {
+		if len(futures)-len(errs) < n {
+			for i, future := range futures {
+				if !ended[i] {
+					future.Cancel()
+				}
+			}
+			return nil, errs
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case r := <-results:
+			ended[r.index] = true
+			if r.err != nil {
+				errs = append(errs, r.err)
+				continue
+			}
+			values = append(values, r.result)
+		}
+	}
+	for i, future := range futures {
+		if !ended[i] {
+			future.Cancel()
+		}
+	}
+	return values, nil
+}
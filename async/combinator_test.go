@@ -0,0 +1,166 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAwaitAll(t *testing.T) {
+	futures := make([]TypedFuture[int], 0, 3)
+	for i := 0; i < 3; i++ {
+		value := i
+		futures = append(futures, Go(func() int {
+			time.Sleep(time.Duration(3-value) * 10 * time.Millisecond)
+			return value
+		}))
+	}
+	results, err := AwaitAll(context.Background(), futures...)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, results)
+}
+
+func TestAwaitAll_ContextDone(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	results, err := AwaitAll(ctx, Go(func() int { return 1 }), Go(doneAsync))
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Nil(t, results)
+}
+
+func TestAwaitAll_Failure(t *testing.T) {
+	cancelled := Go(doneAsync)
+	cancelled.Cancel()
+	results, err := AwaitAll(context.Background(), Go(func() int { return 1 }), cancelled)
+	assert.Equal(t, ErrCancelled, err)
+	assert.Nil(t, results)
+
+	panicked := Go(func() int { panic("boom") })
+	results, err = AwaitAll(context.Background(), panicked, Go(func() int { return 1 }))
+	var errPanicked *ErrPanicked
+	assert.ErrorAs(t, err, &errPanicked)
+	assert.Nil(t, results)
+}
+
+func TestRace(t *testing.T) {
+	slow := AsyncWithContext(context.Background(), func(ctx context.Context) int {
+		<-ctx.Done()
+		return 1
+	})
+	fast := Go(func() int {
+		return 2
+	})
+	result, err := Race(context.Background(), slow, fast)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result)
+	// the loser must have been cancelled
+	assert.Equal(t, 0, slow.Await())
+}
+
+func TestRace_Failure(t *testing.T) {
+	panicked := Go(func() int { panic("boom") })
+	cancelled := Go(doneAsync)
+	cancelled.Cancel()
+	slow := Go(func() int {
+		time.Sleep(20 * time.Millisecond)
+		return 3
+	})
+	result, err := Race(context.Background(), panicked, cancelled, slow)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result)
+
+	panicked = Go(func() int { panic("boom") })
+	cancelled = Go(doneAsync)
+	cancelled.Cancel()
+	_, err = Race(context.Background(), panicked, cancelled)
+	var errPanicked *ErrPanicked
+	assert.ErrorAs(t, err, &errPanicked)
+	assert.ErrorIs(t, err, ErrCancelled)
+}
+
+func TestAwaitN(t *testing.T) {
+	slow := AsyncWithContext(context.Background(), func(ctx context.Context) int {
+		<-ctx.Done()
+		return 1
+	})
+	results, err := AwaitN(context.Background(), 2, slow, Go(func() int { return 2 }), Go(func() int { return 3 }))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{2, 3}, results)
+	// the future not needed must have been cancelled
+	assert.Equal(t, 0, slow.Await())
+
+	_, err = AwaitN(context.Background(), 2, Go(func() int { return 1 }))
+	assert.Error(t, err)
+}
+
+func TestAwaitN_Failure(t *testing.T) {
+	panicked := Go(func() int { panic("boom") })
+	results, err := AwaitN(context.Background(), 2, panicked, Go(func() int { return 2 }), Go(func() int {
+		time.Sleep(20 * time.Millisecond)
+		return 3
+	}))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, results)
+
+	cancelled := Go(doneAsync)
+	cancelled.Cancel()
+	slow := AsyncWithContext(context.Background(), func(ctx context.Context) int {
+		<-ctx.Done()
+		return 1
+	})
+	_, err = AwaitN(context.Background(), 2, cancelled, Go(func() int { panic("boom") }), slow)
+	assert.ErrorIs(t, err, ErrCancelled)
+	// the future still pending cannot make up for the failures, it must have been cancelled
+	assert.Equal(t, 0, slow.Await())
+}
+
+func TestAwaitAllErr(t *testing.T) {
+	results, err := AwaitAllErr(context.Background(), Completed(1), AsyncErr(func() (int, error) { return 2, nil }))
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 2}, results)
+
+	results, err = AwaitAllErr(context.Background(), Completed(1), Failed[int](fmt.Errorf("failure")))
+	assert.EqualError(t, err, "failure")
+	assert.Nil(t, results)
+}
+
+func TestRaceErr(t *testing.T) {
+	slow := AsyncErrWithContext(context.Background(), func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 1, ctx.Err()
+	})
+	result, err := RaceErr(context.Background(), Failed[int](fmt.Errorf("failure")), slow, Completed(2))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result)
+	// the loser must have been cancelled
+	_, err = slow.Await()
+	assert.Equal(t, ErrCancelled, err)
+
+	_, err = RaceErr(context.Background(), Failed[int](fmt.Errorf("first")), Failed[int](fmt.Errorf("second")))
+	assert.Error(t, err)
+}
+
+func TestAwaitNErr(t *testing.T) {
+	results, err := AwaitNErr(context.Background(), 2, Failed[int](fmt.Errorf("failure")), Completed(2), Completed(3))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []int{2, 3}, results)
+
+	_, err = AwaitNErr(context.Background(), 2, Failed[int](fmt.Errorf("failure")), Completed(2))
+	assert.EqualError(t, err, "failure")
+}
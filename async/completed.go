@@ -0,0 +1,29 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+// noCancel is the cancel function of the futures created already completed, there is nothing to cancel.
+func noCancel() {}
+
+// Completed returns an ErrFuture already completed with the given result. No go-routine is started.
+// It is meant for the paths where the result is known right away, like a cache hit, but where a future is required for the API to be uniform.
+// Awaiting it never blocks nor allocates.
+func Completed[T any](result T) ErrFuture[T] {
+	return &errNext[T]{state: &state[T]{status: stateDone, result: result, cancel: noCancel}}
+}
+
+// Failed returns an ErrFuture already completed with the given error. No go-routine is started.
+func Failed[T any](err error) ErrFuture[T] {
+	return &errNext[T]{state: &state[T]{status: stateDone, err: err, cancel: noCancel}}
+}
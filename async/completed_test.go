@@ -0,0 +1,50 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompleted(t *testing.T) {
+	future := Completed(42)
+	assert.True(t, future.IsDone())
+	result, err := future.Await()
+	assert.NoError(t, err)
+	assert.Equal(t, 42, result)
+	allocs := testing.AllocsPerRun(100, func() {
+		_, _ = future.Await()
+	})
+	assert.Equal(t, float64(0), allocs)
+	// cancelling a future already completed has no effect
+	future.Cancel()
+	result, err = future.Await()
+	assert.NoError(t, err)
+	assert.Equal(t, 42, result)
+}
+
+func TestFailed(t *testing.T) {
+	failure := errors.New("failure")
+	future := Failed[int](failure)
+	assert.True(t, future.IsDone())
+	_, err := future.Await()
+	assert.Equal(t, failure, err)
+	result, err, done := future.TryAwait()
+	assert.Equal(t, 0, result)
+	assert.Equal(t, failure, err)
+	assert.True(t, done)
+}
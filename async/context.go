@@ -0,0 +1,65 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"time"
+)
+
+// detachedContext holds the values of its parent but not its cancellation nor its deadline.
+type detachedContext struct {
+	parent context.Context
+	// keys are the keys of the values kept. When nil, every value is kept.
+	keys []interface{}
+}
+
+// Detach returns a context holding the values of ctx for the given keys, but never cancelled, whatever happens to ctx.
+// If no key is given, every value of ctx is kept.
+// It is the way to keep the request ID, the tenant or the principal of the caller in an asynchronous function that must outlive the caller:
+//
+//	future := async.AsyncErrWithContext(async.Detach(ctx, requestIDKey, tenantKey), func(ctx context.Context) (int, error) {
+//		// ctx is not cancelled when the request ends, but it still holds its request ID
+//		return sendNotification(ctx)
+//	})
+func Detach(ctx context.Context, keys ...interface{}) context.Context {
+	if len(keys) == 0 {
+		keys = nil
+	}
+	return detachedContext{parent: ctx, keys: keys}
+}
+
+func (d detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (d detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (d detachedContext) Err() error {
+	return nil
+}
+
+func (d detachedContext) Value(key interface{}) interface{} {
+	if d.keys == nil {
+		return d.parent.Value(key)
+	}
+	for _, k := range d.keys {
+		if k == key {
+			return d.parent.Value(key)
+		}
+	}
+	return nil
+}
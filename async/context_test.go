@@ -0,0 +1,49 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type contextKey string
+
+func TestDetach(t *testing.T) {
+	ctx := context.WithValue(context.Background(), contextKey("request"), "42")
+	ctx = context.WithValue(ctx, contextKey("tenant"), "perses")
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	cancel()
+
+	detached := Detach(ctx, contextKey("request"))
+	assert.NoError(t, detached.Err())
+	assert.Nil(t, detached.Done())
+	_, hasDeadline := detached.Deadline()
+	assert.False(t, hasDeadline)
+	assert.Equal(t, "42", detached.Value(contextKey("request")))
+	assert.Nil(t, detached.Value(contextKey("tenant")))
+
+	all := Detach(ctx)
+	assert.Equal(t, "perses", all.Value(contextKey("tenant")))
+
+	// the asynchronous function is not cancelled with the caller, but it still sees the request ID
+	result, err := AsyncErrWithContext(detached, func(ctx context.Context) (interface{}, error) {
+		return ctx.Value(contextKey("request")), ctx.Err()
+	}).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, "42", result)
+}
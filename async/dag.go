@@ -0,0 +1,157 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// DAGFunc is the function of a node of a DAG. inputs holds the result of every dependency of the node, by name.
+type DAGFunc func(ctx context.Context, inputs map[string]interface{}) (interface{}, error)
+
+type dagNode struct {
+	name string
+	deps []string
+	f    DAGFunc
+}
+
+// DAG executes functions depending on the results of each other. Every node starts as soon as all its dependencies ended,
+// so the independent nodes run concurrently without having to chain the Await manually:
+//
+//	dag := async.NewDAG()
+//	_ = dag.Add("user", nil, fetchUser)
+//	_ = dag.Add("orders", []string{"user"}, fetchOrders)
+//	_ = dag.Add("recommendations", []string{"user"}, fetchRecommendations)
+//	_ = dag.Add("page", []string{"orders", "recommendations"}, buildPage)
+//	results, err := dag.Run(ctx)
+type DAG struct {
+	mutex sync.Mutex
+	nodes map[string]*dagNode
+	// names keeps the order the nodes are added, so the execution is deterministic.
+	names []string
+}
+
+func NewDAG() *DAG {
+	return &DAG{nodes: make(map[string]*dagNode)}
+}
+
+// Add registers a node executing f once every node listed in deps ended successfully.
+// The dependencies don't need to be registered yet, they are checked by Run.
+func (d *DAG) Add(name string, deps []string, f DAGFunc) error {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if _, ok := d.nodes[name]; ok {
+		return fmt.Errorf("node '%s' already exists", name)
+	}
+	d.nodes[name] = &dagNode{name: name, deps: deps, f: f}
+	d.names = append(d.names, name)
+	return nil
+}
+
+// Run executes every node and returns their results by name.
+// The first node failing cancels the context given to the others, and its error is returned.
+// The nodes depending on a node that failed are not executed.
+// Run fails without executing anything if a dependency doesn't exist or if the dependencies form a cycle.
+func (d *DAG) Run(ctx context.Context) (map[string]interface{}, error) {
+	d.mutex.Lock()
+	order, err := d.sort()
+	d.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		once     sync.Once
+		firstErr error
+	)
+	states := make(map[string]*state[interface{}], len(order))
+	// the nodes are started in topological order, so the states of the dependencies of a node always exist when it starts.
+	for _, n := range order {
+		deps := make(map[string]*state[interface{}], len(n.deps))
+		for _, dep := range n.deps {
+			deps[dep] = states[dep]
+		}
+		node := n
+		states[node.name] = run(ctx, func(ctx context.Context) (interface{}, error) {
+			inputs := make(map[string]interface{}, len(deps))
+			for name, dep := range deps {
+				result, depErr := dep.wait(ctx)
+				if depErr != nil {
+					return nil, fmt.Errorf("dependency '%s' of the node '%s' failed: %w", name, node.name, depErr)
+				}
+				inputs[name] = result
+			}
+			result, nodeErr := node.f(ctx, inputs)
+			if nodeErr != nil {
+				nodeErr = fmt.Errorf("node '%s' failed: %w", node.name, nodeErr)
+				once.Do(func() {
+					firstErr = nodeErr
+					cancel()
+				})
+			}
+			return result, nodeErr
+		})
+	}
+	results := make(map[string]interface{}, len(order))
+	for name, s := range states {
+		if result, stateErr := s.wait(context.Background()); stateErr == nil {
+			results[name] = result
+		}
+	}
+	if firstErr == nil {
+		firstErr = ctx.Err()
+	}
+	return results, firstErr
+}
+
+// sort returns the nodes in topological order. It must be called with the mutex held.
+func (d *DAG) sort() ([]*dagNode, error) {
+	pending := make(map[string]int, len(d.nodes))
+	dependents := make(map[string][]string, len(d.nodes))
+	for _, name := range d.names {
+		n := d.nodes[name]
+		for _, dep := range n.deps {
+			if _, ok := d.nodes[dep]; !ok {
+				return nil, fmt.Errorf("node '%s' depends on the unknown node '%s'", name, dep)
+			}
+			dependents[dep] = append(dependents[dep], name)
+		}
+		pending[name] = len(n.deps)
+	}
+	order := make([]*dagNode, 0, len(d.nodes))
+	var ready []string
+	for _, name := range d.names {
+		if pending[name] == 0 {
+			ready = append(ready, name)
+		}
+	}
+	for len(ready) > 0 {
+		name := ready[0]
+		ready = ready[1:]
+		order = append(order, d.nodes[name])
+		for _, dependent := range dependents[name] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				ready = append(ready, dependent)
+			}
+		}
+	}
+	if len(order) != len(d.nodes) {
+		return nil, fmt.Errorf("the dependencies of the nodes form a cycle")
+	}
+	return order, nil
+}
@@ -0,0 +1,74 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDAG(t *testing.T) {
+	dag := NewDAG()
+	assert.NoError(t, dag.Add("sum", []string{"a", "b"}, func(_ context.Context, inputs map[string]interface{}) (interface{}, error) {
+		return inputs["a"].(int) + inputs["b"].(int), nil
+	}))
+	assert.NoError(t, dag.Add("a", nil, func(_ context.Context, _ map[string]interface{}) (interface{}, error) {
+		return 1, nil
+	}))
+	assert.NoError(t, dag.Add("b", []string{"a"}, func(_ context.Context, inputs map[string]interface{}) (interface{}, error) {
+		return inputs["a"].(int) + 1, nil
+	}))
+	assert.Error(t, dag.Add("a", nil, nil))
+	results, err := dag.Run(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"a": 1, "b": 2, "sum": 3}, results)
+}
+
+func TestDAG_Failure(t *testing.T) {
+	failure := fmt.Errorf("failure")
+	dag := NewDAG()
+	_ = dag.Add("a", nil, func(_ context.Context, _ map[string]interface{}) (interface{}, error) {
+		return nil, failure
+	})
+	_ = dag.Add("b", []string{"a"}, func(_ context.Context, _ map[string]interface{}) (interface{}, error) {
+		return nil, errors.New("must not be executed")
+	})
+	_ = dag.Add("c", nil, func(ctx context.Context, _ map[string]interface{}) (interface{}, error) {
+		// the failure of "a" cancels the other nodes
+		<-ctx.Done()
+		return nil, nil
+	})
+	results, err := dag.Run(context.Background())
+	assert.ErrorIs(t, err, failure)
+	assert.EqualError(t, err, "node 'a' failed: failure")
+	assert.Equal(t, map[string]interface{}{"c": nil}, results)
+}
+
+func TestDAG_Invalid(t *testing.T) {
+	noop := func(_ context.Context, _ map[string]interface{}) (interface{}, error) { return nil, nil }
+	dag := NewDAG()
+	_ = dag.Add("a", []string{"b"}, noop)
+	_ = dag.Add("b", []string{"a"}, noop)
+	_, err := dag.Run(context.Background())
+	assert.EqualError(t, err, "the dependencies of the nodes form a cycle")
+
+	dag = NewDAG()
+	_ = dag.Add("a", []string{"unknown"}, noop)
+	_, err = dag.Run(context.Background())
+	assert.EqualError(t, err, "node 'a' depends on the unknown node 'unknown'")
+}
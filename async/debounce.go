@@ -0,0 +1,136 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/perses/common/clock"
+)
+
+// newPendingState creates a state that is not running yet. The function is executed later by calling start.
+func newPendingState[T any]() (*state[T], context.Context) {
+	ctx, cancel := context.WithCancel(context.Background())
+	return newState[T](cancel), ctx
+}
+
+type debouncer[T any] struct {
+	wait  time.Duration
+	f     func() (T, error)
+	clock clock.Clock
+
+	mutex sync.Mutex
+	// generation changes at each call, so a timer fired for a previous call does nothing.
+	generation uint64
+	timer      clock.Timer
+	pending    *state[T]
+	pendingCtx context.Context
+}
+
+// Debounce returns a function delaying the execution of f until wait has elapsed since its last call.
+// Each call returns the ErrFuture of the next execution of f. It is shared by every call made before this execution,
+// so cancelling it cancels it for each of them.
+// It is useful to absorb a storm of events, like many configuration reloads in a row, and to react only once.
+func Debounce[T any](wait time.Duration, f func() (T, error), opts ...ClockOption) func() ErrFuture[T] {
+	d := &debouncer[T]{wait: wait, f: f, clock: newClock(opts)}
+	return d.call
+}
+
+func (d *debouncer[T]) call() ErrFuture[T] {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	if d.pending == nil {
+		d.pending, d.pendingCtx = newPendingState[T]()
+	}
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	d.generation++
+	generation := d.generation
+	d.timer = d.clock.AfterFunc(d.wait, func() {
+		d.fire(generation)
+	})
+	return &errNext[T]{state: d.pending}
+}
+
+func (d *debouncer[T]) fire(generation uint64) {
+	d.mutex.Lock()
+	if generation != d.generation {
+		d.mutex.Unlock()
+		return
+	}
+	s, ctx := d.pending, d.pendingCtx
+	d.pending, d.pendingCtx, d.timer = nil, nil, nil
+	d.mutex.Unlock()
+	s.execute(ctx, KindAsync, "", func(_ context.Context) (T, error) {
+		return d.f()
+	})
+}
+
+type throttler[T any] struct {
+	interval time.Duration
+	f        func() (T, error)
+	clock    clock.Clock
+
+	mutex sync.Mutex
+	// last is the time of the last execution.
+	last       time.Time
+	pending    *state[T]
+	pendingCtx context.Context
+}
+
+// Throttle returns a function executing f at most once per interval.
+// The first call executes f immediately. The calls made during the interval that follows are grouped in a single execution
+// at the end of the interval, and share the same ErrFuture.
+// It is useful to limit the cost of a frequent event, like a cache invalidation, while still reacting to the last one.
+func Throttle[T any](interval time.Duration, f func() (T, error), opts ...ClockOption) func() ErrFuture[T] {
+	t := &throttler[T]{interval: interval, f: f, clock: newClock(opts)}
+	return t.call
+}
+
+func (t *throttler[T]) call() ErrFuture[T] {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.pending != nil {
+		return &errNext[T]{state: t.pending}
+	}
+	s, ctx := newPendingState[T]()
+	now := t.clock.Now()
+	elapsed := now.Sub(t.last)
+	if elapsed >= t.interval {
+		t.last = now
+		go t.execute(s, ctx)
+		return &errNext[T]{state: s}
+	}
+	t.pending, t.pendingCtx = s, ctx
+	t.clock.AfterFunc(t.interval-elapsed, t.fire)
+	return &errNext[T]{state: s}
+}
+
+func (t *throttler[T]) fire() {
+	t.mutex.Lock()
+	s, ctx := t.pending, t.pendingCtx
+	t.pending, t.pendingCtx = nil, nil
+	t.last = t.clock.Now()
+	t.mutex.Unlock()
+	t.execute(s, ctx)
+}
+
+func (t *throttler[T]) execute(s *state[T], ctx context.Context) {
+	s.execute(ctx, KindAsync, "", func(_ context.Context) (T, error) {
+		return t.f()
+	})
+}
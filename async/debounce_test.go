@@ -0,0 +1,59 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDebounce(t *testing.T) {
+	var calls int32
+	reload := Debounce(20*time.Millisecond, func() (int32, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	})
+	futures := make([]ErrFuture[int32], 0, 5)
+	for i := 0; i < 5; i++ {
+		futures = append(futures, reload())
+		time.Sleep(5 * time.Millisecond)
+	}
+	for _, future := range futures {
+		result, err := future.Await()
+		assert.NoError(t, err)
+		assert.Equal(t, int32(1), result)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestThrottle(t *testing.T) {
+	var calls int32
+	invalidate := Throttle(30*time.Millisecond, func() (int32, error) {
+		return atomic.AddInt32(&calls, 1), nil
+	})
+	first, err := invalidate().Await()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), first)
+	// these calls are made during the interval, they are grouped in a single execution
+	second := invalidate()
+	third := invalidate()
+	result, err := second.Await()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), result)
+	result, _ = third.Await()
+	assert.Equal(t, int32(2), result)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,143 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+type delayed struct {
+	at   time.Time
+	fire func()
+	// index is the position of the function in the heap, or -1 once it has been removed.
+	index int
+}
+
+// delayedHeap is a min-heap of delayed functions, ordered by the time they must be fired.
+type delayedHeap []*delayed
+
+func (h delayedHeap) Len() int           { return len(h) }
+func (h delayedHeap) Less(i, j int) bool { return h[i].at.Before(h[j].at) }
+
+func (h delayedHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *delayedHeap) Push(x interface{}) {
+	item := x.(*delayed)
+	item.index = len(*h)
+	*h = append(*h, item)
+}
+
+func (h *delayedHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	item.index = -1
+	*h = old[:n-1]
+	return item
+}
+
+// timers fires every delayed function using a single runtime timer, set to the earliest one.
+// No go-routine is waiting while the delays are not elapsed, which is what makes it cheap to schedule thousands of them.
+type timers struct {
+	mutex   sync.Mutex
+	pending delayedHeap
+	timer   *time.Timer
+}
+
+var defaultTimers = &timers{}
+
+func (t *timers) add(at time.Time, fire func()) *delayed {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	d := &delayed{at: at, fire: fire}
+	heap.Push(&t.pending, d)
+	if t.pending[0] != d {
+		// the timer is already set to an earlier time
+		return d
+	}
+	if t.timer == nil {
+		t.timer = time.AfterFunc(time.Until(at), t.process)
+	} else {
+		t.timer.Reset(time.Until(at))
+	}
+	return d
+}
+
+// remove drops the delayed function from the heap if it has not been fired yet, so a cancelled function doesn't hold its memory until its time.
+func (t *timers) remove(d *delayed) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if d.index < 0 {
+		return
+	}
+	first := d.index == 0
+	heap.Remove(&t.pending, d.index)
+	if !first {
+		return
+	}
+	if len(t.pending) == 0 {
+		t.timer.Stop()
+	} else {
+		t.timer.Reset(time.Until(t.pending[0].at))
+	}
+}
+
+// process fires the delayed functions that are due and sets the timer to the next one.
+func (t *timers) process() {
+	t.mutex.Lock()
+	now := time.Now()
+	var due []*delayed
+	for len(t.pending) > 0 && !t.pending[0].at.After(now) {
+		due = append(due, heap.Pop(&t.pending).(*delayed))
+	}
+	if len(t.pending) > 0 {
+		t.timer.Reset(time.Until(t.pending[0].at))
+	}
+	t.mutex.Unlock()
+	for _, d := range due {
+		d.fire()
+	}
+}
+
+// RunAfter executes the asynchronous function once the delay is elapsed.
+// Cancelling the ErrFuture before the delay is elapsed prevents the function from being executed.
+func RunAfter[T any](delay time.Duration, f func(ctx context.Context) (T, error)) ErrFuture[T] {
+	return RunAt(time.Now().Add(delay), f)
+}
+
+// RunAt executes the asynchronous function at the given time, or immediately if the time is already passed.
+// Cancelling the ErrFuture before this time prevents the function from being executed.
+func RunAt[T any](at time.Time, f func(ctx context.Context) (T, error)) ErrFuture[T] {
+	s, ctx := newPendingState[T]()
+	s.submitter = submitter()
+	d := defaultTimers.add(at, func() {
+		if s.IsDone() {
+			// cancelled while waiting
+			return
+		}
+		go s.execute(ctx, KindAsync, "", f)
+	})
+	s.OnComplete(func(_ T, _ error) {
+		defaultTimers.remove(d)
+	})
+	return &errNext[T]{state: s}
+}
@@ -0,0 +1,68 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunAfter(t *testing.T) {
+	start := time.Now()
+	// scheduled in the reverse order to check the earliest is always fired first
+	late := RunAfter(40*time.Millisecond, func(_ context.Context) (time.Duration, error) {
+		return time.Since(start), nil
+	})
+	early := RunAfter(10*time.Millisecond, func(_ context.Context) (time.Duration, error) {
+		return time.Since(start), nil
+	})
+	earlyDelay, err := early.Await()
+	assert.NoError(t, err)
+	lateDelay, err := late.Await()
+	assert.NoError(t, err)
+	assert.GreaterOrEqual(t, earlyDelay, 10*time.Millisecond)
+	assert.GreaterOrEqual(t, lateDelay, 40*time.Millisecond)
+}
+
+func TestRunAt_Cancel(t *testing.T) {
+	var called int32
+	future := RunAt(time.Now().Add(10*time.Millisecond), func(_ context.Context) (int, error) {
+		atomic.StoreInt32(&called, 1)
+		return 1, nil
+	})
+	future.Cancel()
+	time.Sleep(30 * time.Millisecond)
+	_, err := future.Await()
+	assert.Equal(t, ErrCancelled, err)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&called))
+}
+
+func TestRunAt_CancelRemoves(t *testing.T) {
+	defaultTimers.mutex.Lock()
+	pending := len(defaultTimers.pending)
+	defaultTimers.mutex.Unlock()
+	future := RunAfter(time.Hour, func(_ context.Context) (int, error) {
+		return 1, nil
+	})
+	future.Cancel()
+	defaultTimers.mutex.Lock()
+	defer defaultTimers.mutex.Unlock()
+	// the cancelled function doesn't wait in the heap for an hour
+	assert.Len(t, defaultTimers.pending, pending)
+}
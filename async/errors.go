@@ -0,0 +1,158 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// ErrAwaitTimeout is the error given back by the method AwaitWithTimeout when the future didn't end in time.
+// It is distinct from context.DeadlineExceeded so it cannot be confused with an error returned by the asynchronous function itself.
+var ErrAwaitTimeout = errors.New("timeout expired while awaiting the future")
+
+// ErrExecutionTimeout is the error wrapped by the error of a function, a task or a job that failed because its execution timed out.
+// See Timeout, TimeoutTask and WithJobTimeout.
+var ErrExecutionTimeout = errors.New("execution timed out")
+
+// ErrCancelled is the error given back by a future cancelled by its method Cancel.
+// For compatibility, errors.Is reports it as context.Canceled as well.
+var ErrCancelled error = cancelledError{}
+
+type cancelledError struct{}
+
+func (cancelledError) Error() string {
+	return "future has been cancelled"
+}
+
+func (cancelledError) Is(target error) bool {
+	return target == context.Canceled
+}
+
+// ErrPoolClosed is the error given back by the future of a job submitted to a Pool already shut down.
+var ErrPoolClosed = errors.New("pool is closed")
+
+// ErrQueueFull is the error given back by the future of a job rejected because the queue of the Pool is full (see OverflowReject).
+var ErrQueueFull = errors.New("queue of the pool is full")
+
+// ErrJobDropped is the error given back by the future of a job dropped because the queue of the Pool is full
+// (see OverflowDropOldest and OverflowDropNewest).
+var ErrJobDropped = errors.New("job dropped because the queue of the pool is full")
+
+// ErrDeadlineUnreachable is the error given back by SubmitBeforeDeadline when the job would wait in the queue beyond the deadline of the caller.
+var ErrDeadlineUnreachable = errors.New("deadline cannot be met with the current queue latency")
+
+// ErrPanicked is the error given back by a future when its asynchronous function panicked.
+// Use errors.As to get it back and to access the stack trace.
+type ErrPanicked struct {
+	// Value is the value passed to panic.
+	Value interface{}
+	// Stack is the stack trace of the go-routine at the moment it panicked.
+	Stack []byte
+}
+
+func (e *ErrPanicked) Error() string {
+	return fmt.Sprintf("asynchronous function panicked: %v", e.Value)
+}
+
+// ErrTimedOut is the error returned by a function, a task or a job that failed because its execution timed out.
+// errors.Is reports it as ErrExecutionTimeout, and errors.Unwrap gives back the error returned by the function.
+type ErrTimedOut struct {
+	// Timeout is the duration the execution was allowed to take.
+	Timeout time.Duration
+	// Err is the error returned by the function once its context was cancelled, usually context.DeadlineExceeded.
+	Err error
+}
+
+func (e *ErrTimedOut) Error() string {
+	return fmt.Sprintf("%s after %s: %s", ErrExecutionTimeout, e.Timeout, e.Err)
+}
+
+func (e *ErrTimedOut) Is(target error) bool {
+	return target == ErrExecutionTimeout
+}
+
+func (e *ErrTimedOut) Unwrap() error {
+	return e.Err
+}
+
+// ErrStalled is the error of the EventStalled sent by the TaskManager of the package async/taskhelper for a task whose heartbeat is too old.
+type ErrStalled struct {
+	// LastHeartbeat is the time of the last heartbeat of the task, or the time it started if there was none since.
+	LastHeartbeat time.Time
+	// Stack is the stack trace of the go-routines of the task at the moment it has been detected as stalled.
+	Stack []byte
+}
+
+func (e *ErrStalled) Error() string {
+	return fmt.Sprintf("no heartbeat since %s", e.LastHeartbeat.Format(time.RFC3339))
+}
+
+// protect calls f and converts a panic into an *ErrPanicked.
+func protect(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &ErrPanicked{Value: r, Stack: debug.Stack()}
+		}
+	}()
+	return f()
+}
+
+// MultiError aggregates the errors of several asynchronous functions.
+// It implements the methods Is and As, so errors.Is and errors.As look into each error aggregated.
+type MultiError []error
+
+func (m MultiError) Error() string {
+	messages := make([]string, 0, len(m))
+	for _, err := range m {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (m MultiError) Unwrap() []error {
+	return m
+}
+
+// Is reports whether one of the errors aggregated matches the target. It lets errors.Is look into each error, Unwrap() []error being followed by errors.Is only from Go 1.20.
+func (m MultiError) Is(target error) bool {
+	for _, err := range m {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error aggregated matching the target, see errors.As.
+func (m MultiError) As(target interface{}) bool {
+	for _, err := range m {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// errorOrNil returns nil when there is no error aggregated. It avoids to return a non-nil error interface holding an empty MultiError.
+func (m MultiError) errorOrNil() error {
+	if len(m) == 0 {
+		return nil
+	}
+	return m
+}
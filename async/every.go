@@ -0,0 +1,100 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+type everyConfig struct {
+	name   string
+	logger Logger
+}
+
+// EveryOption tunes the periodic calls made by Every.
+type EveryOption func(c *everyConfig)
+
+// WithEveryName sets the name identifying the periodic calls in the Events given to the Logger. By default, it is "every".
+func WithEveryName(name string) EveryOption {
+	return func(c *everyConfig) {
+		c.name = name
+	}
+}
+
+// WithEveryLogger sets the Logger receiving an EventSkipped for each call skipped and an EventPanicked for each call that panicked.
+// By default, it uses logrus.
+func WithEveryLogger(logger Logger) EveryOption {
+	return func(c *everyConfig) {
+		c.logger = logger
+	}
+}
+
+// Every calls f periodically until the context is done or the TypedFuture returned is cancelled.
+// The delay between two calls is interval randomized by +/- jitter, so many replicas started at the same time don't call
+// a shared backend at the same time.
+// A call is skipped if the previous one is still running, so the calls never overlap.
+// The TypedFuture returned is completed once the context is done and the call in progress, if any, is ended.
+func Every(ctx context.Context, interval time.Duration, jitter time.Duration, f func(ctx context.Context), opts ...EveryOption) TypedFuture[struct{}] {
+	c := &everyConfig{name: "every", logger: NewLogrusLogger(nil)}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return AsyncWithContext(ctx, func(ctx context.Context) struct{} {
+		// running is holding a token while a call is in progress.
+		running := make(chan struct{}, 1)
+		wg := &sync.WaitGroup{}
+		defer wg.Wait()
+		for {
+			timer := time.NewTimer(nextDelay(interval, jitter))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return struct{}{}
+			case <-timer.C:
+			}
+			select {
+			case running <- struct{}{}:
+			default:
+				c.logger.Log(Event{Type: EventSkipped, Kind: KindAsync, Name: c.name})
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-running }()
+				if err := protect(func() error {
+					f(ctx)
+					return nil
+				}); err != nil {
+					c.logger.Log(Event{Type: EventPanicked, Kind: KindAsync, Name: c.name, Err: err})
+				}
+			}()
+		}
+	})
+}
+
+// nextDelay returns interval randomized by +/- jitter. It is never negative.
+func nextDelay(interval time.Duration, jitter time.Duration) time.Duration {
+	if jitter <= 0 {
+		return interval
+	}
+	delay := interval + time.Duration(rand.Int63n(int64(2*jitter)+1)) - jitter
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
@@ -0,0 +1,70 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEvery(t *testing.T) {
+	var calls, running, overlaps int32
+	periodic := Every(context.Background(), 5*time.Millisecond, 2*time.Millisecond, func(_ context.Context) {
+		if atomic.AddInt32(&running, 1) > 1 {
+			atomic.AddInt32(&overlaps, 1)
+		}
+		defer atomic.AddInt32(&running, -1)
+		atomic.AddInt32(&calls, 1)
+		// longer than the interval, so some calls are skipped
+		time.Sleep(12 * time.Millisecond)
+	})
+	time.Sleep(100 * time.Millisecond)
+	periodic.Cancel()
+	assert.Greater(t, atomic.LoadInt32(&calls), int32(2))
+	assert.Equal(t, int32(0), atomic.LoadInt32(&overlaps))
+}
+
+func TestEvery_Logger(t *testing.T) {
+	var skipped, panicked int32
+	logger := LoggerFunc(func(event Event) {
+		assert.Equal(t, "refresh", event.Name)
+		switch event.Type {
+		case EventSkipped:
+			atomic.AddInt32(&skipped, 1)
+		case EventPanicked:
+			atomic.AddInt32(&panicked, 1)
+		}
+	})
+	periodic := Every(context.Background(), 5*time.Millisecond, 0, func(_ context.Context) {
+		time.Sleep(12 * time.Millisecond)
+		panic("boom")
+	}, WithEveryName("refresh"), WithEveryLogger(logger))
+	time.Sleep(100 * time.Millisecond)
+	periodic.Cancel()
+	periodic.Await()
+	assert.Greater(t, atomic.LoadInt32(&skipped), int32(0))
+	assert.Greater(t, atomic.LoadInt32(&panicked), int32(0))
+}
+
+func TestNextDelay(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		delay := nextDelay(10*time.Second, 2*time.Second)
+		assert.GreaterOrEqual(t, delay, 8*time.Second)
+		assert.LessOrEqual(t, delay, 12*time.Second)
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"runtime/debug"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// executionPrefix makes the execution IDs unique across the processes, the sequence makes them unique within the process.
+var (
+	executionPrefix = newExecutionPrefix()
+	executionSeq    uint64
+)
+
+func newExecutionPrefix() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+func nextExecutionSeq() uint64 {
+	return atomic.AddUint64(&executionSeq, 1)
+}
+
+func formatExecutionID(seq uint64) string {
+	return executionPrefix + "-" + strconv.FormatUint(seq, 10)
+}
+
+// NewExecutionID returns a new ID, unique across the executions of this package. It is meant for the packages running their own executions, like async/scheduler.
+func NewExecutionID() string {
+	return formatExecutionID(nextExecutionSeq())
+}
+
+type executionIDKey struct{}
+
+// ContextWithExecutionID returns a context holding the ID of the execution it belongs to.
+// It is called by this package for every future and every job of a Pool, so there is usually no need to call it.
+func ContextWithExecutionID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, executionIDKey{}, id)
+}
+
+// ExecutionID returns the ID of the execution the context belongs to, or an empty string if there is none.
+// Every future, job of a Pool, execution of a task and run of the async/scheduler has an ID, generated unless it is given by WithExecutionID or WithJobID.
+// The ID is also part of the Event given to the Logger and of the DeadLetter, so a failure can be correlated with the logs and the traces of the execution:
+//
+//	future := async.AsyncErrWithContext(ctx, func(ctx context.Context) (int, error) {
+//		logrus.WithField("execution_id", async.ExecutionID(ctx)).Info("computing")
+//		return compute(ctx)
+//	})
+func ExecutionID(ctx context.Context) string {
+	id, _ := ctx.Value(executionIDKey{}).(string)
+	return id
+}
+
+// Identified is implemented by the futures, so the ID of their execution is known before it even starts.
+type Identified interface {
+	ExecutionID() string
+}
+
+// ExecutionID returns the ID of the execution of the future. It is empty for the futures not executing anything, like the ones returned by Completed.
+func (s *state[T]) ExecutionID() string {
+	if len(s.id) > 0 {
+		return s.id
+	}
+	if s.seq == 0 {
+		return ""
+	}
+	return formatExecutionID(s.seq)
+}
+
+// ExecutionHook is implemented by the MetricsHook that also wants the ID of each execution, like to attach it to a trace.
+// The methods are called in addition to Started and Completed.
+type ExecutionHook interface {
+	ExecutionStarted(kind Kind, name string, id string)
+	ExecutionCompleted(kind Kind, name string, id string, duration time.Duration, err error)
+}
+
+// Execute calls f with a context holding the execution ID and reports the execution to the MetricsHook, and to its ExecutionHook if it implements it.
+// If f panics, the panic is reported as an *ErrPanicked. Then it is returned as the error of f when recoverPanic is true, otherwise f panics again.
+// It is used for every future and by the packages running their own executions, like async/taskhelper and async/scheduler.
+func Execute(ctx context.Context, kind Kind, name string, id string, recoverPanic bool, f func(ctx context.Context) error) (err error) {
+	hook := GetMetricsHook()
+	hook.Started(kind, name)
+	executionHook, _ := hook.(ExecutionHook)
+	if executionHook != nil {
+		executionHook.ExecutionStarted(kind, name, id)
+	}
+	start := time.Now()
+	defer func() {
+		r := recover()
+		if r != nil {
+			err = &ErrPanicked{Value: r, Stack: debug.Stack()}
+		}
+		duration := time.Since(start)
+		hook.Completed(kind, name, duration, err)
+		if executionHook != nil {
+			executionHook.ExecutionCompleted(kind, name, id, duration, err)
+		}
+		if r != nil && !recoverPanic {
+			panic(r)
+		}
+	}()
+	return f(ContextWithExecutionID(ctx, id))
+}
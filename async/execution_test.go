@@ -0,0 +1,127 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecutionID(t *testing.T) {
+	future := AsyncErrWithContext(context.Background(), func(ctx context.Context) (string, error) {
+		return ExecutionID(ctx), nil
+	})
+	id, err := future.Await()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, id)
+	assert.Equal(t, id, future.(Identified).ExecutionID())
+
+	other := AsyncErrWithContext(context.Background(), func(ctx context.Context) (string, error) {
+		return ExecutionID(ctx), nil
+	})
+	otherID, _ := other.Await()
+	assert.NotEqual(t, id, otherID)
+
+	given, _ := AsyncErrWithContext(context.Background(), func(ctx context.Context) (string, error) {
+		return ExecutionID(ctx), nil
+	}, WithExecutionID("request-42")).Await()
+	assert.Equal(t, "request-42", given)
+
+	assert.Empty(t, Completed(1).(Identified).ExecutionID())
+	assert.Empty(t, ExecutionID(context.Background()))
+}
+
+func TestPool_ExecutionID(t *testing.T) {
+	var letters []DeadLetter
+	var events []Event
+	pool := NewPool(1, 1, WithDeadLetter(func(letter DeadLetter) {
+		letters = append(letters, letter)
+	}), WithLogger(LoggerFunc(func(event Event) {
+		events = append(events, event)
+	})))
+	id, err := Submit(pool, func(ctx context.Context) (string, error) {
+		return ExecutionID(ctx), nil
+	}, WithJobID("message-1")).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, "message-1", id)
+
+	future := Submit(pool, func(_ context.Context) (int, error) {
+		panic(errors.New("failure"))
+	})
+	_, err = future.Await()
+	assert.Error(t, err)
+	assert.NoError(t, pool.Shutdown(context.Background()))
+	assert.Len(t, letters, 1)
+	assert.Equal(t, future.(Identified).ExecutionID(), letters[0].ExecutionID)
+	var panicked []Event
+	for _, event := range events {
+		if event.Type == EventPanicked {
+			panicked = append(panicked, event)
+		}
+	}
+	assert.Len(t, panicked, 1)
+	assert.Equal(t, letters[0].ExecutionID, panicked[0].ExecutionID)
+}
+
+// executionRecorder is a MetricsHook recording the failures of the executions and their IDs.
+type executionRecorder struct {
+	noopMetricsHook
+	mutex     sync.Mutex
+	completed []error
+	ids       []string
+}
+
+func (r *executionRecorder) Completed(_ Kind, _ string, _ time.Duration, err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.completed = append(r.completed, err)
+}
+
+func (r *executionRecorder) ExecutionStarted(_ Kind, _ string, _ string) {}
+
+func (r *executionRecorder) ExecutionCompleted(_ Kind, _ string, id string, _ time.Duration, _ error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.ids = append(r.ids, id)
+}
+
+func TestExecute(t *testing.T) {
+	recorder := &executionRecorder{}
+	SetMetricsHook(recorder)
+	defer SetMetricsHook(nil)
+
+	err := Execute(context.Background(), KindTask, "task", "execution-1", true, func(ctx context.Context) error {
+		assert.Equal(t, "execution-1", ExecutionID(ctx))
+		panic("boom")
+	})
+	var errPanicked *ErrPanicked
+	assert.ErrorAs(t, err, &errPanicked)
+	assert.Panics(t, func() {
+		_ = Execute(context.Background(), KindTask, "task", "execution-2", false, func(_ context.Context) error {
+			panic("boom")
+		})
+	})
+
+	// the panic is reported to the hook, whether it is recovered or not
+	assert.Len(t, recorder.completed, 2)
+	for _, completed := range recorder.completed {
+		assert.ErrorAs(t, completed, &errPanicked)
+	}
+	assert.Equal(t, []string{"execution-1", "execution-2"}, recorder.ids)
+}
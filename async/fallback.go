@@ -0,0 +1,52 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"time"
+)
+
+// Fallback executes primary asynchronously and, only if it fails, executes secondary.
+// It is useful to read from a cache before the origin, or from a regional replica before the global one.
+func Fallback[T any](primary func() (T, error), secondary func() (T, error)) ErrFuture[T] {
+	return FallbackWithTimeout(0,
+		func(_ context.Context) (T, error) {
+			return primary()
+		},
+		func(_ context.Context) (T, error) {
+			return secondary()
+		},
+	)
+}
+
+// FallbackWithTimeout is the same as Fallback, except secondary is also executed when primary didn't end within the timeout.
+// In that case, the context given to primary is cancelled. A timeout <= 0 means primary has no time limit.
+func FallbackWithTimeout[T any](timeout time.Duration, primary func(ctx context.Context) (T, error), secondary func(ctx context.Context) (T, error)) ErrFuture[T] {
+	return &errNext[T]{
+		state: run(context.Background(), func(ctx context.Context) (T, error) {
+			primaryCtx, cancel := context.WithCancel(ctx)
+			if timeout > 0 {
+				primaryCtx, cancel = context.WithTimeout(ctx, timeout)
+			}
+			defer cancel()
+			result, err := AsyncErrWithContext(primaryCtx, primary).AwaitWithContext(primaryCtx)
+			if err == nil || ctx.Err() != nil {
+				// either primary succeeded, or the fallback itself has been cancelled and secondary is not needed.
+				return result, err
+			}
+			return secondary(ctx)
+		}),
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFallback(t *testing.T) {
+	result, err := Fallback(
+		func() (string, error) { return "cache", nil },
+		func() (string, error) { return "origin", nil },
+	).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, "cache", result)
+
+	result, err = Fallback(
+		func() (string, error) { return "", fmt.Errorf("cache miss") },
+		func() (string, error) { return "origin", nil },
+	).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, "origin", result)
+}
+
+func TestFallbackWithTimeout(t *testing.T) {
+	cancelled := make(chan struct{})
+	result, err := FallbackWithTimeout(10*time.Millisecond,
+		func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			close(cancelled)
+			return "", ctx.Err()
+		},
+		func(_ context.Context) (string, error) { return "global", nil },
+	).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, "global", result)
+	<-cancelled
+}
@@ -0,0 +1,103 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"sync"
+)
+
+// Group runs a set of functions returning a T and collects their results.
+// It is similar to errgroup.Group: the first function failing cancels the context of the Group, and Wait returns its error.
+//
+//	group, ctx := async.NewGroup[*User](ctx)
+//	group.SetLimit(10)
+//	for _, id := range ids {
+//		id := id
+//		group.Go(func() (*User, error) {
+//			return client.GetUser(ctx, id)
+//		})
+//	}
+//	users, err := group.Wait()
+type Group[T any] struct {
+	ctx       context.Context
+	cancel    context.CancelFunc
+	wg        sync.WaitGroup
+	semaphore chan struct{}
+
+	mutex   sync.Mutex
+	results []T
+	once    sync.Once
+	err     error
+}
+
+// NewGroup creates a Group and the context derived from ctx that is cancelled when a function fails or when Wait returns.
+func NewGroup[T any](ctx context.Context) (*Group[T], context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Group[T]{ctx: ctx, cancel: cancel}, ctx
+}
+
+// SetLimit limits the number of functions running at the same time. Once reached, Go blocks until a function ends.
+// A value lower or equal to 0 removes the limit. It must not be called while some functions are running.
+func (g *Group[T]) SetLimit(n int) {
+	if n <= 0 {
+		g.semaphore = nil
+		return
+	}
+	g.semaphore = make(chan struct{}, n)
+}
+
+// Go runs the function in a new go-routine. Its result is kept at the position corresponding to the order of the calls to Go.
+// If the function fails or panics, the context of the group is cancelled.
+func (g *Group[T]) Go(f func() (T, error)) {
+	if g.semaphore != nil {
+		g.semaphore <- struct{}{}
+	}
+	g.mutex.Lock()
+	index := len(g.results)
+	var zero T
+	g.results = append(g.results, zero)
+	g.mutex.Unlock()
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if g.semaphore != nil {
+			defer func() { <-g.semaphore }()
+		}
+		var result T
+		if err := protect(func() (err error) {
+			result, err = f()
+			return err
+		}); err != nil {
+			g.once.Do(func() {
+				g.err = err
+				g.cancel()
+			})
+			return
+		}
+		g.mutex.Lock()
+		g.results[index] = result
+		g.mutex.Unlock()
+	}()
+}
+
+// Wait blocks until every function ended. It returns the results in the order of the calls to Go, and the first error.
+// The result of a function that failed is the zero value of T.
+func (g *Group[T]) Wait() ([]T, error) {
+	g.wg.Wait()
+	g.cancel()
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	return g.results, g.err
+}
@@ -0,0 +1,49 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGroup(t *testing.T) {
+	group, _ := NewGroup[int](context.Background())
+	group.SetLimit(2)
+	for i := 0; i < 5; i++ {
+		value := i
+		group.Go(func() (int, error) {
+			return value * value, nil
+		})
+	}
+	results, err := group.Wait()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 4, 9, 16}, results)
+}
+
+func TestGroup_CancelOnFailure(t *testing.T) {
+	group, ctx := NewGroup[int](context.Background())
+	group.Go(func() (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+	group.Go(func() (int, error) {
+		return 0, fmt.Errorf("failure")
+	})
+	_, err := group.Wait()
+	assert.EqualError(t, err, "failure")
+}
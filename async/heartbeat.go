@@ -0,0 +1,63 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// HeartbeatReporter is implemented by the SimpleTask or the Task telling regularly they are alive.
+// It is optional: the TaskManager of the package async/taskhelper flags, or restarts, the tasks whose last heartbeat is too old (see TaskManager.SetWatchdog).
+// LastHeartbeat is called concurrently with Execute, so it must be safe for concurrent use.
+type HeartbeatReporter interface {
+	// LastHeartbeat returns the time of the last heartbeat. It is zero if there was none yet.
+	LastHeartbeat() time.Time
+}
+
+// Heartbeater is a helper to implement a HeartbeatReporter. It is meant to be embedded in a task calling Heartbeat in its main loop:
+//
+//	type consumerTask struct {
+//		async.SimpleTask
+//		async.Heartbeater
+//	}
+//
+//	func (t *consumerTask) Execute(ctx context.Context, _ context.CancelFunc) error {
+//		for {
+//			t.Heartbeat()
+//			select {
+//			case <-ctx.Done():
+//				return nil
+//			case message := <-t.messages:
+//				t.process(message)
+//			}
+//		}
+//	}
+type Heartbeater struct {
+	// last is the time of the last heartbeat, in nanoseconds since the Unix epoch.
+	last int64
+}
+
+// Heartbeat records that the task is alive.
+func (h *Heartbeater) Heartbeat() {
+	atomic.StoreInt64(&h.last, time.Now().UnixNano())
+}
+
+func (h *Heartbeater) LastHeartbeat() time.Time {
+	last := atomic.LoadInt64(&h.last)
+	if last == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, last)
+}
@@ -0,0 +1,64 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"time"
+)
+
+// Hedge executes f and, if it didn't end after the delay, starts a second speculative execution of f.
+// The first execution to succeed gives the result, and the other one is cancelled.
+// If an execution fails while the other one is still running, Hedge waits for the other one.
+// A failure before the delay is returned without starting the second execution: hedging is meant to cut the latency, use the package retry to handle the failures.
+// f must be idempotent, since it can be executed twice.
+func Hedge[T any](ctx context.Context, delay time.Duration, f func(ctx context.Context) (T, error)) (T, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	// cancel stops the execution that lost.
+	defer cancel()
+	type hedgeResult struct {
+		result T
+		err    error
+	}
+	// results is buffered so the execution that lost never blocks.
+	results := make(chan hedgeResult, 2)
+	launch := func() {
+		future := AsyncErrWithContext(ctx, f)
+		go func() {
+			result, err := future.Await()
+			results <- hedgeResult{result: result, err: err}
+		}()
+	}
+	launch()
+	started, ended := 1, 0
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			var zero T
+			return zero, ctx.Err()
+		case <-timer.C:
+			if started < 2 {
+				launch()
+				started++
+			}
+		case r := <-results:
+			ended++
+			if r.err == nil || ended == started {
+				return r.result, r.err
+			}
+		}
+	}
+}
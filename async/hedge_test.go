@@ -0,0 +1,52 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHedge(t *testing.T) {
+	var calls int32
+	cancelled := make(chan struct{})
+	result, err := Hedge(context.Background(), 10*time.Millisecond, func(ctx context.Context) (int, error) {
+		call := atomic.AddInt32(&calls, 1)
+		if call == 1 {
+			// the first execution is too slow, it must be cancelled once the second one succeeded
+			<-ctx.Done()
+			close(cancelled)
+			return 0, ctx.Err()
+		}
+		return 2, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result)
+	<-cancelled
+}
+
+func TestHedge_FastFailure(t *testing.T) {
+	var calls int32
+	_, err := Hedge(context.Background(), 50*time.Millisecond, func(_ context.Context) (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, fmt.Errorf("failure")
+	})
+	assert.EqualError(t, err, "failure")
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,91 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"sync"
+	"time"
+)
+
+// Kind is the kind of asynchronous execution reported to the MetricsHook.
+type Kind string
+
+const (
+	// KindAsync is an asynchronous function started by Go and its variants.
+	KindAsync Kind = "async"
+	// KindPool is a job executed by a Pool.
+	KindPool Kind = "pool"
+	// KindTask is an execution of the method Execute of a SimpleTask or a Task.
+	KindTask Kind = "task"
+)
+
+// MetricsHook is notified of the lifecycle of every asynchronous execution handled by this package,
+// so it can be exported as metrics. The package async/metrics provides an implementation based on Prometheus.
+// The methods are called synchronously by the go-routine doing the execution, so they must be fast.
+type MetricsHook interface {
+	// Started is called when an execution starts. name is empty when the execution has no name.
+	Started(kind Kind, name string)
+	// Completed is called when an execution ends. err is nil when the execution succeeded.
+	Completed(kind Kind, name string, duration time.Duration, err error)
+	// QueueDepth is called each time the number of jobs waiting in the queue of a pool changes.
+	QueueDepth(pool string, depth int)
+}
+
+// PoolMetricsHook is implemented by the MetricsHook that also wants the detailed metrics of the pools, like the time the jobs wait in the queue.
+// The execution of the jobs themselves is reported through the methods of MetricsHook, with KindPool.
+type PoolMetricsHook interface {
+	// Submitted is called for every job submitted to a pool, before it is added to the queue or rejected.
+	Submitted(pool string)
+	// Rejected is called when a job submitted to a pool is not executed: the pool is closed, the queue is full, the job is dropped to make room (see OverflowPolicy),
+	// its deadline cannot be reached (see SubmitBeforeDeadline), or the context given to SubmitWithContext is done before there is room in the queue.
+	// err is the error the future of the job failed with.
+	Rejected(pool string, err error)
+	// Dequeued is called when a worker takes a job from the queue, wait being the time the job waited.
+	Dequeued(pool string, wait time.Duration)
+}
+
+// getPoolMetricsHook returns the MetricsHook used by the whole package if it is a PoolMetricsHook, nil otherwise.
+func getPoolMetricsHook() PoolMetricsHook {
+	hook, _ := GetMetricsHook().(PoolMetricsHook)
+	return hook
+}
+
+type noopMetricsHook struct{}
+
+func (noopMetricsHook) Started(Kind, string)                         {}
+func (noopMetricsHook) Completed(Kind, string, time.Duration, error) {}
+func (noopMetricsHook) QueueDepth(string, int)                       {}
+
+var (
+	metricsHookMutex sync.RWMutex
+	metricsHook      MetricsHook = noopMetricsHook{}
+)
+
+// SetMetricsHook sets the MetricsHook used by the whole package. Setting nil removes it.
+// It should be called once when the application starts, before any asynchronous execution.
+func SetMetricsHook(hook MetricsHook) {
+	if hook == nil {
+		hook = noopMetricsHook{}
+	}
+	metricsHookMutex.Lock()
+	defer metricsHookMutex.Unlock()
+	metricsHook = hook
+}
+
+// GetMetricsHook returns the MetricsHook used by the whole package. It is never nil.
+func GetMetricsHook() MetricsHook {
+	metricsHookMutex.RLock()
+	defer metricsHookMutex.RUnlock()
+	return metricsHook
+}
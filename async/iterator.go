@@ -0,0 +1,99 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+)
+
+// Iterator gives access, one by one, to the values yielded by a producer running in its own go-routine.
+// It is useful to consume a paginated API without loading every page first:
+//
+//	it := async.NewIterator(ctx, 0, func(ctx context.Context, yield func(Item) bool) error {
+//		for page := 0; ; page++ {
+//			items, err := client.List(ctx, page)
+//			if err != nil || len(items) == 0 {
+//				return err
+//			}
+//			for _, item := range items {
+//				if !yield(item) {
+//					return nil
+//				}
+//			}
+//		}
+//	})
+//	defer it.Close()
+//	for {
+//		item, ok, err := it.Next(ctx)
+//		if !ok {
+//			return err
+//		}
+//		// do something with the item
+//	}
+type Iterator[T any] struct {
+	values chan T
+	cancel context.CancelFunc
+	// err is the error of the producer, set before values is closed.
+	err error
+}
+
+// NewIterator starts the producer. The values yielded are kept in a buffer of the size given, waiting for Next to be called.
+// yield returns false once the iterator is closed or the context is done, the producer must then stop.
+func NewIterator[T any](ctx context.Context, buffer int, producer func(ctx context.Context, yield func(value T) bool) error) *Iterator[T] {
+	if buffer < 0 {
+		buffer = 0
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	it := &Iterator[T]{
+		values: make(chan T, buffer),
+		cancel: cancel,
+	}
+	yield := func(value T) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case it.values <- value:
+			return true
+		}
+	}
+	go func() {
+		defer close(it.values)
+		defer cancel()
+		it.err = protect(func() error {
+			return producer(ctx, yield)
+		})
+	}()
+	return it
+}
+
+// Next returns the next value and true. Once the producer has ended and every value has been consumed,
+// it returns false and the error of the producer, if any.
+// If the context is done before a value is available, it returns false and the error of the context.
+func (it *Iterator[T]) Next(ctx context.Context) (T, bool, error) {
+	var zero T
+	select {
+	case <-ctx.Done():
+		return zero, false, ctx.Err()
+	case value, ok := <-it.values:
+		if !ok {
+			return zero, false, it.err
+		}
+		return value, true, nil
+	}
+}
+
+// Close stops the producer. It can be called several times.
+func (it *Iterator[T]) Close() {
+	it.cancel()
+}
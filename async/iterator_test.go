@@ -0,0 +1,63 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIterator(t *testing.T) {
+	it := NewIterator(context.Background(), 1, func(ctx context.Context, yield func(int) bool) error {
+		for i := 0; i < 3; i++ {
+			if !yield(i) {
+				return nil
+			}
+		}
+		return fmt.Errorf("last page unreachable")
+	})
+	defer it.Close()
+	var values []int
+	for {
+		value, ok, err := it.Next(context.Background())
+		if !ok {
+			assert.EqualError(t, err, "last page unreachable")
+			break
+		}
+		values = append(values, value)
+	}
+	assert.Equal(t, []int{0, 1, 2}, values)
+}
+
+func TestIterator_Close(t *testing.T) {
+	stopped := make(chan struct{})
+	it := NewIterator(context.Background(), 0, func(ctx context.Context, yield func(int) bool) error {
+		defer close(stopped)
+		for i := 0; ; i++ {
+			if !yield(i) {
+				return nil
+			}
+		}
+	})
+	value, ok, err := it.Next(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, 0, value)
+	it.Close()
+	// the producer must stop once the iterator is closed
+	<-stopped
+}
@@ -0,0 +1,170 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobqueue
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/sirupsen/logrus"
+)
+
+// Handler executes the job held by the message.
+type Handler func(ctx context.Context, msg *Message) error
+
+const (
+	defaultRedeliveryDelay = time.Second
+	defaultMaxAttempts     = 10
+)
+
+// Consumer is an async.SimpleTask dequeuing the messages of a Queue and executing them with the Handler through an async.Pool.
+// The number of messages in progress is bounded by the pool: Dequeue isn't called while the queue of the pool is full.
+type Consumer struct {
+	name            string
+	queue           Queue
+	pool            *async.Pool
+	handler         Handler
+	redeliveryDelay time.Duration
+	maxAttempts     int
+	deadLetter      func(msg *Message, err error)
+}
+
+// Option configures a Consumer.
+type Option func(c *Consumer)
+
+// WithRedeliveryDelay sets the time a message that failed is kept before being given back to the queue, so it is not delivered again right away.
+// By default, it is 1 second.
+func WithRedeliveryDelay(delay time.Duration) Option {
+	return func(c *Consumer) {
+		if delay >= 0 {
+			c.redeliveryDelay = delay
+		}
+	}
+}
+
+// WithMaxAttempts sets the number of deliveries of a message before it is given up. By default, it is 10.
+// A message given up is acknowledged, so it is removed from the queue, and passed to the function set by WithDeadLetter.
+func WithMaxAttempts(n int) Option {
+	return func(c *Consumer) {
+		if n > 0 {
+			c.maxAttempts = n
+		}
+	}
+}
+
+// WithDeadLetter sets the function receiving every message given up once its deliveries are exhausted (see WithMaxAttempts),
+// with the error of its last delivery, so a poison message is not silently dropped.
+func WithDeadLetter(handler func(msg *Message, err error)) Option {
+	return func(c *Consumer) {
+		c.deadLetter = handler
+	}
+}
+
+func NewConsumer(name string, queue Queue, pool *async.Pool, handler Handler, opts ...Option) *Consumer {
+	c := &Consumer{
+		name:            name,
+		queue:           queue,
+		pool:            pool,
+		handler:         handler,
+		redeliveryDelay: defaultRedeliveryDelay,
+		maxAttempts:     defaultMaxAttempts,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+func (c *Consumer) String() string {
+	return c.name
+}
+
+// Execute consumes the queue until the context is done. Then it waits for the messages in progress to be settled.
+// It stops with an error wrapping async.ErrPoolClosed when the pool is shut down, since no message can be executed anymore.
+func (c *Consumer) Execute(ctx context.Context, _ context.CancelFunc) error {
+	wg := &sync.WaitGroup{}
+	defer wg.Wait()
+	// consumeCtx is cancelled once the pool is closed, to stop dequeuing.
+	consumeCtx, stopConsuming := context.WithCancel(ctx)
+	defer stopConsuming()
+	for {
+		msg, err := c.queue.Dequeue(consumeCtx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			if consumeCtx.Err() != nil {
+				return fmt.Errorf("consumer '%s' stopped: %w", c.name, async.ErrPoolClosed)
+			}
+			return fmt.Errorf("unable to dequeue a message: %w", err)
+		}
+		future := async.Submit(c.pool, func(jobCtx context.Context) (struct{}, error) {
+			return struct{}{}, c.handler(jobCtx, msg)
+		})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, handlerErr := future.Await()
+			if errors.Is(handlerErr, async.ErrPoolClosed) {
+				// the message hasn't been executed, it is given back right away.
+				stopConsuming()
+				c.nack(msg)
+				return
+			}
+			c.settle(ctx, msg, handlerErr)
+		}()
+	}
+}
+
+// settle acknowledges or gives back the message according to the result of its execution.
+// The message that failed is given back once the redelivery delay is elapsed, or right away if the consumer is stopping.
+// It doesn't use the context of the consumer otherwise, so the messages in progress are still settled while stopping.
+func (c *Consumer) settle(ctx context.Context, msg *Message, handlerErr error) {
+	if handlerErr == nil {
+		c.ack(msg)
+		return
+	}
+	if msg.Attempts >= c.maxAttempts {
+		logrus.WithError(handlerErr).Errorf("message '%s' of the consumer '%s' failed at attempt %d, it is given up", msg.ID, c.name, msg.Attempts)
+		c.ack(msg)
+		if c.deadLetter != nil {
+			c.deadLetter(msg, handlerErr)
+		}
+		return
+	}
+	logrus.WithError(handlerErr).Warningf("message '%s' of the consumer '%s' failed at attempt %d, it is given back to the queue", msg.ID, c.name, msg.Attempts)
+	timer := time.NewTimer(c.redeliveryDelay)
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		timer.Stop()
+	}
+	c.nack(msg)
+}
+
+func (c *Consumer) ack(msg *Message) {
+	if err := c.queue.Ack(context.Background(), msg.ID); err != nil {
+		logrus.WithError(err).Errorf("unable to acknowledge the message '%s' of the consumer '%s'", msg.ID, c.name)
+	}
+}
+
+func (c *Consumer) nack(msg *Message) {
+	if err := c.queue.Nack(context.Background(), msg.ID); err != nil {
+		logrus.WithError(err).Errorf("unable to give back the message '%s' of the consumer '%s'", msg.ID, c.name)
+	}
+}
@@ -0,0 +1,128 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobqueue
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConsumer(t *testing.T) {
+	q := NewMemoryQueue()
+	for i := 0; i < 5; i++ {
+		_, _ = q.Enqueue(context.Background(), []byte(fmt.Sprintf("job-%d", i)))
+	}
+	pool := async.NewPool(2, 2)
+	defer pool.Shutdown(context.Background())
+	var executed int32
+	var failed int32
+	consumer := NewConsumer("test", q, pool, func(_ context.Context, msg *Message) error {
+		// the first delivery of the first job fails, so it must be delivered again
+		if msg.ID == "1" && msg.Attempts == 1 {
+			atomic.AddInt32(&failed, 1)
+			return fmt.Errorf("failure")
+		}
+		atomic.AddInt32(&executed, 1)
+		return nil
+	}, WithRedeliveryDelay(time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Execute(ctx, cancel) }()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&executed) == 5 }, time.Second, 5*time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&failed))
+	assert.Equal(t, 0, q.Len())
+	assert.Empty(t, q.inFlight)
+}
+
+func TestConsumer_PoolClosed(t *testing.T) {
+	q := NewMemoryQueue()
+	_, _ = q.Enqueue(context.Background(), []byte("job"))
+	pool := async.NewPool(1, 1)
+	assert.NoError(t, pool.Shutdown(context.Background()))
+	var executed int32
+	consumer := NewConsumer("test", q, pool, func(_ context.Context, _ *Message) error {
+		atomic.AddInt32(&executed, 1)
+		return nil
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// the consumer stops instead of delivering the message again and again
+	err := consumer.Execute(ctx, cancel)
+	assert.ErrorIs(t, err, async.ErrPoolClosed)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&executed))
+	assert.Equal(t, 1, q.Len())
+	msg, err := q.Dequeue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, msg.Attempts)
+}
+
+func TestConsumer_RedeliveryDelay(t *testing.T) {
+	q := NewMemoryQueue()
+	_, _ = q.Enqueue(context.Background(), []byte("job"))
+	pool := async.NewPool(1, 1)
+	defer pool.Shutdown(context.Background())
+	var attempts int32
+	consumer := NewConsumer("test", q, pool, func(_ context.Context, _ *Message) error {
+		atomic.AddInt32(&attempts, 1)
+		return fmt.Errorf("failure")
+	}, WithRedeliveryDelay(time.Hour))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Execute(ctx, cancel) }()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&attempts))
+	// the message waiting for its redelivery is given back right away once the consumer stops
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, 1, q.Len())
+}
+
+func TestConsumer_MaxAttempts(t *testing.T) {
+	q := NewMemoryQueue()
+	_, _ = q.Enqueue(context.Background(), []byte("poison"))
+	pool := async.NewPool(1, 1)
+	defer pool.Shutdown(context.Background())
+	var attempts int32
+	letters := make(chan *Message, 1)
+	consumer := NewConsumer("test", q, pool, func(_ context.Context, _ *Message) error {
+		atomic.AddInt32(&attempts, 1)
+		return fmt.Errorf("failure")
+	}, WithRedeliveryDelay(time.Millisecond), WithMaxAttempts(3), WithDeadLetter(func(msg *Message, err error) {
+		assert.Error(t, err)
+		letters <- msg
+	}))
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- consumer.Execute(ctx, cancel) }()
+	select {
+	case msg := <-letters:
+		assert.Equal(t, 3, msg.Attempts)
+		assert.Equal(t, []byte("poison"), msg.Payload)
+	case <-time.After(time.Second):
+		t.Fatal("the message has not been given up")
+	}
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&attempts))
+	assert.Equal(t, 0, q.Len())
+	assert.Empty(t, q.inFlight)
+}
@@ -0,0 +1,107 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobqueue
+
+import (
+	"context"
+	"strconv"
+	"sync"
+)
+
+// MemoryQueue is the in-memory implementation of Queue. It doesn't survive a restart of the process.
+// It is meant to be used in tests or when losing the jobs waiting is acceptable.
+type MemoryQueue struct {
+	mutex    sync.Mutex
+	seq      uint64
+	pending  []*Message
+	inFlight map[string]*Message
+	// available is closed and replaced every time a message is added to pending, to wake up the consumers waiting.
+	available chan struct{}
+}
+
+func NewMemoryQueue() *MemoryQueue {
+	return &MemoryQueue{
+		inFlight:  make(map[string]*Message),
+		available: make(chan struct{}),
+	}
+}
+
+func (q *MemoryQueue) Enqueue(_ context.Context, payload []byte) (string, error) {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	q.seq++
+	msg := &Message{ID: strconv.FormatUint(q.seq, 10), Payload: payload}
+	q.push(msg)
+	return msg.ID, nil
+}
+
+func (q *MemoryQueue) Dequeue(ctx context.Context) (*Message, error) {
+	for {
+		q.mutex.Lock()
+		if len(q.pending) > 0 {
+			msg := q.pending[0]
+			q.pending[0] = nil
+			q.pending = q.pending[1:]
+			msg.Attempts++
+			q.inFlight[msg.ID] = msg
+			q.mutex.Unlock()
+			// a copy is returned, so the caller cannot alter the message stored
+			result := *msg
+			return &result, nil
+		}
+		available := q.available
+		q.mutex.Unlock()
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-available:
+		}
+	}
+}
+
+func (q *MemoryQueue) Ack(_ context.Context, id string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if _, ok := q.inFlight[id]; !ok {
+		return ErrUnknownMessage
+	}
+	delete(q.inFlight, id)
+	return nil
+}
+
+func (q *MemoryQueue) Nack(_ context.Context, id string) error {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	msg, ok := q.inFlight[id]
+	if !ok {
+		return ErrUnknownMessage
+	}
+	delete(q.inFlight, id)
+	q.push(msg)
+	return nil
+}
+
+// Len returns the number of messages waiting to be dequeued.
+func (q *MemoryQueue) Len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.pending)
+}
+
+// push must be called with the mutex held.
+func (q *MemoryQueue) push(msg *Message) {
+	q.pending = append(q.pending, msg)
+	close(q.available)
+	q.available = make(chan struct{})
+}
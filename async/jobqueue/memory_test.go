@@ -0,0 +1,57 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jobqueue
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryQueue(t *testing.T) {
+	q := NewMemoryQueue()
+	ctx := context.Background()
+	id, err := q.Enqueue(ctx, []byte("job"))
+	assert.NoError(t, err)
+	msg, err := q.Dequeue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, id, msg.ID)
+	assert.Equal(t, 1, msg.Attempts)
+	assert.Equal(t, 0, q.Len())
+	// the message given back is delivered again
+	assert.NoError(t, q.Nack(ctx, id))
+	msg, err = q.Dequeue(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, msg.Attempts)
+	assert.NoError(t, q.Ack(ctx, id))
+	assert.ErrorIs(t, q.Ack(ctx, id), ErrUnknownMessage)
+}
+
+func TestMemoryQueue_DequeueBlocks(t *testing.T) {
+	q := NewMemoryQueue()
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := q.Dequeue(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		_, _ = q.Enqueue(context.Background(), []byte("late"))
+	}()
+	msg, err := q.Dequeue(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("late"), msg.Payload)
+}
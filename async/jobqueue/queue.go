@@ -0,0 +1,60 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package jobqueue provides a pluggable queue of serialized jobs, so the work waiting to be done can survive a restart
+// of the process when the Queue is backed by a persistent storage (Redis, SQL database, ...).
+//
+// The jobs are consumed by a Consumer that executes them through an async.Pool:
+//
+//	queue := jobqueue.NewMemoryQueue()
+//	consumer := jobqueue.NewConsumer("emails", queue, async.NewPool(4, 16), func(ctx context.Context, msg *jobqueue.Message) error {
+//		return sendEmail(ctx, msg.Payload)
+//	})
+//	app.NewRunner().WithTasks(consumer).Start()
+//
+// A message is acknowledged once the handler succeeded. Otherwise, it is given back to the queue to be delivered again after a delay (see WithRedeliveryDelay),
+// until its deliveries are exhausted (see WithMaxAttempts and WithDeadLetter).
+package jobqueue
+
+import (
+	"context"
+	"fmt"
+)
+
+// ErrUnknownMessage is returned when acknowledging a message that is not in progress.
+var ErrUnknownMessage = fmt.Errorf("unknown message")
+
+// Message is a job stored in a Queue.
+type Message struct {
+	// ID identifies the message in the queue. It is set by the queue.
+	ID string
+	// Payload is the serialized job.
+	Payload []byte
+	// Attempts is the number of times the message has been delivered, including the current delivery.
+	Attempts int
+}
+
+// Queue is the storage of the jobs waiting to be executed.
+// An implementation backed by a persistent storage should deliver again the messages dequeued but never acknowledged,
+// for example after a restart of the process that was executing them.
+type Queue interface {
+	// Enqueue stores the payload in the queue and returns the ID of the message created.
+	Enqueue(ctx context.Context, payload []byte) (string, error)
+	// Dequeue blocks until a message is available or the context is done.
+	// The message dequeued is in progress until it is acknowledged with Ack or given back with Nack.
+	Dequeue(ctx context.Context) (*Message, error)
+	// Ack removes definitively the message from the queue.
+	Ack(ctx context.Context, id string) error
+	// Nack gives back the message to the queue, so it is delivered again.
+	Nack(ctx context.Context, id string) error
+}
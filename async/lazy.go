@@ -0,0 +1,53 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+)
+
+// newLazyState returns a state executing f only the first time its result is awaited.
+func newLazyState[T any](f func(ctx context.Context) (T, error)) *state[T] {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := newState[T](cancel)
+	s.submitter = submitter()
+	s.start = func() {
+		if s.IsDone() {
+			// cancelled before being awaited, f doesn't need to run.
+			return
+		}
+		go s.execute(ctx, KindAsync, "", f)
+	}
+	return s
+}
+
+// Lazy returns a TypedFuture executing the function only once its result is awaited for the first time.
+// The result is then kept for every following call. IsDone and TryAwait don't launch the function, while Then and Catch do.
+// Use it instead of Go when the result may never be needed.
+func Lazy[T any](f func() T) TypedFuture[T] {
+	return &next[T]{
+		state: newLazyState(func(_ context.Context) (T, error) {
+			return f(), nil
+		}),
+	}
+}
+
+// LazyErr is the equivalent of Lazy for a function that can fail. See AsyncErr.
+func LazyErr[T any](f func() (T, error)) ErrFuture[T] {
+	return &errNext[T]{
+		state: newLazyState(func(_ context.Context) (T, error) {
+			return f()
+		}),
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLazy(t *testing.T) {
+	var calls int32
+	future := Lazy(func() int {
+		atomic.AddInt32(&calls, 1)
+		return 42
+	})
+	time.Sleep(10 * time.Millisecond)
+	_, ok := future.TryAwait()
+	assert.False(t, ok)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+	assert.Equal(t, 42, future.Await())
+	assert.Equal(t, 42, future.AwaitWithTimeout(time.Second))
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestLazyErr_Cancel(t *testing.T) {
+	var calls int32
+	future := LazyErr(func() (int, error) {
+		atomic.AddInt32(&calls, 1)
+		return 0, fmt.Errorf("failure")
+	})
+	future.Cancel()
+	_, err := future.Await()
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&calls))
+}
@@ -0,0 +1,133 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"errors"
+
+	"github.com/sirupsen/logrus"
+)
+
+// EventType is the type of lifecycle event given to a Logger.
+type EventType string
+
+const (
+	// EventStarted is sent when a task or a pool starts.
+	EventStarted EventType = "started"
+	// EventStopped is sent when a task or a pool stops. Event.Err is set if it stopped because of an error.
+	EventStopped EventType = "stopped"
+	// EventPanicked is sent when a task or a job panicked. Event.Err is an *ErrPanicked.
+	EventPanicked EventType = "panicked"
+	// EventRetried is sent when a failed execution is retried. Event.Err is the error of the attempt that failed.
+	EventRetried EventType = "retried"
+	// EventRestarted is sent when a Supervisor restarts its task. Event.Err is the error the task ended with, if any.
+	EventRestarted EventType = "restarted"
+	// EventPaused is sent when a task is paused by the TaskManager.
+	EventPaused EventType = "paused"
+	// EventResumed is sent when a task paused is resumed by the TaskManager.
+	EventResumed EventType = "resumed"
+	// EventStalled is sent when the heartbeat of a task is too old. Event.Err is an *ErrStalled.
+	EventStalled EventType = "stalled"
+	// EventSkipped is sent when a periodic call is skipped because the previous one is still running, see Every.
+	EventSkipped EventType = "skipped"
+	// EventFailed is sent when an execution in the background failed and there is no caller to return the error to, see Batcher.
+	EventFailed EventType = "failed"
+)
+
+// Event is a lifecycle event of a task or a pool.
+type Event struct {
+	Type EventType
+	Kind Kind
+	// Name is the name of the task or of the pool.
+	Name string
+	Err  error
+	// Attempt is the number of the attempt that failed, for an EventRetried, or the number of the restart, for an EventRestarted.
+	Attempt int
+	// ExecutionID is the ID of the execution the event is about, see ExecutionID. For a task, it is the ID of its last execution.
+	// It is empty when no execution is involved, like for the EventStarted of a pool.
+	ExecutionID string
+}
+
+// Logger is called by the TaskManager and the Pool each time a lifecycle event happens, so the failures in the background go-routines are visible.
+// It is called synchronously, so it must be fast.
+type Logger interface {
+	Log(event Event)
+}
+
+// LoggerFunc is an adapter to use a function as a Logger.
+type LoggerFunc func(event Event)
+
+func (f LoggerFunc) Log(event Event) {
+	f(event)
+}
+
+type logrusLogger struct {
+	entry *logrus.Entry
+}
+
+// NewLogrusLogger returns a Logger using the given logrus entry. If entry is nil, the standard logger of logrus is used.
+// It is the Logger used by default.
+func NewLogrusLogger(entry *logrus.Entry) Logger {
+	if entry == nil {
+		entry = logrus.NewEntry(logrus.StandardLogger())
+	}
+	return &logrusLogger{entry: entry}
+}
+
+func (l *logrusLogger) Log(event Event) {
+	entry := l.entry.WithFields(logrus.Fields{
+		"kind": event.Kind,
+		"name": event.Name,
+	})
+	if event.Err != nil {
+		entry = entry.WithError(event.Err)
+	}
+	if len(event.ExecutionID) > 0 {
+		entry = entry.WithField("execution_id", event.ExecutionID)
+	}
+	switch event.Type {
+	case EventStarted:
+		entry.Debugf("%s '%s' has started", event.Kind, event.Name)
+	case EventStopped:
+		if event.Err != nil {
+			entry.Errorf("%s '%s' has stopped in error", event.Kind, event.Name)
+		} else {
+			entry.Debugf("%s '%s' has stopped", event.Kind, event.Name)
+		}
+	case EventPanicked:
+		var panicked *ErrPanicked
+		if errors.As(event.Err, &panicked) {
+			entry = entry.WithField("stack", string(panicked.Stack))
+		}
+		entry.Errorf("%s '%s' has panicked", event.Kind, event.Name)
+	case EventRetried:
+		entry.WithField("attempt", event.Attempt).Warningf("%s '%s' failed and is retried", event.Kind, event.Name)
+	case EventRestarted:
+		entry.WithField("restart", event.Attempt).Warningf("%s '%s' is restarted", event.Kind, event.Name)
+	case EventPaused:
+		entry.Infof("%s '%s' is paused", event.Kind, event.Name)
+	case EventResumed:
+		entry.Infof("%s '%s' is resumed", event.Kind, event.Name)
+	case EventStalled:
+		var stalled *ErrStalled
+		if errors.As(event.Err, &stalled) {
+			entry = entry.WithField("stack", string(stalled.Stack))
+		}
+		entry.Errorf("%s '%s' is stalled", event.Kind, event.Name)
+	case EventSkipped:
+		entry.Debugf("%s '%s' is still running, this call is skipped", event.Kind, event.Name)
+	case EventFailed:
+		entry.Errorf("%s '%s' has failed", event.Kind, event.Name)
+	}
+}
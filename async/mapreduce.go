@@ -0,0 +1,146 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+type mapReduceConfig struct {
+	concurrency int
+}
+
+// MapReduceOption configures MapReduce and MapReduceChan.
+type MapReduceOption func(c *mapReduceConfig)
+
+// WithMapConcurrency sets the maximum number of map calls running concurrently. By default, or if n is not strictly positive, it is the number of CPUs.
+func WithMapConcurrency(n int) MapReduceOption {
+	return func(c *mapReduceConfig) {
+		if n > 0 {
+			c.concurrency = n
+		}
+	}
+}
+
+// MapReduce calls mapFn for each input concurrently and merges the values returned with reduceFn into a single one.
+// It is the way to aggregate the results of a query sent to several shards:
+//
+//	future := async.MapReduce(ctx, shards, func(ctx context.Context, shard Shard) (int, error) {
+//		return shard.Count(ctx, query)
+//	}, func(a int, b int) int {
+//		return a + b
+//	}, async.WithMapConcurrency(8))
+//	total, err := future.Await()
+//
+// The values are reduced in the order the map calls end, so reduceFn must be associative and commutative.
+// The first error returned by mapFn cancels the calls in progress and is the error of the future.
+// If there is no input, the result is the zero value of U.
+func MapReduce[T any, U any](ctx context.Context, inputs []T, mapFn func(ctx context.Context, input T) (U, error), reduceFn func(a U, b U) U, opts ...MapReduceOption) ErrFuture[U] {
+	return AsyncErrWithContext(ctx, func(ctx context.Context) (U, error) {
+		in := make(chan T)
+		go func() {
+			defer close(in)
+			for _, input := range inputs {
+				select {
+				case <-ctx.Done():
+					return
+				case in <- input:
+				}
+			}
+		}()
+		return mapReduce(ctx, in, mapFn, reduceFn, opts)
+	})
+}
+
+// MapReduceChan is the equivalent of MapReduce for inputs received from a channel. The future ends once the channel is closed and every value is reduced.
+func MapReduceChan[T any, U any](ctx context.Context, inputs <-chan T, mapFn func(ctx context.Context, input T) (U, error), reduceFn func(a U, b U) U, opts ...MapReduceOption) ErrFuture[U] {
+	return AsyncErrWithContext(ctx, func(ctx context.Context) (U, error) {
+		return mapReduce(ctx, inputs, mapFn, reduceFn, opts)
+	})
+}
+
+func mapReduce[T any, U any](ctx context.Context, inputs <-chan T, mapFn func(ctx context.Context, input T) (U, error), reduceFn func(a U, b U) U, opts []MapReduceOption) (U, error) {
+	c := &mapReduceConfig{concurrency: runtime.NumCPU()}
+	for _, opt := range opts {
+		opt(c)
+	}
+	mapCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	var (
+		mutex    sync.Mutex
+		wg       sync.WaitGroup
+		acc      U
+		reduced  bool
+		firstErr error
+	)
+	// semaphore holds a token for each map call in progress.
+	semaphore := make(chan struct{}, c.concurrency)
+loop:
+	for {
+		var input T
+		select {
+		case <-mapCtx.Done():
+			break loop
+		case i, ok := <-inputs:
+			if !ok {
+				break loop
+			}
+			input = i
+		}
+		select {
+		case <-mapCtx.Done():
+			break loop
+		case semaphore <- struct{}{}:
+		}
+		wg.Add(1)
+		go func(input T) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			var value U
+			err := protect(func() (err error) {
+				value, err = mapFn(mapCtx, input)
+				return err
+			})
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				if firstErr == nil {
+					firstErr = err
+					cancel()
+				}
+				return
+			}
+			if firstErr != nil {
+				return
+			}
+			if reduced {
+				acc = reduceFn(acc, value)
+			} else {
+				acc = value
+				reduced = true
+			}
+		}(input)
+	}
+	wg.Wait()
+	var zero U
+	if firstErr != nil {
+		return zero, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return zero, err
+	}
+	return acc, nil
+}
@@ -0,0 +1,64 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func sum(a int, b int) int {
+	return a + b
+}
+
+func TestMapReduce(t *testing.T) {
+	inputs := []int{1, 2, 3, 4, 5}
+	future := MapReduce(context.Background(), inputs, func(_ context.Context, input int) (int, error) {
+		return input * input, nil
+	}, sum, WithMapConcurrency(2))
+	result, err := future.Await()
+	assert.NoError(t, err)
+	assert.Equal(t, 55, result)
+}
+
+func TestMapReduce_Error(t *testing.T) {
+	failure := errors.New("failure")
+	future := MapReduce(context.Background(), []int{1, 2, 3}, func(ctx context.Context, input int) (int, error) {
+		if input == 2 {
+			return 0, failure
+		}
+		return input, nil
+	}, sum)
+	_, err := future.Await()
+	assert.ErrorIs(t, err, failure)
+}
+
+func TestMapReduceChan(t *testing.T) {
+	inputs := make(chan int)
+	go func() {
+		defer close(inputs)
+		for i := 1; i <= 10; i++ {
+			inputs <- i
+		}
+	}()
+	future := MapReduceChan(context.Background(), inputs, func(_ context.Context, input int) (int, error) {
+		return input, nil
+	}, sum)
+	result, err := future.Await()
+	assert.NoError(t, err)
+	assert.Equal(t, 55, result)
+}
@@ -0,0 +1,185 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package metrics provides an implementation of async.MetricsHook exporting Prometheus metrics.
+//
+//	hook, err := metrics.NewPrometheusHook("my_project")
+//	if err != nil {
+//		logrus.Fatal(err)
+//	}
+//	prometheus.MustRegister(hook)
+//	async.SetMetricsHook(hook)
+package metrics
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	labelKind   = "kind"
+	labelName   = "name"
+	labelPool   = "pool"
+	labelReason = "reason"
+)
+
+// PrometheusHook is an async.MetricsHook, an async.PoolMetricsHook and a prometheus.Collector.
+type PrometheusHook struct {
+	started   *prometheus.CounterVec
+	completed *prometheus.CounterVec
+	failed    *prometheus.CounterVec
+	inFlight  *prometheus.GaugeVec
+	duration  *prometheus.HistogramVec
+	queue     *prometheus.GaugeVec
+	submitted *prometheus.CounterVec
+	rejected  *prometheus.CounterVec
+	wait      *prometheus.HistogramVec
+}
+
+func NewPrometheusHook(namespace string) (*PrometheusHook, error) {
+	if len(namespace) == 0 {
+		return nil, fmt.Errorf("namespace cannot be empty")
+	}
+	return &PrometheusHook{
+		started: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "async",
+			Name:      "started_total",
+			Help:      "Total of asynchronous executions started",
+		}, []string{labelKind, labelName}),
+		completed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "async",
+			Name:      "completed_total",
+			Help:      "Total of asynchronous executions completed, whether they succeeded or not",
+		}, []string{labelKind, labelName}),
+		failed: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "async",
+			Name:      "failed_total",
+			Help:      "Total of asynchronous executions that ended in error",
+		}, []string{labelKind, labelName}),
+		inFlight: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "async",
+			Name:      "in_flight",
+			Help:      "Number of asynchronous executions started and not completed yet",
+		}, []string{labelKind, labelName}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "async",
+			Name:      "duration_seconds",
+			Help:      "Duration of the asynchronous executions in seconds",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{labelKind, labelName}),
+		queue: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Subsystem: "async",
+			Name:      "pool_queue_depth",
+			Help:      "Number of jobs waiting in the queue of a pool",
+		}, []string{labelPool}),
+		submitted: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "async",
+			Name:      "pool_submitted_total",
+			Help:      "Total of jobs submitted to a pool, including the jobs rejected",
+		}, []string{labelPool}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Subsystem: "async",
+			Name:      "pool_rejected_total",
+			Help:      "Total of jobs submitted to a pool that have not been executed",
+		}, []string{labelPool, labelReason}),
+		wait: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Subsystem: "async",
+			Name:      "pool_queue_wait_seconds",
+			Help:      "Time the jobs waited in the queue of a pool before being executed, in seconds",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{labelPool}),
+	}, nil
+}
+
+func (h *PrometheusHook) Started(kind async.Kind, name string) {
+	h.started.WithLabelValues(string(kind), name).Inc()
+	h.inFlight.WithLabelValues(string(kind), name).Inc()
+}
+
+func (h *PrometheusHook) Completed(kind async.Kind, name string, duration time.Duration, err error) {
+	h.completed.WithLabelValues(string(kind), name).Inc()
+	h.inFlight.WithLabelValues(string(kind), name).Dec()
+	h.duration.WithLabelValues(string(kind), name).Observe(duration.Seconds())
+	if err != nil {
+		h.failed.WithLabelValues(string(kind), name).Inc()
+	}
+}
+
+func (h *PrometheusHook) QueueDepth(pool string, depth int) {
+	h.queue.WithLabelValues(pool).Set(float64(depth))
+}
+
+func (h *PrometheusHook) Submitted(pool string) {
+	h.submitted.WithLabelValues(pool).Inc()
+}
+
+func (h *PrometheusHook) Rejected(pool string, err error) {
+	h.rejected.WithLabelValues(pool, rejectReason(err)).Inc()
+}
+
+func (h *PrometheusHook) Dequeued(pool string, wait time.Duration) {
+	h.wait.WithLabelValues(pool).Observe(wait.Seconds())
+}
+
+// rejectReason returns the value of the label reason for the error a job has been rejected with.
+func rejectReason(err error) string {
+	switch {
+	case errors.Is(err, async.ErrQueueFull):
+		return "queue_full"
+	case errors.Is(err, async.ErrJobDropped):
+		return "dropped"
+	case errors.Is(err, async.ErrPoolClosed):
+		return "closed"
+	case errors.Is(err, async.ErrDeadlineUnreachable):
+		return "deadline_unreachable"
+	default:
+		return "cancelled"
+	}
+}
+
+func (h *PrometheusHook) Collect(ch chan<- prometheus.Metric) {
+	h.started.Collect(ch)
+	h.completed.Collect(ch)
+	h.failed.Collect(ch)
+	h.inFlight.Collect(ch)
+	h.duration.Collect(ch)
+	h.queue.Collect(ch)
+	h.submitted.Collect(ch)
+	h.rejected.Collect(ch)
+	h.wait.Collect(ch)
+}
+
+func (h *PrometheusHook) Describe(ch chan<- *prometheus.Desc) {
+	h.started.Describe(ch)
+	h.completed.Describe(ch)
+	h.failed.Describe(ch)
+	h.inFlight.Describe(ch)
+	h.duration.Describe(ch)
+	h.queue.Describe(ch)
+	h.submitted.Describe(ch)
+	h.rejected.Describe(ch)
+	h.wait.Describe(ch)
+}
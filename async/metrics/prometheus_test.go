@@ -0,0 +1,60 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/perses/common/async"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrometheusHook(t *testing.T) {
+	hook, err := NewPrometheusHook("test")
+	assert.NoError(t, err)
+	registry := prometheus.NewRegistry()
+	assert.NoError(t, registry.Register(hook))
+	async.SetMetricsHook(hook)
+	defer async.SetMetricsHook(nil)
+
+	_, _ = async.AsyncErr(func() (int, error) {
+		return 0, fmt.Errorf("failure")
+	}).Await()
+	pool := async.NewPool(1, 1, async.WithPoolName("test"))
+	_, _ = pool.Submit(func(_ context.Context) (interface{}, error) {
+		return nil, nil
+	}).Await()
+	assert.NoError(t, pool.Shutdown(context.Background()))
+	_, _ = pool.Submit(func(_ context.Context) (interface{}, error) {
+		return nil, nil
+	}).Await()
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(hook.started.WithLabelValues(string(async.KindAsync), "")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(hook.failed.WithLabelValues(string(async.KindAsync), "")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(hook.completed.WithLabelValues(string(async.KindPool), "test")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(hook.failed.WithLabelValues(string(async.KindPool), "test")))
+	assert.Equal(t, float64(0), testutil.ToFloat64(hook.inFlight.WithLabelValues(string(async.KindPool), "test")))
+	assert.Equal(t, float64(2), testutil.ToFloat64(hook.submitted.WithLabelValues("test")))
+	assert.Equal(t, float64(1), testutil.ToFloat64(hook.rejected.WithLabelValues("test", "closed")))
+	assert.Equal(t, 1, testutil.CollectAndCount(hook.wait))
+}
+
+func TestNewPrometheusHook_EmptyNamespace(t *testing.T) {
+	_, err := NewPrometheusHook("")
+	assert.Error(t, err)
+}
@@ -0,0 +1,58 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+)
+
+// Labels are key/value pairs describing an asynchronous execution, like the component it belongs to or the tenant it works for.
+type Labels map[string]string
+
+// Named is implemented by the futures returned by AsyncNamed. A task is named by its method String.
+type Named interface {
+	Name() string
+}
+
+// Labeled is implemented by the futures returned by AsyncNamed.
+// A SimpleTask or a Task can implement it as well, the TaskManager of the package async/taskhelper then reports its labels.
+type Labeled interface {
+	Labels() Labels
+}
+
+// AsyncNamed is the equivalent of AsyncErrWithContext for a function identified by a name and some labels.
+// The name is the one reported to the MetricsHook. The future returned implements Named and Labeled:
+//
+//	future := async.AsyncNamed(ctx, "fetch-dashboards", async.Labels{"project": project}, fetch)
+//	name := future.(async.Named).Name()
+func AsyncNamed[T any](ctx context.Context, name string, labels Labels, f func(ctx context.Context) (T, error)) ErrFuture[T] {
+	return &errNext[T]{state: runNamed(ctx, name, labels, f)}
+}
+
+// Name returns the name given by AsyncNamed or WithName. It is empty for the other futures.
+func (s *state[T]) Name() string {
+	return s.name
+}
+
+// Labels returns a copy of the labels given by AsyncNamed or WithLabels. It is nil for the other futures.
+func (s *state[T]) Labels() Labels {
+	if s.labels == nil {
+		return nil
+	}
+	labels := make(Labels, len(s.labels))
+	for k, v := range s.labels {
+		labels[k] = v
+	}
+	return labels
+}
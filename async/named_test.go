@@ -0,0 +1,40 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncNamed(t *testing.T) {
+	labels := Labels{"project": "perses"}
+	future := AsyncNamed(context.Background(), "fetch", labels, func(_ context.Context) (int, error) {
+		return 1, nil
+	})
+	result, err := future.Await()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result)
+	assert.Equal(t, "fetch", future.(Named).Name())
+	assert.Equal(t, labels, future.(Labeled).Labels())
+	// the labels returned are a copy
+	future.(Labeled).Labels()["project"] = "other"
+	assert.Equal(t, "perses", future.(Labeled).Labels()["project"])
+
+	anonymous := AsyncErr(func() (int, error) { return 1, nil })
+	assert.Empty(t, anonymous.(Named).Name())
+	assert.Nil(t, anonymous.(Labeled).Labels())
+}
@@ -0,0 +1,128 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+)
+
+type asyncConfig struct {
+	name      string
+	labels    Labels
+	pool      *Pool
+	noRecover bool
+	buffer    int
+	id        string
+}
+
+// AsyncOption tunes how an asynchronous function is executed by Go, AsyncErr, AsyncWithContext, AsyncErrWithContext and AsyncStream.
+type AsyncOption func(c *asyncConfig)
+
+// WithName sets the name identifying the function in the MetricsHook. The future returned implements Named.
+func WithName(name string) AsyncOption {
+	return func(c *asyncConfig) {
+		c.name = name
+	}
+}
+
+// WithLabels sets the labels describing the function. The future returned implements Labeled.
+func WithLabels(labels Labels) AsyncOption {
+	return func(c *asyncConfig) {
+		c.labels = labels
+	}
+}
+
+// WithExecutionID sets the ID of the execution, instead of generating one, like to reuse the ID of a request. See ExecutionID.
+func WithExecutionID(id string) AsyncOption {
+	return func(c *asyncConfig) {
+		c.id = id
+	}
+}
+
+// WithPool executes the function as a job of the Pool instead of in a new go-routine.
+// The function is then reported as a job of the pool, so WithName, WithLabels and WithRecover have no effect, unlike WithExecutionID.
+// The context given to the constructor bounds the wait for room in the queue, and it is still observed by the function once executed.
+func WithPool(p *Pool) AsyncOption {
+	return func(c *asyncConfig) {
+		c.pool = p
+	}
+}
+
+// WithRecover sets whether a panic of the function is recovered. By default, it is, and the future fails with an *ErrPanicked.
+// WithRecover(false) lets the panic crash the process, which is preferable when a panic means the state of the application is corrupted.
+func WithRecover(recover bool) AsyncOption {
+	return func(c *asyncConfig) {
+		c.noRecover = !recover
+	}
+}
+
+// WithBuffer sets the number of values the producer of a StreamFuture can emit in advance of the consumer. By default, it is 0.
+func WithBuffer(n int) AsyncOption {
+	return func(c *asyncConfig) {
+		if n > 0 {
+			c.buffer = n
+		}
+	}
+}
+
+func newAsyncConfig(opts []AsyncOption) *asyncConfig {
+	c := &asyncConfig{}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// runWith executes the function according to the configuration.
+func runWith[T any](ctx context.Context, c *asyncConfig, f func(ctx context.Context) (T, error)) *state[T] {
+	if c.pool != nil {
+		var opts []SubmitOption
+		if len(c.id) > 0 {
+			opts = append(opts, WithJobID(c.id))
+		}
+		return submit(ctx, c.pool, bind(ctx, f), opts)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	s := newState[T](cancel)
+	s.name = c.name
+	s.labels = c.labels
+	s.noRecover = c.noRecover
+	s.id = c.id
+	s.submitter = submitter()
+	spawn(func() {
+		_ = s.execute(ctx, KindAsync, c.name, f)
+	})
+	return s
+}
+
+// bind returns a function for a job of a Pool, calling f with a context cancelled once ctx or the context of the job is done.
+// The context given to f is derived from the context of the job, so it keeps its values (like the execution ID) and its deadline.
+func bind[T any](ctx context.Context, f func(ctx context.Context) (T, error)) func(ctx context.Context) (T, error) {
+	if ctx.Done() == nil {
+		// ctx is never cancelled, the context of the job is enough.
+		return f
+	}
+	return func(jobCtx context.Context) (T, error) {
+		merged, cancel := context.WithCancel(jobCtx)
+		defer cancel()
+		go func() {
+			select {
+			case <-ctx.Done():
+				cancel()
+			case <-merged.Done():
+			}
+		}()
+		return f(merged)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWithName(t *testing.T) {
+	future := AsyncErr(func() (int, error) {
+		return 1, nil
+	}, WithName("compute"), WithLabels(Labels{"team": "perses"}))
+	_, _ = future.Await()
+	assert.Equal(t, "compute", future.(Named).Name())
+	assert.Equal(t, Labels{"team": "perses"}, future.(Labeled).Labels())
+}
+
+func TestWithPool(t *testing.T) {
+	pool := NewPool(1, 1)
+	defer pool.Shutdown(context.Background())
+	release := blockPool(t, pool)
+	future := Go(func() int {
+		return 1
+	}, WithPool(pool))
+	// the only worker of the pool is busy
+	time.Sleep(10 * time.Millisecond)
+	assert.False(t, future.IsDone())
+	release()
+	assert.Equal(t, 1, future.Await())
+}
+
+func TestWithPool_Context(t *testing.T) {
+	pool := NewPool(1, 1)
+	defer pool.Shutdown(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	future := AsyncErrWithContext(ctx, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}, WithPool(pool))
+	cancel()
+	_, err := future.Await()
+	assert.Equal(t, context.Canceled, err)
+}
+
+func TestWithPool_ContextValues(t *testing.T) {
+	pool := NewPool(1, 1, WithJobTimeout(time.Minute))
+	defer pool.Shutdown(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	future := AsyncErrWithContext(ctx, func(ctx context.Context) (string, error) {
+		// the context of the job is kept even if the context of the caller is cancellable
+		_, ok := ctx.Deadline()
+		assert.True(t, ok)
+		return ExecutionID(ctx), nil
+	}, WithPool(pool), WithExecutionID("req-1"))
+	id, err := future.Await()
+	assert.NoError(t, err)
+	assert.Equal(t, "req-1", id)
+}
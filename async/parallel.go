@@ -0,0 +1,158 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// ForEach calls f for each item with at most limit calls running concurrently. If limit is not strictly positive, there is no limit.
+// It waits for every call to end and returns the errors aggregated in a MultiError, or nil if every call succeeded.
+// Once the context is done, the remaining items are not processed and the error of the context is added to the errors returned.
+func ForEach[T any](ctx context.Context, items []T, limit int, f func(ctx context.Context, item T) error) error {
+	_, err := ParallelMap(ctx, items, limit, func(ctx context.Context, item T) (struct{}, error) {
+		return struct{}{}, f(ctx, item)
+	})
+	return err
+}
+
+// ParallelMap calls f for each item with at most limit calls running concurrently. If limit is not strictly positive, there is no limit.
+// It returns the results of the calls that succeeded, in the order they ended, and the errors aggregated in a MultiError.
+// Once the context is done, the remaining items are not processed and the error of the context is added to the errors returned.
+func ParallelMap[T any, U any](ctx context.Context, items []T, limit int, f func(ctx context.Context, item T) (U, error)) ([]U, error) {
+	results := make([]U, 0, len(items))
+	err := parallelMap(ctx, items, limit, f, func(_ int, result U) {
+		results = append(results, result)
+	})
+	return results, err
+}
+
+// ParallelMapOrdered is the same as ParallelMap, except the results are in the same order as the items:
+// the result of items[i] is at the index i, whatever the order the calls ended.
+// The result of an item that failed or that has not been processed is the zero value of U.
+func ParallelMapOrdered[T any, U any](ctx context.Context, items []T, limit int, f func(ctx context.Context, item T) (U, error)) ([]U, error) {
+	results := make([]U, len(items))
+	err := parallelMap(ctx, items, limit, f, func(index int, result U) {
+		results[index] = result
+	})
+	return results, err
+}
+
+// parallelMap calls f for each item, like ParallelMap, and gives every successful result to collect. collect is never called concurrently.
+func parallelMap[T any, U any](ctx context.Context, items []T, limit int, f func(ctx context.Context, item T) (U, error), collect func(index int, result U)) error {
+	if limit <= 0 || limit > len(items) {
+		limit = len(items)
+	}
+	var (
+		mutex sync.Mutex
+		errs  MultiError
+		wg    sync.WaitGroup
+	)
+	// semaphore holds a token for each call in progress.
+	semaphore := make(chan struct{}, limit)
+	for i, item := range items {
+		select {
+		case <-ctx.Done():
+		case semaphore <- struct{}{}:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func(index int, item T) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			var result U
+			err := protect(func() error {
+				var err error
+				result, err = f(ctx, item)
+				return err
+			})
+			mutex.Lock()
+			defer mutex.Unlock()
+			if err != nil {
+				errs = append(errs, err)
+				return
+			}
+			collect(index, result)
+		}(i, item)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		errs = append(errs, err)
+	}
+	return errs.errorOrNil()
+}
+
+// ChunkError is the error of a chunk processed by ParallelChunks. It tells which items the failure is about.
+type ChunkError struct {
+	// Index is the index of the chunk.
+	Index int
+	// Start and End are the bounds of the chunk in the items: the chunk is items[Start:End].
+	Start int
+	End   int
+	Err   error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("chunk %d (items %d to %d) failed: %s", e.Index, e.Start, e.End, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+type chunk[T any] struct {
+	index int
+	start int
+	items []T
+}
+
+// ParallelChunks splits the items into chunks of chunkSize items and calls f for each chunk with at most limit calls running concurrently.
+// It is meant for the large datasets, where a go-routine per item is too fine-grained. If limit is not strictly positive, there is no limit.
+// It returns the results of every chunk that succeeded concatenated in the order of the chunks,
+// and the errors aggregated in a MultiError, each one being a *ChunkError telling which chunk failed.
+// Once the context is done, the remaining chunks are not processed and the error of the context is added to the errors returned.
+func ParallelChunks[T any, U any](ctx context.Context, items []T, chunkSize int, limit int, f func(ctx context.Context, chunk []T) ([]U, error)) ([]U, error) {
+	if chunkSize <= 0 {
+		chunkSize = 1
+	}
+	chunks := make([]chunk[T], 0, (len(items)+chunkSize-1)/chunkSize)
+	for start := 0; start < len(items); start += chunkSize {
+		end := start + chunkSize
+		if end > len(items) {
+			end = len(items)
+		}
+		// the capacity is limited so f cannot overwrite the next chunk by appending to its own.
+		chunks = append(chunks, chunk[T]{index: len(chunks), start: start, items: items[start:end:end]})
+	}
+	results, err := ParallelMapOrdered(ctx, chunks, limit, func(ctx context.Context, c chunk[T]) ([]U, error) {
+		result, err := f(ctx, c.items)
+		if err != nil {
+			return nil, &ChunkError{Index: c.index, Start: c.start, End: c.start + len(c.items), Err: err}
+		}
+		return result, nil
+	})
+	size := 0
+	for _, result := range results {
+		size += len(result)
+	}
+	merged := make([]U, 0, size)
+	for _, result := range results {
+		merged = append(merged, result...)
+	}
+	return merged, err
+}
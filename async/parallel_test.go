@@ -0,0 +1,112 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParallelMap(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6}
+	var running int32
+	results, err := ParallelMap(context.Background(), items, 2, func(_ context.Context, item int) (int, error) {
+		defer atomic.AddInt32(&running, -1)
+		if atomic.AddInt32(&running, 1) > 2 {
+			return 0, fmt.Errorf("limit exceeded")
+		}
+		if item%3 == 0 {
+			return 0, fmt.Errorf("%d is a multiple of 3", item)
+		}
+		return item * 10, nil
+	})
+	sort.Ints(results)
+	assert.Equal(t, []int{10, 20, 40, 50}, results)
+	var errs MultiError
+	assert.ErrorAs(t, err, &errs)
+	assert.Len(t, errs, 2)
+}
+
+func TestForEach(t *testing.T) {
+	var sum int32
+	err := ForEach(context.Background(), []int32{1, 2, 3}, 0, func(_ context.Context, item int32) error {
+		atomic.AddInt32(&sum, item)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, int32(6), sum)
+}
+
+func TestParallelMapOrdered(t *testing.T) {
+	items := []int{5, 4, 3, 2, 1}
+	results, err := ParallelMapOrdered(context.Background(), items, 0, func(_ context.Context, item int) (string, error) {
+		// the first items end last
+		time.Sleep(time.Duration(item) * time.Millisecond)
+		if item == 3 {
+			return "", fmt.Errorf("failure")
+		}
+		return fmt.Sprintf("item-%d", item), nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, []string{"item-5", "item-4", "", "item-2", "item-1"}, results)
+}
+
+func TestParallelChunks(t *testing.T) {
+	items := make([]int, 10)
+	for i := range items {
+		items[i] = i
+	}
+	results, err := ParallelChunks(context.Background(), items, 3, 2, func(_ context.Context, chunk []int) ([]int, error) {
+		doubled := make([]int, 0, len(chunk))
+		for _, item := range chunk {
+			doubled = append(doubled, item*2)
+		}
+		return doubled, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 2, 4, 6, 8, 10, 12, 14, 16, 18}, results)
+}
+
+func TestParallelChunks_Error(t *testing.T) {
+	items := []int{0, 1, 2, 3, 4}
+	results, err := ParallelChunks(context.Background(), items, 2, 0, func(_ context.Context, chunk []int) ([]int, error) {
+		if chunk[0] == 2 {
+			return nil, fmt.Errorf("failure")
+		}
+		return chunk, nil
+	})
+	assert.Equal(t, []int{0, 1, 4}, results)
+	var chunkErr *ChunkError
+	assert.ErrorAs(t, err, &chunkErr)
+	assert.Equal(t, 1, chunkErr.Index)
+	assert.Equal(t, 2, chunkErr.Start)
+	assert.Equal(t, 4, chunkErr.End)
+}
+
+func TestMultiError(t *testing.T) {
+	failure := fmt.Errorf("failure")
+	err := MultiError{&ChunkError{Index: 3, Err: fmt.Errorf("chunk")}, fmt.Errorf("wrapped: %w", failure)}.errorOrNil()
+	assert.ErrorIs(t, err, failure)
+	assert.NotErrorIs(t, err, context.Canceled)
+	var chunkErr *ChunkError
+	assert.ErrorAs(t, err, &chunkErr)
+	assert.Equal(t, 3, chunkErr.Index)
+	assert.NoError(t, MultiError{}.errorOrNil())
+}
@@ -0,0 +1,164 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"sync"
+)
+
+// Pipeline chains stages of processing through bounded channels. Each stage consumes the channel of the previous one,
+// with its own concurrency. The first error of a stage cancels the whole pipeline.
+//
+// As a stage can change the type of the items, the stages are created with the functions Source, Stage and Sink:
+//
+//	p := async.NewPipeline(ctx)
+//	ids := async.Source(p, 100, func(ctx context.Context, emit func(string) bool) error {
+//		for _, id := range listIDs() {
+//			if !emit(id) {
+//				return nil
+//			}
+//		}
+//		return nil
+//	})
+//	documents := async.Stage(p, ids, 10, 100, fetchDocument)
+//	async.Sink(p, documents, 2, indexDocument)
+//	err := p.Wait()
+type Pipeline struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+	once   sync.Once
+	err    error
+}
+
+// NewPipeline creates an empty Pipeline. Cancelling the context stops every stage.
+func NewPipeline(ctx context.Context) *Pipeline {
+	ctx, cancel := context.WithCancel(ctx)
+	return &Pipeline{ctx: ctx, cancel: cancel}
+}
+
+// fail keeps the first error and cancels every stage.
+func (p *Pipeline) fail(err error) {
+	p.once.Do(func() {
+		p.err = err
+		p.cancel()
+	})
+}
+
+// run executes the given number of workers and calls onceDone when they all ended.
+func (p *Pipeline) run(workers int, worker func(), onceDone func()) {
+	if workers < 1 {
+		workers = 1
+	}
+	stageWG := &sync.WaitGroup{}
+	stageWG.Add(workers)
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer p.wg.Done()
+			defer stageWG.Done()
+			worker()
+		}()
+	}
+	if onceDone != nil {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			stageWG.Wait()
+			onceDone()
+		}()
+	}
+}
+
+// Wait blocks until every stage ended. It returns the first error of a stage, or the error of the context if the pipeline has been cancelled.
+func (p *Pipeline) Wait() error {
+	p.wg.Wait()
+	p.fail(p.ctx.Err())
+	return p.err
+}
+
+// Source adds the first stage of the pipeline. f produces the items by calling emit, which returns false once the pipeline is cancelled.
+// buffer is the size of the channel returned.
+func Source[T any](p *Pipeline, buffer int, f func(ctx context.Context, emit func(item T) bool) error) <-chan T {
+	out := make(chan T, buffer)
+	emit := func(item T) bool {
+		select {
+		case <-p.ctx.Done():
+			return false
+		case out <- item:
+			return true
+		}
+	}
+	p.run(1, func() {
+		if err := protect(func() error { return f(p.ctx, emit) }); err != nil {
+			p.fail(err)
+		}
+	}, func() { close(out) })
+	return out
+}
+
+// Stage adds a stage transforming each item of the channel in with f, with at most concurrency calls of f running at the same time.
+// buffer is the size of the channel returned. The order of the items is not kept when concurrency is greater than 1.
+func Stage[In any, Out any](p *Pipeline, in <-chan In, concurrency int, buffer int, f func(ctx context.Context, item In) (Out, error)) <-chan Out {
+	out := make(chan Out, buffer)
+	p.run(concurrency, func() {
+		for {
+			var item In
+			var ok bool
+			select {
+			case <-p.ctx.Done():
+				return
+			case item, ok = <-in:
+				if !ok {
+					return
+				}
+			}
+			var result Out
+			if err := protect(func() (err error) {
+				result, err = f(p.ctx, item)
+				return err
+			}); err != nil {
+				p.fail(err)
+				return
+			}
+			select {
+			case <-p.ctx.Done():
+				return
+			case out <- result:
+			}
+		}
+	}, func() { close(out) })
+	return out
+}
+
+// Sink adds the last stage of the pipeline, consuming each item of the channel in with f, with at most concurrency calls of f running at the same time.
+func Sink[T any](p *Pipeline, in <-chan T, concurrency int, f func(ctx context.Context, item T) error) {
+	p.run(concurrency, func() {
+		for {
+			select {
+			case <-p.ctx.Done():
+				return
+			case item, ok := <-in:
+				if !ok {
+					return
+				}
+				if err := protect(func() error { return f(p.ctx, item) }); err != nil {
+					p.fail(err)
+					return
+				}
+			}
+		}
+	}, nil)
+}
@@ -0,0 +1,73 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func numbers(n int) func(ctx context.Context, emit func(int) bool) error {
+	return func(_ context.Context, emit func(int) bool) error {
+		for i := 1; i <= n; i++ {
+			if !emit(i) {
+				return nil
+			}
+		}
+		return nil
+	}
+}
+
+func TestPipeline(t *testing.T) {
+	p := NewPipeline(context.Background())
+	source := Source(p, 10, numbers(100))
+	strings := Stage(p, source, 4, 10, func(_ context.Context, item int) (string, error) {
+		return strconv.Itoa(item * 2), nil
+	})
+	var sum int64
+	Sink(p, strings, 2, func(_ context.Context, item string) error {
+		i, err := strconv.Atoi(item)
+		atomic.AddInt64(&sum, int64(i))
+		return err
+	})
+	assert.NoError(t, p.Wait())
+	assert.Equal(t, int64(10100), sum)
+}
+
+func TestPipeline_Error(t *testing.T) {
+	p := NewPipeline(context.Background())
+	source := Source(p, 0, func(ctx context.Context, emit func(int) bool) error {
+		// the source would never end if the error of the stage didn't cancel the pipeline
+		for i := 0; ; i++ {
+			if !emit(i) {
+				return nil
+			}
+		}
+	})
+	doubled := Stage(p, source, 2, 0, func(_ context.Context, item int) (int, error) {
+		if item == 10 {
+			return 0, fmt.Errorf("failure")
+		}
+		return item * 2, nil
+	})
+	Sink(p, doubled, 1, func(_ context.Context, _ int) error {
+		return nil
+	})
+	assert.EqualError(t, p.Wait(), "failure")
+}
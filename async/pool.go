@@ -0,0 +1,486 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/perses/common/retry"
+)
+
+// Pool executes jobs through a fixed number of workers, or a number following the load (see WithAutoscaling), instead of spawning one go-routine per job.
+// It should be preferred to Go when fanning out thousands of small jobs.
+//
+//	pool := async.NewPool(10, 100)
+//	defer pool.Shutdown(context.Background())
+//	future := async.Submit(pool, func(ctx context.Context) (int, error) {
+//		return compute(ctx)
+//	})
+//	result, err := future.Await()
+type Pool struct {
+	// name identifies the pool in the MetricsHook.
+	name string
+	// limiter, when set, throttles the execution of the jobs.
+	limiter RateLimiter
+	// concurrency, when set, caps the number of jobs executed at the same time.
+	concurrency ConcurrencyLimiter
+	logger      Logger
+	// deadLetter, when set, receives every job that failed for good.
+	deadLetter func(letter DeadLetter)
+	// jobTimeout, when set, is the maximum duration of each execution of a job.
+	jobTimeout time.Duration
+	// jobs is the bounded queue of jobs waiting for a worker.
+	jobs dispatcher
+	// policy and workStealing define the queue created once the options are applied.
+	policy       OverflowPolicy
+	workStealing bool
+	// scaling, when set, lets the number of workers vary between minWorkers and scaling.MaxWorkers.
+	scaling    *Autoscaling
+	minWorkers int
+	// scaleMutex protects free, the indexes of the workers not running, and the changes of running, the number of workers running.
+	scaleMutex sync.Mutex
+	free       []int
+	running    int32
+	// busy is the number of workers executing a job.
+	busy int32
+	// latency is the average time the jobs wait before being executed.
+	latency latencyEstimator
+	// mutex protects closed and guarantees no job is sent to the queue once it is closed.
+	mutex  sync.RWMutex
+	closed bool
+	// closing is cancelled as soon as Shutdown is called, so the jobs waiting for room in the queue give up instead of delaying the Shutdown.
+	closing      context.Context
+	startClosing context.CancelFunc
+	// ctx is the parent context of every job. It is cancelled when the Shutdown doesn't end in time.
+	ctx     context.Context
+	cancel  context.CancelFunc
+	workers sync.WaitGroup
+}
+
+// RateLimiter is able to throttle the jobs executed by a Pool. It is implemented by the limiters of the package ratelimit.
+type RateLimiter interface {
+	// Wait blocks until an event can happen or until the context is done.
+	Wait(ctx context.Context) error
+}
+
+// ConcurrencyLimiter caps the number of jobs of a Pool executed at the same time. It is implemented by the Limiter of the package adaptive.
+type ConcurrencyLimiter interface {
+	// Acquire blocks until a job can be executed or until the context is done.
+	// The function returned is called with the error of the job once it ended.
+	Acquire(ctx context.Context) (func(err error), error)
+}
+
+// PoolOption configures a Pool.
+type PoolOption func(p *Pool)
+
+// WithRateLimiter throttles the jobs: before executing a job, a worker waits for the limiter to allow it.
+// If the limiter returns an error, the job is not executed and its future fails with this error.
+func WithRateLimiter(limiter RateLimiter) PoolOption {
+	return func(p *Pool) {
+		p.limiter = limiter
+	}
+}
+
+// WithConcurrencyLimiter caps the number of jobs executed at the same time below the number of workers:
+// before executing a job, a worker waits for the limiter to have room for it, then gives it the outcome of the job.
+// If the limiter returns an error, the job is not executed and its future fails with this error.
+func WithConcurrencyLimiter(limiter ConcurrencyLimiter) PoolOption {
+	return func(p *Pool) {
+		p.concurrency = limiter
+	}
+}
+
+// WithPoolName sets the name identifying the pool in the MetricsHook. By default, it is "default".
+func WithPoolName(name string) PoolOption {
+	return func(p *Pool) {
+		p.name = name
+	}
+}
+
+// WithLogger sets the Logger notified when the pool starts, stops, and when a job panics. By default, it is a logrus Logger.
+func WithLogger(logger Logger) PoolOption {
+	return func(p *Pool) {
+		p.logger = logger
+	}
+}
+
+// WithOverflowPolicy sets what Submit does when the queue is full. By default, it is OverflowBlock.
+// The jobs dropped by OverflowDropOldest and OverflowDropNewest are given to the function set by WithDeadLetter.
+func WithOverflowPolicy(policy OverflowPolicy) PoolOption {
+	return func(p *Pool) {
+		p.policy = policy
+	}
+}
+
+// WithWorkStealing gives each worker its own queue instead of a queue shared by all of them: the jobs submitted are spread across the queues,
+// and a worker whose queue is empty steals the jobs of the others. It reduces the contention when the jobs are submitted at a high rate.
+// The priorities are only honored within each queue, and OverflowDropOldest drops the oldest job of a single queue, not of the whole pool.
+func WithWorkStealing() PoolOption {
+	return func(p *Pool) {
+		p.workStealing = true
+	}
+}
+
+// WithDeadLetter sets the function receiving every job that failed for good, once its retries are exhausted (see WithRetry),
+// so the failed background work is not silently dropped. It is called by the worker that executed the job, so it must be fast.
+func WithDeadLetter(handler func(letter DeadLetter)) PoolOption {
+	return func(p *Pool) {
+		p.deadLetter = handler
+	}
+}
+
+// WithJobTimeout sets the maximum duration of each execution of a job, see Timeout. When it is combined with WithRetry, the timeout applies to each attempt.
+// A job that failed because of the timeout has an error wrapping ErrExecutionTimeout.
+func WithJobTimeout(timeout time.Duration) PoolOption {
+	return func(p *Pool) {
+		p.jobTimeout = timeout
+	}
+}
+
+// NewPool creates a Pool and starts its workers.
+// If workers is not strictly positive, the number of CPUs is used instead. With WithAutoscaling, it is the minimum number of workers.
+// queueSize is the maximum number of jobs waiting for a worker, once reached Submit blocks until a worker is available. It is at least 1.
+func NewPool(workers int, queueSize int, opts ...PoolOption) *Pool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	closing, startClosing := context.WithCancel(context.Background())
+	p := &Pool{
+		name:         "default",
+		logger:       NewLogrusLogger(nil),
+		ctx:          ctx,
+		cancel:       cancel,
+		closing:      closing,
+		startClosing: startClosing,
+	}
+	for _, opt := range opts {
+		opt(p)
+	}
+	p.minWorkers = workers
+	maxWorkers := workers
+	if p.scaling != nil && p.scaling.MaxWorkers > workers {
+		maxWorkers = p.scaling.MaxWorkers
+	}
+	for i := maxWorkers - 1; i >= workers; i-- {
+		p.free = append(p.free, i)
+	}
+	p.running = int32(workers)
+	if p.workStealing {
+		p.jobs = newStealingQueue(maxWorkers, queueSize, p.policy)
+	} else {
+		p.jobs = newJobQueue(queueSize, p.policy)
+	}
+	p.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work(i)
+	}
+	p.logger.Log(Event{Type: EventStarted, Kind: KindPool, Name: p.name})
+	return p
+}
+
+func (p *Pool) work(worker int) {
+	defer p.workers.Done()
+	var idleTimeout time.Duration
+	if worker >= p.minWorkers {
+		idleTimeout = p.scaling.idleTimeout()
+	}
+	// the labels of the worker, set again after each job since the job changes them.
+	labels := pprof.WithLabels(context.Background(), pprof.Labels(ProfileLabelKind, string(KindPool), ProfileLabelName, p.name))
+	pprof.SetGoroutineLabels(labels)
+	for {
+		j, ok := p.jobs.pop(worker, idleTimeout)
+		if !ok {
+			if idleTimeout > 0 && !p.retire(worker) {
+				continue
+			}
+			return
+		}
+		GetMetricsHook().QueueDepth(p.name, p.jobs.len())
+		wait := time.Since(j.submitted)
+		p.latency.observe(wait)
+		if hook := getPoolMetricsHook(); hook != nil {
+			hook.Dequeued(p.name, wait)
+		}
+		atomic.AddInt32(&p.busy, 1)
+		j.run()
+		atomic.AddInt32(&p.busy, -1)
+		pprof.SetGoroutineLabels(labels)
+	}
+}
+
+type submitConfig struct {
+	priority  Priority
+	retry     bool
+	retryOpts []retry.Option
+	payload   interface{}
+	id        string
+}
+
+// SubmitOption configures how a job is submitted to a Pool.
+type SubmitOption func(c *submitConfig)
+
+// WithPriority sets the priority of the job. The jobs with a higher priority are dispatched to the workers first.
+// By default, it is PriorityNormal.
+func WithPriority(priority Priority) SubmitOption {
+	return func(c *submitConfig) {
+		c.priority = priority
+	}
+}
+
+// WithRetry retries the job when it fails, according to the options of the package retry.
+// A Logger EventRetried is sent for each retry, unless the options set their own retry.WithOnRetry.
+func WithRetry(opts ...retry.Option) SubmitOption {
+	return func(c *submitConfig) {
+		c.retry = true
+		c.retryOpts = opts
+	}
+}
+
+// WithPayload attaches a value describing the job, like the message it processes. It is given back in the DeadLetter if the job fails.
+func WithPayload(payload interface{}) SubmitOption {
+	return func(c *submitConfig) {
+		c.payload = payload
+	}
+}
+
+// WithJobID sets the execution ID of the job, instead of generating one, like to reuse the ID of the message it processes. See ExecutionID.
+func WithJobID(id string) SubmitOption {
+	return func(c *submitConfig) {
+		c.id = id
+	}
+}
+
+// Submit is the untyped equivalent of the function Submit.
+func (p *Pool) Submit(f func(ctx context.Context) (interface{}, error), opts ...SubmitOption) ErrFuture[interface{}] {
+	return Submit(p, f, opts...)
+}
+
+// SubmitWithContext is the untyped equivalent of the function SubmitWithContext.
+func (p *Pool) SubmitWithContext(ctx context.Context, f func(ctx context.Context) (interface{}, error), opts ...SubmitOption) ErrFuture[interface{}] {
+	return SubmitWithContext(ctx, p, f, opts...)
+}
+
+// QueueDepth returns the number of jobs waiting for a worker.
+func (p *Pool) QueueDepth() int {
+	return p.jobs.len()
+}
+
+// QueueCapacity returns the maximum number of jobs waiting for a worker.
+func (p *Pool) QueueCapacity() int {
+	return p.jobs.capacity()
+}
+
+// Attempt is an execution of a job by a Pool.
+type Attempt struct {
+	Start    time.Time
+	Duration time.Duration
+	// Err is the error returned by the job, or the *ErrPanicked if it panicked.
+	Err error
+}
+
+// DeadLetter describes a job submitted to a Pool that failed for good. It is given to the function set by WithDeadLetter.
+type DeadLetter struct {
+	// Pool is the name of the pool that executed the job.
+	Pool string
+	// Payload is the value attached to the job by WithPayload, nil otherwise.
+	Payload interface{}
+	// Err is the error the future of the job failed with.
+	Err error
+	// Attempts is the history of the executions of the job, in order.
+	Attempts []Attempt
+	// ExecutionID is the ID of the execution of the job, see ExecutionID.
+	ExecutionID string
+}
+
+// Submit adds the job to the queue of the pool and returns the ErrFuture holding its result.
+// By default, it blocks while the queue is full, see WithOverflowPolicy for the alternatives. If the pool is already shut down, the future fails with ErrPoolClosed.
+// The context given to the job is cancelled when the future is cancelled or when the pool is forced to stop.
+func Submit[T any](p *Pool, f func(ctx context.Context) (T, error), opts ...SubmitOption) ErrFuture[T] {
+	return SubmitWithContext(context.Background(), p, f, opts...)
+}
+
+// SubmitWithContext is the equivalent of Submit giving up waiting for room in the queue once the context is done.
+// In that case, the future fails with the error of the context. The context only bounds the wait:
+// once the job is in the queue, it is not cancelled by the context. Combined with QueueDepth and QueueCapacity,
+// it lets a producer slow down instead of piling up jobs during a burst.
+func SubmitWithContext[T any](ctx context.Context, p *Pool, f func(ctx context.Context) (T, error), opts ...SubmitOption) ErrFuture[T] {
+	return &errNext[T]{state: submit(ctx, p, f, opts)}
+}
+
+// submit adds the job to the queue of the pool and returns the state holding its result, see SubmitWithContext.
+func submit[T any](ctx context.Context, p *Pool, f func(ctx context.Context) (T, error), opts []SubmitOption) *state[T] {
+	c := &submitConfig{priority: PriorityNormal}
+	for _, opt := range opts {
+		opt(c)
+	}
+	jobCtx, cancel := context.WithCancel(p.ctx)
+	s := newState[T](cancel)
+	s.id = c.id
+	if getProfilingLabels() != ProfilingLabelsNone {
+		jobCtx = inheritLabels(jobCtx, ctx)
+		s.submitter = submitter()
+	}
+	if hook := getPoolMetricsHook(); hook != nil {
+		hook.Submitted(p.name)
+	}
+	p.mutex.RLock()
+	defer p.mutex.RUnlock()
+	if p.closed {
+		var zero T
+		s.complete(zero, ErrPoolClosed)
+		cancel()
+		p.rejected(ErrPoolClosed)
+		return s
+	}
+	// attempts is only accessed by the worker executing the job.
+	var attempts []Attempt
+	if p.jobTimeout > 0 {
+		f = Timeout(p.jobTimeout, f)
+	}
+	execute := func(ctx context.Context) (T, error) {
+		var result T
+		start := time.Now()
+		err := protect(func() (err error) {
+			result, err = f(ctx)
+			return err
+		})
+		attempts = append(attempts, Attempt{Start: start, Duration: time.Since(start), Err: err})
+		return result, err
+	}
+	if c.retry {
+		onRetry := retry.WithOnRetry(func(attempt int, err error, _ time.Duration) {
+			p.logger.Log(Event{Type: EventRetried, Kind: KindPool, Name: p.name, Err: err, Attempt: attempt, ExecutionID: s.ExecutionID()})
+		})
+		execute = retry.Wrap(execute, append([]retry.Option{onRetry}, c.retryOpts...)...)
+	}
+	run := func() {
+		if s.IsDone() {
+			// the future has been cancelled while it was waiting in the queue.
+			return
+		}
+		if p.limiter != nil {
+			if err := p.limiter.Wait(jobCtx); err != nil {
+				var zero T
+				s.complete(zero, err)
+				cancel()
+				return
+			}
+		}
+		release := func(error) {}
+		if p.concurrency != nil {
+			var err error
+			if release, err = p.concurrency.Acquire(jobCtx); err != nil {
+				var zero T
+				s.complete(zero, err)
+				cancel()
+				return
+			}
+		}
+		err := s.execute(jobCtx, KindPool, p.name, execute)
+		release(err)
+		var panicked *ErrPanicked
+		if errors.As(err, &panicked) {
+			p.logger.Log(Event{Type: EventPanicked, Kind: KindPool, Name: p.name, Err: err, ExecutionID: s.ExecutionID()})
+		}
+		if err != nil && p.deadLetter != nil {
+			p.deadLetter(DeadLetter{Pool: p.name, Payload: c.payload, Err: err, Attempts: attempts, ExecutionID: s.ExecutionID()})
+		}
+	}
+	drop := func(err error) {
+		var zero T
+		s.complete(zero, err)
+		cancel()
+		p.rejected(err)
+		// unlike a job dropped, a job rejected is not a dead letter: the caller is told right away by the future.
+		if p.deadLetter != nil && err == ErrJobDropped {
+			p.deadLetter(DeadLetter{Pool: p.name, Payload: c.payload, Err: err, ExecutionID: s.ExecutionID()})
+		}
+	}
+	pushCtx, stopPush := p.pushContext(ctx)
+	defer stopPush()
+	dropped, err := p.jobs.push(pushCtx, &job{run: run, drop: drop, priority: c.priority, submitted: time.Now()})
+	if err != nil {
+		if ctx.Err() == nil && p.closing.Err() != nil {
+			// the wait has been interrupted by the Shutdown.
+			err = ErrPoolClosed
+		}
+		drop(err)
+		return s
+	}
+	if dropped != nil {
+		dropped.drop(ErrJobDropped)
+	}
+	GetMetricsHook().QueueDepth(p.name, p.jobs.len())
+	p.scaleUp()
+	return s
+}
+
+// pushContext returns the context bounding the wait for room in the queue: it is done once ctx is done or once Shutdown is called.
+// The read lock of the mutex is held during the wait, interrupting it is what lets Shutdown take the lock without waiting for room in the queue.
+func (p *Pool) pushContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx.Done() == nil {
+		return p.closing, func() {}
+	}
+	pushCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-p.closing.Done():
+			cancel()
+		case <-pushCtx.Done():
+		}
+	}()
+	return pushCtx, cancel
+}
+
+// rejected reports to the PoolMetricsHook a job that will not be executed.
+func (p *Pool) rejected(err error) {
+	if hook := getPoolMetricsHook(); hook != nil {
+		hook.Rejected(p.name, err)
+	}
+}
+
+// Shutdown stops the pool from accepting new jobs and waits for the jobs already submitted to end.
+// If the context is done before, the context of the remaining jobs is cancelled and the error of the context is returned.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	// the jobs waiting for room in the queue hold the read lock, they must give up first.
+	p.startClosing()
+	p.mutex.Lock()
+	if !p.closed {
+		p.closed = true
+		p.jobs.close()
+	}
+	p.mutex.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		p.workers.Wait()
+	}()
+	var err error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	p.cancel()
+	p.logger.Log(Event{Type: EventStopped, Kind: KindPool, Name: p.name, Err: err})
+	return err
+}
@@ -0,0 +1,329 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/perses/common/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPool_Submit(t *testing.T) {
+	pool := NewPool(2, 10)
+	var running, maxRunning int32
+	futures := make([]ErrFuture[int], 0, 10)
+	for i := 0; i < 10; i++ {
+		value := i
+		futures = append(futures, Submit(pool, func(_ context.Context) (int, error) {
+			current := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			for {
+				previous := atomic.LoadInt32(&maxRunning)
+				if current <= previous || atomic.CompareAndSwapInt32(&maxRunning, previous, current) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+			return value, nil
+		}))
+	}
+	for i, future := range futures {
+		result, err := future.Await()
+		assert.NoError(t, err)
+		assert.Equal(t, i, result)
+	}
+	assert.LessOrEqual(t, maxRunning, int32(2))
+	assert.NoError(t, pool.Shutdown(context.Background()))
+}
+
+func TestPool_Shutdown(t *testing.T) {
+	pool := NewPool(1, 1)
+	future := pool.Submit(func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	// the job never ends by itself, so the shutdown has to force it.
+	assert.Equal(t, context.DeadlineExceeded, pool.Shutdown(ctx))
+	_, err := future.Await()
+	assert.Equal(t, context.Canceled, err)
+	_, err = pool.Submit(func(_ context.Context) (interface{}, error) {
+		return nil, nil
+	}).Await()
+	assert.Equal(t, ErrPoolClosed, err)
+}
+
+func TestPool_ShutdownWhileSubmitting(t *testing.T) {
+	pool := NewPool(1, 1)
+	release := make(chan struct{})
+	pool.Submit(func(_ context.Context) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	pool.Submit(func(_ context.Context) (interface{}, error) {
+		return nil, nil
+	})
+	// the queue is full, so this submission waits for room in the queue
+	submitted := make(chan error)
+	go func() {
+		_, err := pool.Submit(func(_ context.Context) (interface{}, error) {
+			return nil, nil
+		}).Await()
+		submitted <- err
+	}()
+	time.Sleep(20 * time.Millisecond)
+	stopped := make(chan error)
+	go func() {
+		stopped <- pool.Shutdown(context.Background())
+	}()
+	// the submission waiting must give up instead of blocking the shutdown
+	select {
+	case err := <-submitted:
+		assert.Equal(t, ErrPoolClosed, err)
+	case <-time.After(time.Second):
+		t.Fatal("the submission has not been interrupted by the shutdown")
+	}
+	close(release)
+	assert.NoError(t, <-stopped)
+}
+
+func TestPool_Priority(t *testing.T) {
+	pool := NewPool(1, 10)
+	defer pool.Shutdown(context.Background())
+	release := make(chan struct{})
+	// keep the only worker busy, so the following jobs are queued
+	blocking := pool.Submit(func(_ context.Context) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	var mutex sync.Mutex
+	var order []string
+	record := func(name string) func(context.Context) (interface{}, error) {
+		return func(_ context.Context) (interface{}, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			order = append(order, name)
+			return nil, nil
+		}
+	}
+	// wait for the blocking job to be taken by the worker
+	for pool.jobs.len() > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	futures := []ErrFuture[interface{}]{
+		pool.Submit(record("low"), WithPriority(PriorityLow)),
+		pool.Submit(record("normal")),
+		pool.Submit(record("high-1"), WithPriority(PriorityHigh)),
+		pool.Submit(record("high-2"), WithPriority(PriorityHigh)),
+	}
+	close(release)
+	_, _ = blocking.Await()
+	for _, future := range futures {
+		_, _ = future.Await()
+	}
+	assert.Equal(t, []string{"high-1", "high-2", "normal", "low"}, order)
+}
+
+func TestPool_DeadLetter(t *testing.T) {
+	letters := make(chan DeadLetter, 1)
+	var retried int32
+	pool := NewPool(1, 10,
+		WithDeadLetter(func(letter DeadLetter) { letters <- letter }),
+		WithLogger(LoggerFunc(func(event Event) {
+			if event.Type == EventRetried {
+				atomic.AddInt32(&retried, 1)
+			}
+		})),
+	)
+	defer pool.Shutdown(context.Background())
+	_, err := Submit(pool, func(_ context.Context) (int, error) {
+		return 0, errors.New("failure")
+	}, WithRetry(retry.WithMaxAttempts(3), retry.WithBackoff(retry.Constant(0))), WithPayload("message-1")).Await()
+	assert.EqualError(t, err, "giving up after 3 attempts: failure")
+	letter := <-letters
+	assert.Equal(t, "default", letter.Pool)
+	assert.Equal(t, "message-1", letter.Payload)
+	assert.Equal(t, err, letter.Err)
+	assert.Len(t, letter.Attempts, 3)
+	for _, attempt := range letter.Attempts {
+		assert.EqualError(t, attempt.Err, "failure")
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&retried))
+
+	// a job succeeding is not a dead letter, and neither is a panic recovered by a retry
+	attempt := 0
+	result, err := Submit(pool, func(_ context.Context) (int, error) {
+		attempt++
+		if attempt == 1 {
+			panic("boom")
+		}
+		return attempt, nil
+	}, WithRetry(retry.WithBackoff(retry.Constant(0)))).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result)
+	assert.Empty(t, letters)
+}
+
+// blockPool submits a job occupying the single worker of the pool until the returned function is called.
+func blockPool(t *testing.T, pool *Pool) func() {
+	started := make(chan struct{})
+	release := make(chan struct{})
+	pool.Submit(func(_ context.Context) (interface{}, error) {
+		close(started)
+		<-release
+		return nil, nil
+	})
+	<-started
+	return func() { close(release) }
+}
+
+func TestPool_OverflowPolicy(t *testing.T) {
+	value := func(v int) func(_ context.Context) (int, error) {
+		return func(_ context.Context) (int, error) { return v, nil }
+	}
+	t.Run("reject", func(t *testing.T) {
+		pool := NewPool(1, 1, WithOverflowPolicy(OverflowReject))
+		defer pool.Shutdown(context.Background())
+		release := blockPool(t, pool)
+		first := Submit(pool, value(1))
+		_, err := Submit(pool, value(2)).Await()
+		assert.Equal(t, ErrQueueFull, err)
+		release()
+		result, err := first.Await()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result)
+	})
+	t.Run("drop newest", func(t *testing.T) {
+		var letters []DeadLetter
+		pool := NewPool(1, 1, WithOverflowPolicy(OverflowDropNewest), WithDeadLetter(func(letter DeadLetter) {
+			letters = append(letters, letter)
+		}))
+		defer pool.Shutdown(context.Background())
+		release := blockPool(t, pool)
+		first := Submit(pool, value(1))
+		_, err := Submit(pool, value(2), WithPayload(2)).Await()
+		assert.Equal(t, ErrJobDropped, err)
+		release()
+		result, err := first.Await()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result)
+		assert.Len(t, letters, 1)
+		assert.Equal(t, 2, letters[0].Payload)
+	})
+	t.Run("drop oldest", func(t *testing.T) {
+		pool := NewPool(1, 2, WithOverflowPolicy(OverflowDropOldest))
+		defer pool.Shutdown(context.Background())
+		release := blockPool(t, pool)
+		first := Submit(pool, value(1))
+		second := Submit(pool, value(2))
+		third := Submit(pool, value(3))
+		_, err := first.Await()
+		assert.Equal(t, ErrJobDropped, err)
+		release()
+		for expected, future := range map[int]ErrFuture[int]{2: second, 3: third} {
+			result, err := future.Await()
+			assert.NoError(t, err)
+			assert.Equal(t, expected, result)
+		}
+	})
+}
+
+func TestPool_SubmitWithContext(t *testing.T) {
+	pool := NewPool(1, 1)
+	defer pool.Shutdown(context.Background())
+	release := blockPool(t, pool)
+	first := Submit(pool, func(_ context.Context) (int, error) { return 1, nil })
+	assert.Equal(t, 1, pool.QueueDepth())
+	assert.Equal(t, 1, pool.QueueCapacity())
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := SubmitWithContext(ctx, pool, func(_ context.Context) (int, error) { return 2, nil }).Await()
+	assert.Equal(t, context.DeadlineExceeded, err)
+	release()
+	result, err := first.Await()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result)
+	// the context only bounds the wait for room in the queue
+	ctx, cancel = context.WithCancel(context.Background())
+	future := pool.SubmitWithContext(ctx, func(jobCtx context.Context) (interface{}, error) {
+		cancel()
+		return "done", jobCtx.Err()
+	})
+	value, err := future.Await()
+	assert.NoError(t, err)
+	assert.Equal(t, "done", value)
+}
+
+// fixedLimiter is a ConcurrencyLimiter with a fixed limit, recording the errors it is released with.
+type fixedLimiter struct {
+	slots  chan struct{}
+	mutex  sync.Mutex
+	errors []error
+}
+
+func (l *fixedLimiter) Acquire(ctx context.Context) (func(err error), error) {
+	select {
+	case l.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	return func(err error) {
+		l.mutex.Lock()
+		l.errors = append(l.errors, err)
+		l.mutex.Unlock()
+		<-l.slots
+	}, nil
+}
+
+func TestPool_ConcurrencyLimiter(t *testing.T) {
+	limiter := &fixedLimiter{slots: make(chan struct{}, 1)}
+	pool := NewPool(4, 10, WithConcurrencyLimiter(limiter))
+	var running, maxRunning int32
+	var futures []ErrFuture[int]
+	for i := 0; i < 4; i++ {
+		index := i
+		futures = append(futures, Submit(pool, func(_ context.Context) (int, error) {
+			current := atomic.AddInt32(&running, 1)
+			defer atomic.AddInt32(&running, -1)
+			if current > atomic.LoadInt32(&maxRunning) {
+				atomic.StoreInt32(&maxRunning, current)
+			}
+			time.Sleep(5 * time.Millisecond)
+			if index == 0 {
+				return 0, errors.New("failure")
+			}
+			return index, nil
+		}))
+	}
+	for _, future := range futures {
+		_, _ = future.Await()
+	}
+	assert.NoError(t, pool.Shutdown(context.Background()))
+	assert.Equal(t, int32(1), maxRunning)
+	assert.Len(t, limiter.errors, 4)
+	failed := 0
+	for _, err := range limiter.errors {
+		if err != nil {
+			failed++
+		}
+	}
+	assert.Equal(t, 1, failed)
+}
@@ -0,0 +1,134 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"runtime"
+	"runtime/pprof"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// The pprof labels set on the go-routines executing the asynchronous functions, so the CPU and the goroutine profiles attribute the work to them.
+const (
+	// ProfileLabelKind is the Kind of the execution.
+	ProfileLabelKind = "async_kind"
+	// ProfileLabelName is the name of the future (see WithName) or of the pool executing the job.
+	ProfileLabelName = "async_name"
+	// ProfileLabelSubmitter is the function that started the execution, the first one of the call stack outside of this package.
+	ProfileLabelSubmitter = "async_submitter"
+)
+
+// packagePrefix is the prefix of the name of the functions of this package, as given by the runtime.
+const packagePrefix = "github.com/perses/common/async."
+
+// ProfilingLabels defines which pprof labels are set on the go-routines executing the asynchronous functions and the jobs of the pools.
+type ProfilingLabels int32
+
+const (
+	// ProfilingLabelsDefault sets the labels of the context given to the constructor, if any, ProfileLabelKind, ProfileLabelName and the labels set by WithLabels.
+	ProfilingLabelsDefault ProfilingLabels = iota
+	// ProfilingLabelsSubmitter sets ProfileLabelSubmitter on top of the default labels. Finding the submitter walks the call stack
+	// each time a function is started, which roughly doubles the cost of submitting a tiny job to a Pool.
+	ProfilingLabelsSubmitter
+	// ProfilingLabelsNone sets no label.
+	ProfilingLabelsNone
+)
+
+var profilingLabels int32
+
+// SetProfilingLabels sets which pprof labels are set, so the CPU and the goroutine profiles attribute the work to the asynchronous functions
+// instead of showing anonymous functions of this package. By default, it is ProfilingLabelsDefault.
+func SetProfilingLabels(labels ProfilingLabels) {
+	atomic.StoreInt32(&profilingLabels, int32(labels))
+}
+
+func getProfilingLabels() ProfilingLabels {
+	return ProfilingLabels(atomic.LoadInt32(&profilingLabels))
+}
+
+// submitters caches, for each program counter, the submitter found in its frames, since resolving the frames is costly.
+var submitters sync.Map
+
+// submitter returns the name of the first function of the call stack outside of this package, or an empty string if it is not part of the profiling labels.
+func submitter() string {
+	if getProfilingLabels() != ProfilingLabelsSubmitter {
+		return ""
+	}
+	var pcs [8]uintptr
+	n := runtime.Callers(2, pcs[:])
+	for _, pc := range pcs[:n] {
+		if name := submitterOf(pc); len(name) > 0 {
+			return name
+		}
+	}
+	return ""
+}
+
+// submitterOf returns the first function outside of this package among the frames of the program counter, which are several when functions are inlined.
+func submitterOf(pc uintptr) string {
+	if name, ok := submitters.Load(pc); ok {
+		return name.(string)
+	}
+	name := ""
+	frames := runtime.CallersFrames([]uintptr{pc})
+	for {
+		frame, more := frames.Next()
+		if !strings.HasPrefix(frame.Function, packagePrefix) || strings.HasSuffix(frame.File, "_test.go") {
+			name = frame.Function
+			break
+		}
+		if !more {
+			break
+		}
+	}
+	submitters.Store(pc, name)
+	return name
+}
+
+// profile calls f with the profiling labels describing the execution set on the current go-routine and on the context.
+func (s *state[T]) profile(ctx context.Context, kind Kind, name string, f func(ctx context.Context) (T, error)) (result T, err error) {
+	if getProfilingLabels() == ProfilingLabelsNone {
+		return f(ctx)
+	}
+	labels := []string{ProfileLabelKind, string(kind)}
+	if len(name) > 0 {
+		labels = append(labels, ProfileLabelName, name)
+	}
+	if len(s.submitter) > 0 {
+		labels = append(labels, ProfileLabelSubmitter, s.submitter)
+	}
+	for key, value := range s.labels {
+		labels = append(labels, key, value)
+	}
+	pprof.Do(ctx, pprof.Labels(labels...), func(ctx context.Context) {
+		result, err = f(ctx)
+	})
+	return result, err
+}
+
+// inheritLabels returns dst with the profiling labels of src, so a job executed by a Pool is attributed to the context that submitted it.
+func inheritLabels(dst context.Context, src context.Context) context.Context {
+	var labels []string
+	pprof.ForLabels(src, func(key, value string) bool {
+		labels = append(labels, key, value)
+		return true
+	})
+	if len(labels) == 0 {
+		return dst
+	}
+	return pprof.WithLabels(dst, pprof.Labels(labels...))
+}
@@ -0,0 +1,63 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func labelsOf(ctx context.Context) map[string]string {
+	labels := map[string]string{}
+	pprof.ForLabels(ctx, func(key, value string) bool {
+		labels[key] = value
+		return true
+	})
+	return labels
+}
+
+func TestProfilingLabels(t *testing.T) {
+	labels := AsyncWithContext(context.Background(), func(ctx context.Context) map[string]string {
+		return labelsOf(ctx)
+	}, WithName("compute"), WithLabels(Labels{"tenant": "a"})).Await()
+	assert.Equal(t, map[string]string{ProfileLabelKind: "async", ProfileLabelName: "compute", "tenant": "a"}, labels)
+
+	pool := NewPool(1, 1, WithPoolName("workers"))
+	defer pool.Shutdown(context.Background())
+	// the labels of the context submitting the job are kept
+	ctx := pprof.WithLabels(context.Background(), pprof.Labels("request", "42"))
+	labels, err := SubmitWithContext(ctx, pool, func(ctx context.Context) (map[string]string, error) {
+		return labelsOf(ctx), nil
+	}).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{ProfileLabelKind: "pool", ProfileLabelName: "workers", "request": "42"}, labels)
+
+	SetProfilingLabels(ProfilingLabelsSubmitter)
+	labels, err = Submit(pool, func(ctx context.Context) (map[string]string, error) {
+		return labelsOf(ctx), nil
+	}).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, "github.com/perses/common/async.TestProfilingLabels", labels[ProfileLabelSubmitter])
+
+	SetProfilingLabels(ProfilingLabelsNone)
+	defer SetProfilingLabels(ProfilingLabelsDefault)
+	labels, err = Submit(pool, func(ctx context.Context) (map[string]string, error) {
+		return labelsOf(ctx), nil
+	}).Await()
+	assert.NoError(t, err)
+	assert.Empty(t, labels)
+}
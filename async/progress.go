@@ -0,0 +1,98 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"sync"
+	"time"
+)
+
+// Progress describes how far a task has gotten.
+type Progress struct {
+	// Percentage is the part of the work already done, between 0 and 100.
+	Percentage float64
+	// Step is a human-readable description of what the task is currently doing.
+	Step string
+	// ETA is the estimated time remaining before the task ends. It is 0 when it is unknown.
+	ETA time.Duration
+}
+
+// ProgressReporter is implemented by the SimpleTask or the Task able to tell how far they have gotten.
+// It is optional: the TaskManager of the package async/taskhelper surfaces the progress of the tasks implementing it.
+// Progress is called concurrently with Execute, so it must be safe for concurrent use.
+type ProgressReporter interface {
+	Progress() Progress
+}
+
+// ProgressTracker is a helper to implement a ProgressReporter when the amount of work is known in advance.
+// The ETA is extrapolated from the speed observed since the tracker has been created.
+//
+//	type reindexTask struct {
+//		async.SimpleTask
+//		*async.ProgressTracker
+//	}
+//
+//	func (t *reindexTask) Execute(ctx context.Context, _ context.CancelFunc) error {
+//		t.SetStep("reindexing the dashboards")
+//		for _, dashboard := range dashboards {
+//			reindex(dashboard)
+//			t.Advance(1)
+//		}
+//		return nil
+//	}
+type ProgressTracker struct {
+	mutex sync.Mutex
+	total int64
+	done  int64
+	step  string
+	start time.Time
+}
+
+// NewProgressTracker creates a ProgressTracker for the given total amount of work.
+func NewProgressTracker(total int64) *ProgressTracker {
+	return &ProgressTracker{total: total, start: time.Now()}
+}
+
+// Advance records that n more units of work are done.
+func (p *ProgressTracker) Advance(n int64) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.done += n
+	if p.done > p.total {
+		p.done = p.total
+	}
+}
+
+// SetStep sets the description of what the task is currently doing.
+func (p *ProgressTracker) SetStep(step string) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	p.step = step
+}
+
+// Progress returns the progress reached so far.
+func (p *ProgressTracker) Progress() Progress {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	progress := Progress{Step: p.step, Percentage: 100}
+	if p.total <= 0 {
+		return progress
+	}
+	progress.Percentage = float64(p.done) * 100 / float64(p.total)
+	if p.done > 0 {
+		elapsed := time.Since(p.start)
+		progress.ETA = time.Duration(float64(elapsed) * float64(p.total-p.done) / float64(p.done))
+	}
+	return progress
+}
@@ -0,0 +1,40 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProgressTracker(t *testing.T) {
+	tracker := NewProgressTracker(4)
+	assert.Equal(t, Progress{}, tracker.Progress())
+	tracker.start = time.Now().Add(-time.Minute)
+	tracker.SetStep("reindexing")
+	tracker.Advance(1)
+	progress := tracker.Progress()
+	assert.Equal(t, "reindexing", progress.Step)
+	assert.Equal(t, float64(25), progress.Percentage)
+	// one unit of work took about one minute, so the three remaining ones should take about three minutes.
+	assert.InDelta(t, float64(3*time.Minute), float64(progress.ETA), float64(time.Second))
+	tracker.Advance(10)
+	assert.Equal(t, Progress{Step: "reindexing", Percentage: 100}, tracker.Progress())
+}
+
+func TestProgressTracker_NoTotal(t *testing.T) {
+	assert.Equal(t, Progress{Percentage: 100}, NewProgressTracker(0).Progress())
+}
@@ -0,0 +1,53 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+// Promise is a way to create an ErrFuture resolved from the outside instead of by an asynchronous function.
+// It is useful to bridge a callback-based library with the async package:
+//
+//	promise := async.NewPromise[string]()
+//	client.Publish(message, func(ack string, err error) {
+//		if err != nil {
+//			promise.Reject(err)
+//			return
+//		}
+//		promise.Resolve(ack)
+//	})
+//	ack, err := promise.Future().Await()
+//
+// Only the first call to Resolve or Reject has an effect. The following calls are ignored.
+type Promise[T any] struct {
+	state *state[T]
+}
+
+func NewPromise[T any]() *Promise[T] {
+	// there is no function to cancel, cancelling the future only marks it as cancelled.
+	return &Promise[T]{state: newState[T](func() {})}
+}
+
+// Resolve completes the future with the given result.
+func (p *Promise[T]) Resolve(result T) {
+	p.state.complete(result, nil)
+}
+
+// Reject completes the future with the given error.
+func (p *Promise[T]) Reject(err error) {
+	var zero T
+	p.state.complete(zero, err)
+}
+
+// Future returns the ErrFuture completed by Resolve or Reject.
+func (p *Promise[T]) Future() ErrFuture[T] {
+	return &errNext[T]{state: p.state}
+}
@@ -0,0 +1,41 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPromise_Resolve(t *testing.T) {
+	promise := NewPromise[string]()
+	go promise.Resolve("ack")
+	result, err := promise.Future().Await()
+	assert.NoError(t, err)
+	assert.Equal(t, "ack", result)
+	// the promise is already resolved, rejecting it has no effect
+	promise.Reject(fmt.Errorf("failure"))
+	result, err = promise.Future().Await()
+	assert.NoError(t, err)
+	assert.Equal(t, "ack", result)
+}
+
+func TestPromise_Reject(t *testing.T) {
+	promise := NewPromise[string]()
+	promise.Reject(fmt.Errorf("failure"))
+	_, err := promise.Future().Await()
+	assert.EqualError(t, err, "failure")
+}
@@ -0,0 +1,212 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+
+	psync "github.com/perses/common/sync"
+)
+
+// Priority is the priority of a job submitted to a Pool. The jobs with the highest priority are dispatched first.
+// Any int can be used, the constants below are there for convenience.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+// OverflowPolicy defines what a Pool does with a job submitted while its queue is full.
+type OverflowPolicy int
+
+const (
+	// OverflowBlock waits for a job to leave the queue. It is the default policy.
+	OverflowBlock OverflowPolicy = iota
+	// OverflowReject fails the future of the new job with ErrQueueFull, so the caller knows right away it has to back off.
+	OverflowReject
+	// OverflowDropOldest drops the job waiting for the longest time to make room for the new one. The future of the job dropped fails with ErrJobDropped.
+	OverflowDropOldest
+	// OverflowDropNewest drops the new job. Its future fails with ErrJobDropped.
+	OverflowDropNewest
+)
+
+type job struct {
+	run func()
+	// drop fails the future of the job when it is dropped from the queue.
+	drop     func(err error)
+	priority Priority
+	// seq keeps the order of submission between the jobs having the same priority.
+	seq uint64
+	// submitted is the time the job has been submitted, to measure the latency of the queue.
+	submitted time.Time
+}
+
+// jobHeap is a heap of jobs ordered by priority first, then by order of submission.
+type jobHeap []*job
+
+func (h jobHeap) Len() int { return len(h) }
+func (h jobHeap) Less(i, j int) bool {
+	if h[i].priority != h[j].priority {
+		return h[i].priority > h[j].priority
+	}
+	return h[i].seq < h[j].seq
+}
+func (h jobHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *jobHeap) Push(x interface{}) { *h = append(*h, x.(*job)) }
+func (h *jobHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	old[n-1] = nil
+	*h = old[:n-1]
+	return item
+}
+
+// dispatcher holds the jobs of a Pool until a worker executes them. It is either a jobQueue or a stealingQueue, see WithWorkStealing.
+type dispatcher interface {
+	// push adds the job to the queue. It must not be called once the queue is closed.
+	// When the queue is full, it applies the OverflowPolicy: it blocks until the context is done, or it returns ErrQueueFull, or it returns the job dropped.
+	push(ctx context.Context, j *job) (*job, error)
+	// pop removes a job from the queue for the given worker. It blocks while the queue is empty.
+	// It returns false once the queue is closed and empty, or once the worker waited for idleTimeout, if it is strictly positive.
+	pop(worker int, idleTimeout time.Duration) (*job, bool)
+	// close prevents the workers from waiting for new jobs. The jobs already in the queue can still be popped.
+	close()
+	len() int
+	capacity() int
+}
+
+// jobQueue is the bounded priority queue of a Pool, shared by all its workers.
+type jobQueue struct {
+	// slots has a unit acquired per job in the queue, so push blocks while the queue is full.
+	slots *psync.Weighted
+	// ready holds a token per job that can be popped.
+	ready   chan struct{}
+	maxJobs int
+	policy  OverflowPolicy
+	mutex   sync.Mutex
+	jobs    jobHeap
+	seq     uint64
+}
+
+func newJobQueue(capacity int, policy OverflowPolicy) *jobQueue {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &jobQueue{
+		slots:   psync.NewWeighted(int64(capacity)),
+		ready:   make(chan struct{}, capacity),
+		maxJobs: capacity,
+		policy:  policy,
+	}
+}
+
+func (q *jobQueue) push(ctx context.Context, j *job) (*job, error) {
+	if q.policy == OverflowBlock {
+		if err := q.slots.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+	} else if !q.slots.TryAcquire(1) {
+		switch q.policy {
+		case OverflowReject:
+			return nil, ErrQueueFull
+		case OverflowDropNewest:
+			return j, nil
+		case OverflowDropOldest:
+			if dropped := q.replaceOldest(j); dropped != nil {
+				return dropped, nil
+			}
+		}
+		// the queue is being emptied by a worker, the slot of the job it popped is about to be released.
+		if err := q.slots.Acquire(ctx, 1); err != nil {
+			return nil, err
+		}
+	}
+	q.insert(j)
+	return nil, nil
+}
+
+// insert adds the job to the queue. The slot of the job must have been acquired.
+func (q *jobQueue) insert(j *job) {
+	q.mutex.Lock()
+	q.seq++
+	j.seq = q.seq
+	heap.Push(&q.jobs, j)
+	q.mutex.Unlock()
+	q.ready <- struct{}{}
+}
+
+// replaceOldest removes the job submitted first from the queue and adds the new job in its place.
+// It returns the job removed, or nil if the queue is empty.
+func (q *jobQueue) replaceOldest(j *job) *job {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	if len(q.jobs) == 0 {
+		return nil
+	}
+	oldest := 0
+	for i := range q.jobs {
+		if q.jobs[i].seq < q.jobs[oldest].seq {
+			oldest = i
+		}
+	}
+	dropped := heap.Remove(&q.jobs, oldest).(*job)
+	q.seq++
+	j.seq = q.seq
+	// the new job takes the slot and the ready token of the job dropped.
+	heap.Push(&q.jobs, j)
+	return dropped
+}
+
+// pop removes the job with the highest priority from the queue. All the workers share the queue, so the worker is ignored.
+func (q *jobQueue) pop(_ int, idleTimeout time.Duration) (*job, bool) {
+	var idle <-chan time.Time
+	if idleTimeout > 0 {
+		timer := time.NewTimer(idleTimeout)
+		defer timer.Stop()
+		idle = timer.C
+	}
+	select {
+	case _, ok := <-q.ready:
+		if !ok {
+			return nil, false
+		}
+	case <-idle:
+		return nil, false
+	}
+	q.mutex.Lock()
+	j := heap.Pop(&q.jobs).(*job)
+	q.mutex.Unlock()
+	q.slots.Release(1)
+	return j, true
+}
+
+func (q *jobQueue) close() {
+	close(q.ready)
+}
+
+func (q *jobQueue) len() int {
+	q.mutex.Lock()
+	defer q.mutex.Unlock()
+	return len(q.jobs)
+}
+
+func (q *jobQueue) capacity() int {
+	return q.maxJobs
+}
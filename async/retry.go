@@ -0,0 +1,56 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/perses/common/retry"
+)
+
+// Retried is implemented by the futures returned by AsyncRetry.
+type Retried interface {
+	// Attempts returns the number of calls of the asynchronous function made so far.
+	Attempts() int
+}
+
+type retriedFuture[T any] struct {
+	*errNext[T]
+	attempts *int32
+}
+
+func (f *retriedFuture[T]) Attempts() int {
+	return int(atomic.LoadInt32(f.attempts))
+}
+
+// AsyncRetry is the equivalent of AsyncErrWithContext calling the function again when it fails, according to the options of the package retry.
+// The future is completed once the function succeeded or once retry gave up. It implements Retried:
+//
+//	future := async.AsyncRetry(ctx, []retry.Option{retry.WithMaxAttempts(5)}, func(ctx context.Context) (*Response, error) {
+//		return client.Get(ctx, id)
+//	})
+//	response, err := future.Await()
+//	attempts := future.(async.Retried).Attempts()
+func AsyncRetry[T any](ctx context.Context, policy []retry.Option, f func(ctx context.Context) (T, error)) ErrFuture[T] {
+	attempts := new(int32)
+	counted := func(ctx context.Context) (T, error) {
+		atomic.AddInt32(attempts, 1)
+		return f(ctx)
+	}
+	return &retriedFuture[T]{
+		errNext:  &errNext[T]{state: run(ctx, retry.Wrap(counted, policy...))},
+		attempts: attempts,
+	}
+}
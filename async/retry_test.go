@@ -0,0 +1,50 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/perses/common/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncRetry(t *testing.T) {
+	policy := []retry.Option{retry.WithMaxAttempts(5), retry.WithBackoff(retry.Constant(0))}
+	calls := 0
+	future := AsyncRetry(context.Background(), policy, func(_ context.Context) (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, errors.New("not yet")
+		}
+		return calls, nil
+	})
+	result, err := future.Await()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, result)
+	assert.Equal(t, 3, future.(Retried).Attempts())
+}
+
+func TestAsyncRetry_GiveUp(t *testing.T) {
+	policy := []retry.Option{retry.WithMaxAttempts(2), retry.WithBackoff(retry.Constant(0))}
+	failure := errors.New("failure")
+	future := AsyncRetry(context.Background(), policy, func(_ context.Context) (int, error) {
+		return 0, failure
+	})
+	_, err := future.Await()
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, 2, future.(Retried).Attempts())
+}
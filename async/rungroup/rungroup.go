@@ -0,0 +1,141 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rungroup runs a set of components together until the first one exits, then stops the others.
+// Each component is made of an execute function, blocking while the component runs, and of an interrupt function, making execute return.
+//
+// It is the glue between the components of an application and its main function:
+//
+//	g := &rungroup.Group{}
+//	g.AddTask(ctx, myConsumer)
+//	g.Add(func() error {
+//		return server.ListenAndServe()
+//	}, func(error) {
+//		_ = server.Shutdown(context.Background())
+//	})
+//	g.Add(rungroup.SignalHandler(ctx, os.Interrupt, syscall.SIGTERM))
+//	err := g.Run()
+package rungroup
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+
+	"github.com/perses/common/async"
+)
+
+type actor struct {
+	execute   func() error
+	interrupt func(err error)
+}
+
+// Group is a set of components run together. The zero value is ready to use.
+type Group struct {
+	actors []actor
+}
+
+// Add registers a component. execute must block until the component stops, and must return once interrupt has been called.
+// interrupt receives the error that ended the first component.
+func (g *Group) Add(execute func() error, interrupt func(err error)) {
+	g.actors = append(g.actors, actor{execute: execute, interrupt: interrupt})
+}
+
+// AddTask registers an async.SimpleTask as a component. The task is interrupted by cancelling the context given to its method Execute.
+// If the task is an async.Task, Initialize is called before Execute and Finalize after.
+func (g *Group) AddTask(ctx context.Context, task async.SimpleTask) {
+	ctx, cancel := context.WithCancel(ctx)
+	g.Add(func() error {
+		return runTask(ctx, cancel, task)
+	}, func(error) {
+		cancel()
+	})
+}
+
+// Run executes every component concurrently and waits for the first one to return. Then, every component is interrupted
+// and Run waits for all of them to return. It returns the error of the first component that returned.
+// Without any component, it returns nil immediately.
+func (g *Group) Run() error {
+	if len(g.actors) == 0 {
+		return nil
+	}
+	errs := make(chan error, len(g.actors))
+	for _, a := range g.actors {
+		go func(a actor) {
+			errs <- protect(a.execute)
+		}(a)
+	}
+	err := <-errs
+	for _, a := range g.actors {
+		a.interrupt(err)
+	}
+	for i := 1; i < len(g.actors); i++ {
+		<-errs
+	}
+	return err
+}
+
+// SignalHandler returns a component returning once one of the signals is received, or once the context is done.
+// The error returned for a signal is a *SignalError.
+func SignalHandler(ctx context.Context, signals ...os.Signal) (func() error, func(error)) {
+	ctx, cancel := context.WithCancel(ctx)
+	return func() error {
+			c := make(chan os.Signal, 1)
+			signal.Notify(c, signals...)
+			defer signal.Stop(c)
+			select {
+			case sig := <-c:
+				return &SignalError{Signal: sig}
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}, func(error) {
+			cancel()
+		}
+}
+
+// SignalError is returned by the component of SignalHandler when a signal is received.
+type SignalError struct {
+	Signal os.Signal
+}
+
+func (e *SignalError) Error() string {
+	return fmt.Sprintf("received signal %s", e.Signal)
+}
+
+func runTask(ctx context.Context, cancel context.CancelFunc, task async.SimpleTask) error {
+	t, isTask := task.(async.Task)
+	if isTask {
+		if err := t.Initialize(); err != nil {
+			return fmt.Errorf("unable to initialize the task '%s': %w", task.String(), err)
+		}
+	}
+	err := task.Execute(ctx, cancel)
+	if isTask {
+		if finalizeErr := t.Finalize(); finalizeErr != nil && err == nil {
+			err = fmt.Errorf("unable to finalize the task '%s': %w", task.String(), finalizeErr)
+		}
+	}
+	return err
+}
+
+// protect converts a panic of the component into an error, so the other components are still interrupted.
+func protect(execute func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("component panicked: %v", r)
+		}
+	}()
+	return execute()
+}
@@ -0,0 +1,69 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rungroup
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/perses/common/async"
+	"github.com/stretchr/testify/assert"
+)
+
+type blockingTask struct {
+	async.SimpleTask
+	stopped bool
+}
+
+func (b *blockingTask) String() string {
+	return "blocking"
+}
+
+func (b *blockingTask) Execute(ctx context.Context, _ context.CancelFunc) error {
+	<-ctx.Done()
+	b.stopped = true
+	return nil
+}
+
+func TestGroup(t *testing.T) {
+	failure := fmt.Errorf("failure")
+	task := &blockingTask{}
+	interrupted := make(chan struct{})
+	var interruptErr error
+	g := &Group{}
+	g.AddTask(context.Background(), task)
+	g.Add(func() error {
+		<-interrupted
+		return nil
+	}, func(err error) {
+		interruptErr = err
+		close(interrupted)
+	})
+	g.Add(func() error {
+		return failure
+	}, func(error) {})
+	assert.Equal(t, failure, g.Run())
+	assert.Equal(t, failure, interruptErr)
+	assert.True(t, task.stopped)
+	assert.NoError(t, (&Group{}).Run())
+}
+
+func TestSignalHandler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	g := &Group{}
+	g.Add(SignalHandler(ctx))
+	cancel()
+	assert.ErrorIs(t, g.Run(), context.Canceled)
+}
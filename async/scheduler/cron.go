@@ -0,0 +1,248 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule describes when a job must run.
+type Schedule interface {
+	// Next returns the next time the job must run, strictly after the given time.
+	Next(t time.Time) time.Time
+}
+
+type intervalSchedule struct {
+	interval time.Duration
+}
+
+// Every returns a Schedule running a job at a fixed interval.
+func Every(interval time.Duration) (Schedule, error) {
+	if interval <= 0 {
+		return nil, fmt.Errorf("interval cannot be negative or equal to 0")
+	}
+	return &intervalSchedule{interval: interval}, nil
+}
+
+func (s *intervalSchedule) Next(t time.Time) time.Time {
+	return t.Add(s.interval)
+}
+
+// bounds is the range of values accepted by a field of a cron expression.
+type bounds struct {
+	name     string
+	min, max uint
+}
+
+var (
+	secondBounds = bounds{name: "second", min: 0, max: 59}
+	minuteBounds = bounds{name: "minute", min: 0, max: 59}
+	hourBounds   = bounds{name: "hour", min: 0, max: 23}
+	domBounds    = bounds{name: "day of month", min: 1, max: 31}
+	monthBounds  = bounds{name: "month", min: 1, max: 12}
+	// 7 is accepted as an alias of Sunday (0)
+	dowBounds = bounds{name: "day of week", min: 0, max: 7}
+)
+
+// cronSchedule is a cron expression parsed. Each field is a set of bits where the bit n is set when the value n matches.
+type cronSchedule struct {
+	second, minute, hour, dom, month, dow uint64
+	// domStar and dowStar are true when the field is '*'. It changes how the day is matched, see matchDay.
+	domStar, dowStar bool
+	// location is the location the times are evaluated in. When nil, it is the location of the time given to Next.
+	location *time.Location
+}
+
+// shortcuts are the predefined cron expressions.
+var shortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseCron parses a standard cron expression with 5 fields: minute, hour, day of month, month and day of week.
+// A 6th field can be given first for the seconds. When there are only 5 fields, the jobs run at the second 0.
+// Each field accepts '*', a value, a range 'a-b', a step '*/n' or 'a-b/n' and a list of them separated by a comma.
+// The shortcuts @yearly (or @annually), @monthly, @weekly, @daily (or @midnight), @hourly and '@every <duration>' are accepted as well,
+// the duration following the syntax of time.ParseDuration.
+// The times are evaluated in the location of the time given to the method Next of the Schedule returned, see ParseCronInLocation to set it.
+func ParseCron(expr string) (Schedule, error) {
+	return ParseCronInLocation(expr, nil)
+}
+
+// ParseCronInLocation is the equivalent of ParseCron evaluating the times in the given location, whatever the location of the time given to Next.
+// For example, '0 9 * * 1-5' in the location Europe/Paris runs at 9 AM in Paris on weekdays, following the daylight saving time.
+// If location is nil, the times are evaluated in the location of the time given to Next.
+func ParseCronInLocation(expr string, location *time.Location) (Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	if strings.HasPrefix(expr, "@every ") {
+		interval, err := time.ParseDuration(strings.TrimSpace(strings.TrimPrefix(expr, "@every ")))
+		if err != nil {
+			return nil, fmt.Errorf("invalid duration in the cron expression %q: %w", expr, err)
+		}
+		return Every(interval)
+	}
+	if strings.HasPrefix(expr, "@") {
+		shortcut, ok := shortcuts[expr]
+		if !ok {
+			return nil, fmt.Errorf("unknown cron shortcut %q", expr)
+		}
+		expr = shortcut
+	}
+	fields := strings.Fields(expr)
+	if len(fields) != 5 && len(fields) != 6 {
+		return nil, fmt.Errorf("cron expression %q must have 5 or 6 fields, found %d", expr, len(fields))
+	}
+	s := &cronSchedule{second: 1, location: location}
+	var err error
+	if len(fields) == 6 {
+		if s.second, err = parseField(fields[0], secondBounds); err != nil {
+			return nil, err
+		}
+		fields = fields[1:]
+	}
+	s.domStar = fields[2] == "*"
+	s.dowStar = fields[4] == "*"
+	if s.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if s.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if s.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, err
+	}
+	if s.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if s.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, err
+	}
+	// Sunday can be written 0 or 7
+	if s.dow&(1<<7) != 0 {
+		s.dow |= 1
+	}
+	return s, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		partBits, err := parseRange(part, b)
+		if err != nil {
+			return 0, err
+		}
+		bits |= partBits
+	}
+	return bits, nil
+}
+
+func parseRange(expr string, b bounds) (uint64, error) {
+	start, end, step := b.min, b.max, uint(1)
+	rangeExpr := expr
+	if i := strings.Index(expr, "/"); i >= 0 {
+		rangeExpr = expr[:i]
+		var err error
+		if step, err = parseValue(expr[i+1:], b.name); err != nil {
+			return 0, err
+		}
+		if step == 0 {
+			return 0, fmt.Errorf("step of the %s field cannot be 0", b.name)
+		}
+	}
+	if rangeExpr != "*" {
+		var err error
+		bounds := strings.SplitN(rangeExpr, "-", 2)
+		if start, err = parseValue(bounds[0], b.name); err != nil {
+			return 0, err
+		}
+		end = start
+		if len(bounds) == 2 {
+			if end, err = parseValue(bounds[1], b.name); err != nil {
+				return 0, err
+			}
+		} else if step > 1 {
+			// 'a/n' means every n starting at a
+			end = b.max
+		}
+	}
+	if start < b.min || end > b.max || start > end {
+		return 0, fmt.Errorf("%q is out of the bounds [%d-%d] of the %s field", expr, b.min, b.max, b.name)
+	}
+	var bits uint64
+	for i := start; i <= end; i += step {
+		bits |= 1 << i
+	}
+	return bits, nil
+}
+
+func parseValue(value string, name string) (uint, error) {
+	i, err := strconv.ParseUint(value, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a valid value for the %s field", value, name)
+	}
+	return uint(i), nil
+}
+
+// maxSearchYears is the bound used to stop looking for the next time of an expression that never matches (like the 30th of February).
+const maxSearchYears = 5
+
+func (s *cronSchedule) Next(t time.Time) time.Time {
+	if s.location != nil {
+		t = t.In(s.location)
+	}
+	t = t.Truncate(time.Second).Add(time.Second)
+	limit := t.AddDate(maxSearchYears, 0, 0)
+	for t.Before(limit) {
+		if s.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minute&(1<<uint(t.Minute())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute()+1, 0, 0, t.Location())
+			continue
+		}
+		if s.second&(1<<uint(t.Second())) == 0 {
+			t = t.Add(time.Second)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+// matchDay follows the cron semantic: when both the day of month and the day of week are restricted, the day matches if one of them matches.
+func (s *cronSchedule) matchDay(t time.Time) bool {
+	domMatch := s.dom&(1<<uint(t.Day())) != 0
+	dowMatch := s.dow&(1<<uint(t.Weekday())) != 0
+	if s.domStar || s.dowStar {
+		return domMatch && dowMatch
+	}
+	return domMatch || dowMatch
+}
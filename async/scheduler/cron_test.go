@@ -0,0 +1,113 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	from := time.Date(2022, time.March, 15, 10, 30, 0, 0, time.UTC)
+	testSuites := []struct {
+		title  string
+		expr   string
+		result time.Time
+	}{
+		{
+			title:  "every minute",
+			expr:   "* * * * *",
+			result: time.Date(2022, time.March, 15, 10, 31, 0, 0, time.UTC),
+		},
+		{
+			title:  "every 15 minutes",
+			expr:   "*/15 * * * *",
+			result: time.Date(2022, time.March, 15, 10, 45, 0, 0, time.UTC),
+		},
+		{
+			title:  "every day at 2am",
+			expr:   "0 2 * * *",
+			result: time.Date(2022, time.March, 16, 2, 0, 0, 0, time.UTC),
+		},
+		{
+			title:  "working days at 9am",
+			expr:   "0 9 * * 1-5",
+			result: time.Date(2022, time.March, 16, 9, 0, 0, 0, time.UTC),
+		},
+		{
+			title:  "first day of the month or sunday",
+			expr:   "0 0 1 * 0",
+			result: time.Date(2022, time.March, 20, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			title:  "list of months",
+			expr:   "0 0 1 1,6 *",
+			result: time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			title:  "every 10 seconds",
+			expr:   "*/10 * * * * *",
+			result: time.Date(2022, time.March, 15, 10, 30, 10, 0, time.UTC),
+		},
+		{
+			title:  "second 30 of minute 45",
+			expr:   "30 45 * * * *",
+			result: time.Date(2022, time.March, 15, 10, 45, 30, 0, time.UTC),
+		},
+		{
+			title:  "daily shortcut",
+			expr:   "@daily",
+			result: time.Date(2022, time.March, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			title:  "monthly shortcut",
+			expr:   "@monthly",
+			result: time.Date(2022, time.April, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			title:  "every shortcut",
+			expr:   "@every 1h30m",
+			result: time.Date(2022, time.March, 15, 12, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, test := range testSuites {
+		t.Run(test.title, func(t *testing.T) {
+			schedule, err := ParseCron(test.expr)
+			assert.NoError(t, err)
+			assert.Equal(t, test.result, schedule.Next(from))
+		})
+	}
+}
+
+func TestParseCron_Error(t *testing.T) {
+	for _, expr := range []string{"* * * *", "* * * * * * *", "60 * * * * *", "@unknown", "@every 1x", "@every -1s", "60 * * * *", "* * 0 * *", "*/0 * * * *", "a * * * *", "5-2 * * * *"} {
+		_, err := ParseCron(expr)
+		assert.Error(t, err, expr)
+	}
+}
+
+func TestParseCronInLocation(t *testing.T) {
+	paris, err := time.LoadLocation("Europe/Paris")
+	assert.NoError(t, err)
+	schedule, err := ParseCronInLocation("0 9 * * 1-5", paris)
+	assert.NoError(t, err)
+	// it is 8 AM in Paris in winter
+	from := time.Date(2022, time.March, 15, 7, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2022, time.March, 15, 8, 0, 0, 0, time.UTC), schedule.Next(from).UTC())
+	// it is 7 AM in Paris in summer
+	from = time.Date(2022, time.July, 15, 5, 0, 0, 0, time.UTC)
+	assert.Equal(t, time.Date(2022, time.July, 15, 7, 0, 0, 0, time.UTC), schedule.Next(from).UTC())
+}
@@ -0,0 +1,197 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package scheduler provides a way to run async.SimpleTask or async.Task periodically, following a cron expression or a fixed interval.
+//
+// The Scheduler is itself a SimpleTask, so it is designed to be run by the app.Runner like any other task:
+//
+//	s := scheduler.New()
+//	if err := s.Cron("0 2 * * *", myNightlyTask); err != nil {
+//		logrus.Fatal(err)
+//	}
+//	if err := s.Interval(30*time.Second, myPeriodicTask); err != nil {
+//		logrus.Fatal(err)
+//	}
+//	app.NewRunner().WithTasks(s).Start()
+//
+// Each execution of a job receives its own context, cancelled when the scheduler stops.
+// An execution that is still running when the next one is due is not overlapped: the next one is skipped.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/perses/common/clock"
+	"github.com/sirupsen/logrus"
+)
+
+type job struct {
+	schedule Schedule
+	task     async.SimpleTask
+}
+
+// Scheduler runs the registered jobs according to their Schedule until the context given to Execute is done.
+type Scheduler struct {
+	clock clock.Clock
+	// location is the location the cron expressions are evaluated in.
+	location *time.Location
+	mutex    sync.Mutex
+	jobs     []job
+	started  bool
+}
+
+// Option configures a Scheduler.
+type Option func(s *Scheduler)
+
+// WithClock sets the Clock used to know when the jobs are due. By default, it is clock.Real.
+func WithClock(c clock.Clock) Option {
+	return func(s *Scheduler) {
+		s.clock = clock.OrReal(c)
+	}
+}
+
+// WithLocation sets the location the cron expressions given to Cron are evaluated in. By default, it is time.Local.
+func WithLocation(location *time.Location) Option {
+	return func(s *Scheduler) {
+		s.location = location
+	}
+}
+
+func New(opts ...Option) *Scheduler {
+	s := &Scheduler{clock: clock.Real}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Schedule registers the task to be executed according to the schedule.
+// If the task is an async.Task, Initialize is called before the first execution and Finalize once the scheduler stops.
+func (s *Scheduler) Schedule(schedule Schedule, task async.SimpleTask) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.started {
+		return fmt.Errorf("cannot schedule the task '%s', the scheduler is already started", task.String())
+	}
+	s.jobs = append(s.jobs, job{schedule: schedule, task: task})
+	return nil
+}
+
+// Cron registers the task to be executed according to the cron expression, evaluated in the location set by WithLocation.
+// See ParseCron for the syntax accepted.
+func (s *Scheduler) Cron(expr string, task async.SimpleTask) error {
+	schedule, err := ParseCronInLocation(expr, s.location)
+	if err != nil {
+		return err
+	}
+	return s.Schedule(schedule, task)
+}
+
+// Interval registers the task to be executed at a fixed interval.
+func (s *Scheduler) Interval(interval time.Duration, task async.SimpleTask) error {
+	schedule, err := Every(interval)
+	if err != nil {
+		return err
+	}
+	return s.Schedule(schedule, task)
+}
+
+func (s *Scheduler) String() string {
+	return "scheduler"
+}
+
+// Execute runs every job registered until the context is done. Then it waits for the executions in progress to end.
+func (s *Scheduler) Execute(ctx context.Context, cancelFunc context.CancelFunc) error {
+	s.mutex.Lock()
+	if s.started {
+		s.mutex.Unlock()
+		return fmt.Errorf("scheduler already started")
+	}
+	s.started = true
+	jobs := s.jobs
+	s.mutex.Unlock()
+
+	wg := &sync.WaitGroup{}
+	wg.Add(len(jobs))
+	for _, j := range jobs {
+		go func(j job) {
+			defer wg.Done()
+			s.loop(ctx, cancelFunc, j)
+		}(j)
+	}
+	wg.Wait()
+	return nil
+}
+
+func (s *Scheduler) loop(ctx context.Context, cancelFunc context.CancelFunc, j job) {
+	if t, ok := j.task.(async.Task); ok {
+		if err := t.Initialize(); err != nil {
+			logrus.WithError(err).Errorf("unable to initialize the task '%s', it won't be scheduled", t.String())
+			return
+		}
+		defer func() {
+			if err := t.Finalize(); err != nil {
+				logrus.WithError(err).Errorf("unable to finalize the task '%s'", t.String())
+			}
+		}()
+	}
+	// running is holding a token while an execution is in progress.
+	running := make(chan struct{}, 1)
+	executions := &sync.WaitGroup{}
+	defer executions.Wait()
+	for {
+		next := j.schedule.Next(s.clock.Now())
+		if next.IsZero() {
+			logrus.Warningf("task '%s' won't be executed anymore, its schedule has no next time", j.task.String())
+			return
+		}
+		timer := s.clock.NewTimer(next.Sub(s.clock.Now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			logrus.Debugf("task '%s' has been canceled", j.task.String())
+			return
+		case <-timer.C():
+		}
+		select {
+		case running <- struct{}{}:
+		default:
+			logrus.Warningf("task '%s' is still running, the execution planned at %s is skipped", j.task.String(), next)
+			continue
+		}
+		executions.Add(1)
+		go func() {
+			defer executions.Done()
+			defer func() { <-running }()
+			s.execute(ctx, cancelFunc, j.task)
+		}()
+	}
+}
+
+func (s *Scheduler) execute(ctx context.Context, cancelFunc context.CancelFunc, task async.SimpleTask) {
+	// each execution has its own context, so the sub go-routines it created are stopped when it ends.
+	jobCtx, jobCancel := context.WithCancel(ctx)
+	defer jobCancel()
+	id := async.NewExecutionID()
+	// a panicking task must not stop the scheduler, it is reported as an *async.ErrPanicked like an error.
+	err := async.Execute(jobCtx, async.KindTask, task.String(), id, true, func(ctx context.Context) error {
+		return task.Execute(ctx, cancelFunc)
+	})
+	if err != nil {
+		logrus.WithError(err).WithField("execution_id", id).Errorf("execution of the task '%s' ended in error", task.String())
+	}
+}
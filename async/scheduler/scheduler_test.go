@@ -0,0 +1,74 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/stretchr/testify/assert"
+)
+
+type counterTask struct {
+	async.SimpleTask
+	counter int32
+}
+
+func (c *counterTask) String() string {
+	return "counter"
+}
+
+func (c *counterTask) Execute(_ context.Context, _ context.CancelFunc) error {
+	atomic.AddInt32(&c.counter, 1)
+	return nil
+}
+
+func TestScheduler_Interval(t *testing.T) {
+	task := &counterTask{}
+	s := New()
+	assert.NoError(t, s.Interval(10*time.Millisecond, task))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.NoError(t, s.Execute(ctx, cancel))
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&task.counter), int32(5))
+	assert.Error(t, s.Interval(time.Second, task))
+}
+
+type panickingTask struct {
+	async.SimpleTask
+	counter int32
+}
+
+func (p *panickingTask) String() string {
+	return "panicking"
+}
+
+func (p *panickingTask) Execute(_ context.Context, _ context.CancelFunc) error {
+	atomic.AddInt32(&p.counter, 1)
+	panic("boom")
+}
+
+func TestScheduler_PanickingTask(t *testing.T) {
+	task := &panickingTask{}
+	s := New()
+	assert.NoError(t, s.Interval(10*time.Millisecond, task))
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	// the panic is recovered, so the task keeps being executed
+	assert.NoError(t, s.Execute(ctx, cancel))
+	assert.GreaterOrEqual(t, atomic.LoadInt32(&task.counter), int32(2))
+}
@@ -0,0 +1,86 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Selectable is implemented by every TypedFuture and every ErrFuture, whatever the type of their result, so they can be given together to Select.
+type Selectable interface {
+	IsDone() bool
+	Cancel()
+}
+
+// selectable is the part of the futures Select relies on. It is implemented by the state shared by every future of this package.
+type selectable interface {
+	selectDone() <-chan struct{}
+	selectResult() (interface{}, error)
+}
+
+func (s *state[T]) selectDone() <-chan struct{} {
+	s.trigger()
+	return s.doneChan()
+}
+
+func (s *state[T]) selectResult() (interface{}, error) {
+	return s.result, s.err
+}
+
+// Select waits for the first future to end and returns its index, its result and its error.
+// Unlike Race, the futures can have different types of result, and the others are not cancelled.
+// A nil future is ignored, so Select can be called in a loop to process the futures in the order they end:
+//
+//	futures := []async.Selectable{dashboards, datasources, users}
+//	for range futures {
+//		index, result, err := async.Select(ctx, futures...)
+//		if index < 0 {
+//			return err
+//		}
+//		futures[index] = nil
+//		...
+//	}
+//
+// If the context is done before any future ends, it returns -1 and the error of the context.
+// It returns -1 and an error as well if there is no future to wait for, or if a future is not created by this package.
+func Select(ctx context.Context, futures ...Selectable) (int, interface{}, error) {
+	cases := make([]reflect.SelectCase, 0, len(futures)+1)
+	// indexes is the index of the future of each case, the first case being the context.
+	indexes := make([]int, 0, len(futures)+1)
+	cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(ctx.Done())})
+	indexes = append(indexes, -1)
+	for i, future := range futures {
+		if future == nil {
+			continue
+		}
+		f, ok := future.(selectable)
+		if !ok {
+			return -1, nil, fmt.Errorf("future %d cannot be selected, it is not created by the package async", i)
+		}
+		cases = append(cases, reflect.SelectCase{Dir: reflect.SelectRecv, Chan: reflect.ValueOf(f.selectDone())})
+		indexes = append(indexes, i)
+	}
+	if len(cases) == 1 {
+		return -1, nil, fmt.Errorf("no future to select")
+	}
+	chosen, _, _ := reflect.Select(cases)
+	index := indexes[chosen]
+	if index < 0 {
+		return -1, nil, ctx.Err()
+	}
+	result, err := futures[index].(selectable).selectResult()
+	return index, result, err
+}
@@ -0,0 +1,65 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSelect(t *testing.T) {
+	failure := errors.New("failure")
+	release := make(chan struct{})
+	futures := []Selectable{
+		AsyncErr(func() (int, error) {
+			<-release
+			return 0, failure
+		}),
+		Go(func() string {
+			return "first"
+		}),
+	}
+	index, result, err := Select(context.Background(), futures...)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, index)
+	assert.Equal(t, "first", result)
+
+	futures[index] = nil
+	close(release)
+	index, _, err = Select(context.Background(), futures...)
+	assert.Equal(t, 0, index)
+	assert.ErrorIs(t, err, failure)
+
+	futures[index] = nil
+	index, _, err = Select(context.Background(), futures...)
+	assert.Equal(t, -1, index)
+	assert.Error(t, err)
+}
+
+func TestSelect_Context(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	future := AsyncWithContext(ctx, func(ctx context.Context) int {
+		<-ctx.Done()
+		time.Sleep(time.Second)
+		return 0
+	})
+	index, _, err := Select(ctx, future)
+	assert.Equal(t, -1, index)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,97 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"runtime"
+	"sync"
+)
+
+// ShardedPool executes jobs through a fixed number of workers, each of them having its own queue.
+// The jobs submitted with the same key are always executed by the same worker, one after the other in the order they have been submitted,
+// while the jobs with different keys run concurrently. It provides a per-entity ordering without a global serial queue:
+//
+//	pool := async.NewShardedPool(10, 100)
+//	defer pool.Shutdown(context.Background())
+//	future := async.SubmitKeyed(pool, userID, func(ctx context.Context) (*User, error) {
+//		return updateUser(ctx, userID)
+//	})
+type ShardedPool struct {
+	shards []*Pool
+}
+
+// NewShardedPool creates a ShardedPool and starts its workers.
+// If workers is not strictly positive, the number of CPUs is used instead.
+// queueSize is the maximum number of jobs waiting for each worker. The options are applied to every worker,
+// the name of the pool identifying each worker in the MetricsHook is suffixed by the index of the worker.
+// WithAutoscaling and WithWorkStealing are ignored: more than one worker per queue would break the order of the jobs sharing a key.
+func NewShardedPool(workers int, queueSize int, opts ...PoolOption) *ShardedPool {
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	p := &ShardedPool{shards: make([]*Pool, workers)}
+	for i := range p.shards {
+		index := i
+		shardOpts := append(opts[:len(opts):len(opts)], func(shard *Pool) {
+			shard.name = fmt.Sprintf("%s-%d", shard.name, index)
+			// each shard must keep a single worker.
+			shard.scaling = nil
+			shard.workStealing = false
+		})
+		p.shards[i] = NewPool(1, queueSize, shardOpts...)
+	}
+	return p
+}
+
+func (p *ShardedPool) shard(key string) *Pool {
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(key))
+	return p.shards[h.Sum32()%uint32(len(p.shards))]
+}
+
+// Submit is the untyped equivalent of the function SubmitKeyed.
+func (p *ShardedPool) Submit(key string, f func(ctx context.Context) (interface{}, error), opts ...SubmitOption) ErrFuture[interface{}] {
+	return SubmitKeyed(p, key, f, opts...)
+}
+
+// SubmitKeyed adds the job to the queue of the worker in charge of the key and returns the ErrFuture holding its result.
+// The order of the jobs sharing the same key is always kept, so WithPriority is ignored.
+// It blocks while the queue of the worker is full. If the pool is already shut down, the future fails with ErrPoolClosed.
+func SubmitKeyed[T any](p *ShardedPool, key string, f func(ctx context.Context) (T, error), opts ...SubmitOption) ErrFuture[T] {
+	return Submit(p.shard(key), f, append(opts[:len(opts):len(opts)], WithPriority(PriorityNormal))...)
+}
+
+// Shutdown stops the pool from accepting new jobs and waits for the jobs already submitted to end.
+// If the context is done before, the context of the remaining jobs is cancelled and the error of the context is returned.
+func (p *ShardedPool) Shutdown(ctx context.Context) error {
+	errs := make([]error, len(p.shards))
+	wg := &sync.WaitGroup{}
+	wg.Add(len(p.shards))
+	for i, shard := range p.shards {
+		go func(i int, shard *Pool) {
+			defer wg.Done()
+			errs[i] = shard.Shutdown(ctx)
+		}(i, shard)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShardedPool_Ordering(t *testing.T) {
+	p := NewShardedPool(4, 10, WithLogger(LoggerFunc(func(Event) {})))
+	var mutex sync.Mutex
+	executed := make(map[string][]int)
+	var futures []ErrFuture[int]
+	for i := 0; i < 100; i++ {
+		key := fmt.Sprintf("user-%d", i%5)
+		value := i
+		futures = append(futures, SubmitKeyed(p, key, func(_ context.Context) (int, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			executed[key] = append(executed[key], value)
+			return value, nil
+		}))
+	}
+	for i, future := range futures {
+		result, err := future.Await()
+		assert.NoError(t, err)
+		assert.Equal(t, i, result)
+	}
+	assert.NoError(t, p.Shutdown(context.Background()))
+	for key, values := range executed {
+		assert.Len(t, values, 20, key)
+		assert.IsIncreasing(t, values, key)
+	}
+}
+
+func TestShardedPool_Priority(t *testing.T) {
+	p := NewShardedPool(1, 10, WithLogger(LoggerFunc(func(Event) {})))
+	release := make(chan struct{})
+	// keep the only worker busy, so the following jobs are queued
+	blocking := SubmitKeyed(p, "key", func(_ context.Context) (int, error) {
+		<-release
+		return 0, nil
+	})
+	var mutex sync.Mutex
+	var order []int
+	record := func(value int) func(context.Context) (int, error) {
+		return func(_ context.Context) (int, error) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			order = append(order, value)
+			return value, nil
+		}
+	}
+	first := SubmitKeyed(p, "key", record(1))
+	second := SubmitKeyed(p, "key", record(2), WithPriority(PriorityHigh))
+	close(release)
+	_, _ = blocking.Await()
+	_, _ = first.Await()
+	_, _ = second.Await()
+	assert.NoError(t, p.Shutdown(context.Background()))
+	// the priority doesn't let a job jump ahead of the jobs submitted before with the same key
+	assert.Equal(t, []int{1, 2}, order)
+}
+
+func TestShardedPool_Autoscaling(t *testing.T) {
+	p := NewShardedPool(1, 10, WithAutoscaling(Autoscaling{MaxWorkers: 8}), WithLogger(LoggerFunc(func(Event) {})))
+	var running, maxRunning int32
+	var mutex sync.Mutex
+	var futures []ErrFuture[int]
+	for i := 0; i < 10; i++ {
+		futures = append(futures, SubmitKeyed(p, "key", func(_ context.Context) (int, error) {
+			mutex.Lock()
+			running++
+			if running > maxRunning {
+				maxRunning = running
+			}
+			mutex.Unlock()
+			time.Sleep(time.Millisecond)
+			mutex.Lock()
+			running--
+			mutex.Unlock()
+			return 0, nil
+		}))
+	}
+	for _, future := range futures {
+		_, err := future.Await()
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, p.Shutdown(context.Background()))
+	// the autoscaling is ignored, the jobs sharing a key never run concurrently
+	assert.Equal(t, int32(1), maxRunning)
+}
+
+func TestShardedPool_Closed(t *testing.T) {
+	p := NewShardedPool(2, 1, WithLogger(LoggerFunc(func(Event) {})))
+	assert.NoError(t, p.Shutdown(context.Background()))
+	_, err := p.Submit("key", func(_ context.Context) (interface{}, error) { return nil, nil }).Await()
+	assert.Equal(t, ErrPoolClosed, err)
+}
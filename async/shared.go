@@ -0,0 +1,86 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"sync"
+)
+
+// SharedGroup deduplicates the concurrent calls of a function sharing the same key: while a call for a key is in progress,
+// the following calls for the same key don't execute the function again but wait for the result of the call in progress.
+// Once the call ended, the next call for the key executes the function again.
+//
+// The zero value is ready to use.
+type SharedGroup[T any] struct {
+	group KeyedSharedGroup[string, T]
+}
+
+// Shared executes f asynchronously, unless a call for the same key is already in progress, in which case it shares its result.
+// Each caller gets its own ErrFuture: cancelling it doesn't cancel the call shared with the other callers.
+func (g *SharedGroup[T]) Shared(key string, f func() (T, error)) ErrFuture[T] {
+	return g.group.Shared(key, f)
+}
+
+// KeyedSharedGroup is a SharedGroup whose calls are identified by any comparable key instead of a string,
+// so the caller doesn't have to build a string representing its key.
+//
+// The zero value is ready to use.
+type KeyedSharedGroup[K comparable, T any] struct {
+	mutex sync.Mutex
+	calls map[K]*state[T]
+}
+
+// Shared executes f asynchronously, unless a call for the same key is already in progress, in which case it shares its result.
+// Each caller gets its own ErrFuture: cancelling it doesn't cancel the call shared with the other callers.
+func (g *KeyedSharedGroup[K, T]) Shared(key K, f func() (T, error)) ErrFuture[T] {
+	g.mutex.Lock()
+	if g.calls == nil {
+		g.calls = make(map[K]*state[T])
+	}
+	s, ok := g.calls[key]
+	if !ok {
+		s = run(context.Background(), func(_ context.Context) (T, error) {
+			defer g.forget(key)
+			return f()
+		})
+		g.calls[key] = s
+	}
+	g.mutex.Unlock()
+	return &errNext[T]{
+		state: s.chain(
+			func(result T) (T, error) {
+				return result, nil
+			},
+			func(err error) (T, error) {
+				var zero T
+				return zero, err
+			},
+		),
+	}
+}
+
+func (g *KeyedSharedGroup[K, T]) forget(key K) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+	delete(g.calls, key)
+}
+
+var defaultSharedGroup = &SharedGroup[interface{}]{}
+
+// Shared deduplicates the concurrent calls sharing the same key, using a SharedGroup common to the whole process.
+// It avoids many go-routines fetching the same resource at the same time. Use your own SharedGroup to get a typed result.
+func Shared(key string, f func() (interface{}, error)) ErrFuture[interface{}] {
+	return defaultSharedGroup.Shared(key, f)
+}
@@ -0,0 +1,78 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedGroup(t *testing.T) {
+	var calls int32
+	group := &SharedGroup[string]{}
+	release := make(chan struct{})
+	fetch := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "resource", nil
+	}
+	futures := make([]ErrFuture[string], 0, 10)
+	for i := 0; i < 10; i++ {
+		futures = append(futures, group.Shared("key", fetch))
+	}
+	// one of the callers gives up, it must not impact the others
+	futures[0].Cancel()
+	close(release)
+	for _, future := range futures[1:] {
+		result, err := future.Await()
+		assert.NoError(t, err)
+		assert.Equal(t, "resource", result)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+
+	// once the call ended, the function is executed again
+	_, err := group.Shared("key", fetch).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestKeyedSharedGroup(t *testing.T) {
+	type key struct {
+		name *string
+	}
+	first, second := "resource", "resource"
+	var calls int32
+	group := &KeyedSharedGroup[key, string]{}
+	release := make(chan struct{})
+	fetch := func() (string, error) {
+		atomic.AddInt32(&calls, 1)
+		<-release
+		return "resource", nil
+	}
+	futures := []ErrFuture[string]{
+		group.Shared(key{name: &first}, fetch),
+		group.Shared(key{name: &first}, fetch),
+		// the pointers are different, so is the key even if the values pointed are equal
+		group.Shared(key{name: &second}, fetch),
+	}
+	close(release)
+	for _, future := range futures {
+		result, err := future.Await()
+		assert.NoError(t, err)
+		assert.Equal(t, "resource", result)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
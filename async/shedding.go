@@ -0,0 +1,78 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// latencyEstimator is an exponentially weighted moving average of the time the jobs wait in the queue of a Pool.
+type latencyEstimator struct {
+	// nanoseconds is accessed atomically.
+	nanoseconds int64
+}
+
+// observe adds a new measure. Each measure weighs 1/8 of the average, like the round-trip time estimation of TCP.
+func (l *latencyEstimator) observe(latency time.Duration) {
+	for {
+		old := atomic.LoadInt64(&l.nanoseconds)
+		updated := old + (int64(latency)-old)/8
+		if atomic.CompareAndSwapInt64(&l.nanoseconds, old, updated) {
+			return
+		}
+	}
+}
+
+func (l *latencyEstimator) get() time.Duration {
+	return time.Duration(atomic.LoadInt64(&l.nanoseconds))
+}
+
+// QueueLatency estimates the time a job submitted now waits before being executed.
+// It is the average time the last jobs waited, or 0 when no job is waiting.
+func (p *Pool) QueueLatency() time.Duration {
+	if p.jobs.len() == 0 {
+		return 0
+	}
+	return p.latency.get()
+}
+
+// SubmitBeforeDeadline submits the job only if it can start before the deadline of the context, according to the QueueLatency.
+// Otherwise, the job is rejected and the future fails immediately with ErrDeadlineUnreachable,
+// so the workers don't waste their time on a job whose result will arrive too late anyway.
+// The deadline of the context is also applied to the context given to the job. A job still in the queue once the deadline is passed is not executed.
+// Like with SubmitWithContext, the wait for room in the queue stops once the context is done.
+func SubmitBeforeDeadline[T any](ctx context.Context, p *Pool, f func(ctx context.Context) (T, error), opts ...SubmitOption) ErrFuture[T] {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return SubmitWithContext(ctx, p, f, opts...)
+	}
+	if time.Until(deadline) < p.QueueLatency() {
+		if hook := getPoolMetricsHook(); hook != nil {
+			hook.Submitted(p.name)
+		}
+		p.rejected(ErrDeadlineUnreachable)
+		return Failed[T](ErrDeadlineUnreachable)
+	}
+	return SubmitWithContext(ctx, p, func(jobCtx context.Context) (T, error) {
+		jobCtx, cancel := context.WithDeadline(jobCtx, deadline)
+		defer cancel()
+		if err := jobCtx.Err(); err != nil {
+			var zero T
+			return zero, err
+		}
+		return f(jobCtx)
+	}, opts...)
+}
@@ -0,0 +1,77 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSubmitBeforeDeadline(t *testing.T) {
+	pool := NewPool(1, 10)
+	defer pool.Shutdown(context.Background())
+	release := make(chan struct{})
+	blocking := pool.Submit(func(_ context.Context) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	// simulate a queue where the jobs are waiting a long time
+	atomic.StoreInt64(&pool.latency.nanoseconds, int64(time.Second))
+	queued := pool.Submit(func(_ context.Context) (interface{}, error) {
+		return nil, nil
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err := SubmitBeforeDeadline(ctx, pool, func(_ context.Context) (int, error) {
+		return 1, nil
+	}).Await()
+	assert.ErrorIs(t, err, ErrDeadlineUnreachable)
+
+	close(release)
+	_, _ = blocking.Await()
+	_, _ = queued.Await()
+	// once the queue is empty, the job is accepted
+	result, err := SubmitBeforeDeadline(ctx, pool, func(_ context.Context) (int, error) {
+		return 1, nil
+	}).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result)
+}
+
+func TestSubmitBeforeDeadline_QueueFull(t *testing.T) {
+	pool := NewPool(1, 1)
+	defer pool.Shutdown(context.Background())
+	release := make(chan struct{})
+	defer close(release)
+	pool.Submit(func(_ context.Context) (interface{}, error) {
+		<-release
+		return nil, nil
+	})
+	pool.Submit(func(_ context.Context) (interface{}, error) {
+		return nil, nil
+	})
+
+	// the queue is full, the wait for room stops at the deadline
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := SubmitBeforeDeadline(ctx, pool, func(_ context.Context) (int, error) {
+		return 1, nil
+	}).Await()
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -17,6 +17,7 @@ import (
 	"context"
 	"os"
 	"os/signal"
+	"time"
 
 	"github.com/sirupsen/logrus"
 )
@@ -48,3 +49,48 @@ func (s *signalListener) Execute(ctx context.Context, cancelFunc context.CancelF
 	}
 	return nil
 }
+
+// NotifyContext is a two-phase equivalent of signal.NotifyContext. It returns two contexts derived from the parent:
+//   - the first one is cancelled on the first signal received, so the application starts stopping gracefully,
+//   - the second one is cancelled once the grace period has expired after the first signal, or on a second signal,
+//     so the work still in progress is abandoned.
+//
+// The stop function cancels both contexts and stops listening to the signals. It must be called once the contexts are not needed anymore.
+// Exiting the process when the grace period expires is up to the caller:
+//
+//	ctx, hardCtx, stop := async.NotifyContext(context.Background(), 30*time.Second, os.Interrupt, syscall.SIGTERM)
+//	defer stop()
+//	go func() {
+//		<-hardCtx.Done()
+//		os.Exit(1)
+//	}()
+func NotifyContext(parent context.Context, gracePeriod time.Duration, signals ...os.Signal) (context.Context, context.Context, context.CancelFunc) {
+	softCtx, softCancel := context.WithCancel(parent)
+	hardCtx, hardCancel := context.WithCancel(parent)
+	sigChannel := make(chan os.Signal, 2)
+	signal.Notify(sigChannel, signals...)
+	go func() {
+		defer signal.Stop(sigChannel)
+		select {
+		case sig := <-sigChannel:
+			logrus.Infof("signal received: %s, stopping gracefully within %s", sig, gracePeriod)
+			softCancel()
+		case <-hardCtx.Done():
+			return
+		}
+		timer := time.NewTimer(gracePeriod)
+		defer timer.Stop()
+		select {
+		case sig := <-sigChannel:
+			logrus.Warningf("second signal received: %s, stopping now", sig)
+		case <-timer.C:
+			logrus.Warningf("grace period of %s expired, stopping now", gracePeriod)
+		case <-hardCtx.Done():
+		}
+		hardCancel()
+	}()
+	return softCtx, hardCtx, func() {
+		softCancel()
+		hardCancel()
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build linux || darwin || freebsd || netbsd || openbsd || dragonfly
+
+package async
+
+import (
+	"context"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNotifyContext_GracePeriod(t *testing.T) {
+	ctx, hardCtx, stop := NotifyContext(context.Background(), 50*time.Millisecond, syscall.SIGUSR1)
+	defer stop()
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR1))
+	<-ctx.Done()
+	assert.NoError(t, hardCtx.Err())
+	select {
+	case <-hardCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the hard context has not been cancelled after the grace period")
+	}
+}
+
+func TestNotifyContext_SecondSignal(t *testing.T) {
+	ctx, hardCtx, stop := NotifyContext(context.Background(), time.Hour, syscall.SIGUSR2)
+	defer stop()
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	<-ctx.Done()
+	assert.NoError(t, syscall.Kill(syscall.Getpid(), syscall.SIGUSR2))
+	select {
+	case <-hardCtx.Done():
+	case <-time.After(time.Second):
+		t.Fatal("the hard context has not been cancelled by the second signal")
+	}
+}
+
+func TestNotifyContext_Stop(t *testing.T) {
+	ctx, hardCtx, stop := NotifyContext(context.Background(), time.Hour, syscall.SIGUSR1)
+	stop()
+	assert.Error(t, ctx.Err())
+	assert.Error(t, hardCtx.Err())
+}
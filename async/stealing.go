@@ -0,0 +1,286 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// localQueue is the queue of a single worker of a stealingQueue.
+type localQueue struct {
+	mutex sync.Mutex
+	jobs  jobHeap
+	seq   uint64
+	// wake receives a token when a job is pushed while the worker is idle.
+	wake chan struct{}
+}
+
+func (l *localQueue) insert(j *job) {
+	l.mutex.Lock()
+	l.seq++
+	j.seq = l.seq
+	heap.Push(&l.jobs, j)
+	l.mutex.Unlock()
+}
+
+// take removes the job with the highest priority, or returns nil if the queue is empty.
+func (l *localQueue) take() *job {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.jobs) == 0 {
+		return nil
+	}
+	return heap.Pop(&l.jobs).(*job)
+}
+
+// replaceOldest is the equivalent of jobQueue.replaceOldest for a single worker queue.
+func (l *localQueue) replaceOldest(j *job) *job {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.jobs) == 0 {
+		return nil
+	}
+	oldest := 0
+	for i := range l.jobs {
+		if l.jobs[i].seq < l.jobs[oldest].seq {
+			oldest = i
+		}
+	}
+	dropped := heap.Remove(&l.jobs, oldest).(*job)
+	l.seq++
+	j.seq = l.seq
+	heap.Push(&l.jobs, j)
+	return dropped
+}
+
+// stealingQueue is the bounded queue of a Pool created with WithWorkStealing.
+// Each worker pops the jobs of its own queue and steals the jobs of the other queues once its own is empty,
+// so the workers and the producers don't all contend on a single lock and a single channel.
+// The shared state is only touched through atomic operations, except when a worker is idle or when a producer waits for room.
+type stealingQueue struct {
+	locals  []*localQueue
+	next    uint32
+	size    int64
+	maxJobs int
+	policy  OverflowPolicy
+	closed  int32
+	// idle holds the workers waiting for a job. idleCount lets the producers check it without the lock.
+	idleMutex sync.Mutex
+	idle      []int
+	idleCount int32
+	// space is closed, then replaced, when a job leaves the queue while producers are waiting for room.
+	spaceMutex sync.Mutex
+	space      chan struct{}
+	waiting    int32
+}
+
+func newStealingQueue(workers int, capacity int, policy OverflowPolicy) *stealingQueue {
+	if capacity < 1 {
+		capacity = 1
+	}
+	q := &stealingQueue{
+		locals:  make([]*localQueue, workers),
+		maxJobs: capacity,
+		policy:  policy,
+		space:   make(chan struct{}),
+	}
+	for i := range q.locals {
+		q.locals[i] = &localQueue{wake: make(chan struct{}, 1)}
+	}
+	return q
+}
+
+func (q *stealingQueue) push(ctx context.Context, j *job) (*job, error) {
+	local := q.locals[atomic.AddUint32(&q.next, 1)%uint32(len(q.locals))]
+	if !q.tryReserve() {
+		switch q.policy {
+		case OverflowReject:
+			return nil, ErrQueueFull
+		case OverflowDropNewest:
+			return j, nil
+		case OverflowDropOldest:
+			for i := range q.locals {
+				if dropped := q.locals[i].replaceOldest(j); dropped != nil {
+					// the new job takes the place of the job dropped, and the worker waiting for it, if any, is already awake.
+					return dropped, nil
+				}
+			}
+		}
+		if err := q.reserve(ctx); err != nil {
+			return nil, err
+		}
+	}
+	local.insert(j)
+	q.wakeOne()
+	return nil, nil
+}
+
+// tryReserve counts the job in the size of the queue if the queue is not full.
+func (q *stealingQueue) tryReserve() bool {
+	for {
+		size := atomic.LoadInt64(&q.size)
+		if size >= int64(q.maxJobs) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&q.size, size, size+1) {
+			return true
+		}
+	}
+}
+
+// reserve waits for room in the queue, then counts the job in its size.
+func (q *stealingQueue) reserve(ctx context.Context) error {
+	for {
+		q.spaceMutex.Lock()
+		space := q.space
+		atomic.AddInt32(&q.waiting, 1)
+		q.spaceMutex.Unlock()
+		// checking again once registered guarantees a job leaving the queue in the meantime is not missed.
+		if q.tryReserve() {
+			atomic.AddInt32(&q.waiting, -1)
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			atomic.AddInt32(&q.waiting, -1)
+			return ctx.Err()
+		case <-space:
+			atomic.AddInt32(&q.waiting, -1)
+		}
+	}
+}
+
+// wakeOne wakes up an idle worker, if any, so it executes the job just pushed.
+func (q *stealingQueue) wakeOne() {
+	if atomic.LoadInt32(&q.idleCount) == 0 {
+		return
+	}
+	q.idleMutex.Lock()
+	if len(q.idle) == 0 {
+		q.idleMutex.Unlock()
+		return
+	}
+	worker := q.idle[len(q.idle)-1]
+	q.idle = q.idle[:len(q.idle)-1]
+	atomic.AddInt32(&q.idleCount, -1)
+	q.idleMutex.Unlock()
+	select {
+	case q.locals[worker].wake <- struct{}{}:
+	default:
+	}
+}
+
+func (q *stealingQueue) pop(worker int, idleTimeout time.Duration) (*job, bool) {
+	for {
+		if j := q.take(worker); j != nil {
+			return j, true
+		}
+		q.idleMutex.Lock()
+		q.idle = append(q.idle, worker)
+		atomic.AddInt32(&q.idleCount, 1)
+		q.idleMutex.Unlock()
+		// checking again once idle guarantees a job pushed in the meantime is not missed: either it is found here, or the producer wakes the worker up.
+		if j := q.take(worker); j != nil {
+			q.active(worker)
+			return j, true
+		}
+		if atomic.LoadInt32(&q.closed) == 1 {
+			q.active(worker)
+			return nil, false
+		}
+		if !q.park(worker, idleTimeout) {
+			return nil, false
+		}
+	}
+}
+
+// park waits for the worker to be woken up. It returns false if the worker waited for idleTimeout without being woken up.
+func (q *stealingQueue) park(worker int, idleTimeout time.Duration) bool {
+	if idleTimeout <= 0 {
+		<-q.locals[worker].wake
+		return true
+	}
+	timer := time.NewTimer(idleTimeout)
+	defer timer.Stop()
+	select {
+	case <-q.locals[worker].wake:
+		return true
+	case <-timer.C:
+		// a producer may have woken up the worker in the meantime, in which case its job must not be missed.
+		return !q.active(worker)
+	}
+}
+
+// take removes a job from the queue of the worker, or steals one from the queue of another worker.
+func (q *stealingQueue) take(worker int) *job {
+	for i := 0; i < len(q.locals); i++ {
+		if j := q.locals[(worker+i)%len(q.locals)].take(); j != nil {
+			q.release()
+			return j
+		}
+	}
+	return nil
+}
+
+// release removes the job taken from the size of the queue and wakes up the producers waiting for room.
+func (q *stealingQueue) release() {
+	atomic.AddInt64(&q.size, -1)
+	if atomic.LoadInt32(&q.waiting) == 0 {
+		return
+	}
+	q.spaceMutex.Lock()
+	close(q.space)
+	q.space = make(chan struct{})
+	q.spaceMutex.Unlock()
+}
+
+// active removes the worker from the idle workers. It returns false if it was not there anymore, because it has been woken up.
+func (q *stealingQueue) active(worker int) bool {
+	q.idleMutex.Lock()
+	defer q.idleMutex.Unlock()
+	for i, idle := range q.idle {
+		if idle == worker {
+			q.idle = append(q.idle[:i], q.idle[i+1:]...)
+			atomic.AddInt32(&q.idleCount, -1)
+			return true
+		}
+	}
+	return false
+}
+
+func (q *stealingQueue) close() {
+	atomic.StoreInt32(&q.closed, 1)
+	q.idleMutex.Lock()
+	defer q.idleMutex.Unlock()
+	for _, worker := range q.idle {
+		select {
+		case q.locals[worker].wake <- struct{}{}:
+		default:
+		}
+	}
+	q.idle = nil
+	atomic.StoreInt32(&q.idleCount, 0)
+}
+
+func (q *stealingQueue) len() int {
+	return int(atomic.LoadInt64(&q.size))
+}
+
+func (q *stealingQueue) capacity() int {
+	return q.maxJobs
+}
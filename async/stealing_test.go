@@ -0,0 +1,127 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPool_WorkStealing(t *testing.T) {
+	pool := NewPool(4, 1000, WithWorkStealing())
+	var executed int32
+	var futures []ErrFuture[int]
+	var mutex sync.Mutex
+	var wg sync.WaitGroup
+	for producer := 0; producer < 4; producer++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 500; i++ {
+				future := Submit(pool, func(_ context.Context) (int, error) {
+					return int(atomic.AddInt32(&executed, 1)), nil
+				})
+				mutex.Lock()
+				futures = append(futures, future)
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	for _, future := range futures {
+		_, err := future.Await()
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, pool.Shutdown(context.Background()))
+	assert.Equal(t, int32(2000), executed)
+}
+
+func TestPool_WorkStealing_Steal(t *testing.T) {
+	pool := NewPool(2, 10, WithWorkStealing())
+	defer pool.Shutdown(context.Background())
+	release := blockPool(t, pool)
+	defer release()
+	// the jobs are spread across both queues, the worker not blocked has to steal the jobs of the other one.
+	var futures []ErrFuture[int]
+	for i := 0; i < 6; i++ {
+		value := i
+		futures = append(futures, Submit(pool, func(_ context.Context) (int, error) { return value, nil }))
+	}
+	for i, future := range futures {
+		result, err := future.Await()
+		assert.NoError(t, err)
+		assert.Equal(t, i, result)
+	}
+}
+
+func TestPool_WorkStealing_OverflowPolicy(t *testing.T) {
+	value := func(v int) func(_ context.Context) (int, error) {
+		return func(_ context.Context) (int, error) { return v, nil }
+	}
+	t.Run("block", func(t *testing.T) {
+		pool := NewPool(1, 1, WithWorkStealing())
+		defer pool.Shutdown(context.Background())
+		release := blockPool(t, pool)
+		first := Submit(pool, value(1))
+		assert.Equal(t, 1, pool.QueueDepth())
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := SubmitWithContext(ctx, pool, value(2)).Await()
+		assert.Equal(t, context.DeadlineExceeded, err)
+		second := make(chan ErrFuture[int])
+		go func() {
+			second <- Submit(pool, value(3))
+		}()
+		release()
+		result, err := first.Await()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result)
+		result, err = (<-second).Await()
+		assert.NoError(t, err)
+		assert.Equal(t, 3, result)
+	})
+	t.Run("reject", func(t *testing.T) {
+		pool := NewPool(1, 1, WithWorkStealing(), WithOverflowPolicy(OverflowReject))
+		defer pool.Shutdown(context.Background())
+		release := blockPool(t, pool)
+		first := Submit(pool, value(1))
+		_, err := Submit(pool, value(2)).Await()
+		assert.Equal(t, ErrQueueFull, err)
+		release()
+		result, err := first.Await()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, result)
+	})
+	t.Run("drop oldest", func(t *testing.T) {
+		pool := NewPool(1, 2, WithWorkStealing(), WithOverflowPolicy(OverflowDropOldest))
+		defer pool.Shutdown(context.Background())
+		release := blockPool(t, pool)
+		first := Submit(pool, value(1))
+		second := Submit(pool, value(2))
+		third := Submit(pool, value(3))
+		_, err := first.Await()
+		assert.Equal(t, ErrJobDropped, err)
+		release()
+		for expected, future := range map[int]ErrFuture[int]{2: second, 3: third} {
+			result, err := future.Await()
+			assert.NoError(t, err)
+			assert.Equal(t, expected, result)
+		}
+	})
+}
@@ -0,0 +1,108 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+)
+
+// Emitter is given to the producer of a StreamFuture to send its intermediate values.
+type Emitter[T any] struct {
+	ctx    context.Context
+	values chan<- T
+}
+
+// Emit sends the value to the consumer of the StreamFuture. It blocks while the buffer of the stream is full.
+// It returns the error of the context if the context is done before the value could be sent, so the producer knows it must stop.
+func (e *Emitter[T]) Emit(value T) error {
+	select {
+	case <-e.ctx.Done():
+		return e.ctx.Err()
+	case e.values <- value:
+		return nil
+	}
+}
+
+// StreamFuture is a future whose asynchronous function emits intermediate values before it ends.
+// It is useful when the caller can process the results as they arrive, like the rows of a long export:
+//
+//	stream := async.AsyncStream(ctx, func(ctx context.Context, emitter *async.Emitter[Row]) error {
+//		for rows.Next() {
+//			if err := emitter.Emit(rows.Row()); err != nil {
+//				return err
+//			}
+//		}
+//		return rows.Err()
+//	}, async.WithBuffer(100))
+//	err := stream.ForEach(render)
+//
+// The values must be consumed, through Values or ForEach, otherwise the producer is blocked once the buffer is full.
+type StreamFuture[T any] struct {
+	values chan T
+	state  *state[struct{}]
+}
+
+// AsyncStream executes the producer in a new go-routine and returns the StreamFuture receiving its values.
+// By default, the values are not buffered, see WithBuffer. WithPool is not supported, the producer always has its own go-routine.
+// The context given to the producer is cancelled when the future is cancelled or when the producer ends.
+func AsyncStream[T any](ctx context.Context, f func(ctx context.Context, emitter *Emitter[T]) error, opts ...AsyncOption) *StreamFuture[T] {
+	c := newAsyncConfig(opts)
+	ctx, cancel := context.WithCancel(ctx)
+	s := &StreamFuture[T]{
+		values: make(chan T, c.buffer),
+		state:  newState[struct{}](cancel),
+	}
+	s.state.name = c.name
+	s.state.labels = c.labels
+	s.state.noRecover = c.noRecover
+	emitter := &Emitter[T]{ctx: ctx, values: s.values}
+	spawn(func() {
+		defer close(s.values)
+		_ = s.state.execute(ctx, KindAsync, c.name, func(ctx context.Context) (struct{}, error) {
+			return struct{}{}, f(ctx, emitter)
+		})
+	})
+	return s
+}
+
+// Values returns the channel receiving the values emitted. It is closed once the producer ended.
+func (s *StreamFuture[T]) Values() <-chan T {
+	return s.values
+}
+
+// ForEach calls f for each value emitted, in order, until the producer ends. Then it returns the error of the producer.
+func (s *StreamFuture[T]) ForEach(f func(value T)) error {
+	for value := range s.values {
+		f(value)
+	}
+	return s.Await()
+}
+
+// Await waits for the producer to end and returns its error. The values not consumed yet are discarded.
+func (s *StreamFuture[T]) Await() error {
+	for range s.values {
+	}
+	_, err := s.state.wait(context.Background())
+	return err
+}
+
+// Cancel cancels the context given to the producer. The future fails with ErrCancelled.
+func (s *StreamFuture[T]) Cancel() {
+	s.state.Cancel()
+}
+
+// IsDone returns true once the producer ended or the future has been cancelled.
+func (s *StreamFuture[T]) IsDone() bool {
+	return s.state.IsDone()
+}
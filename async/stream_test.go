@@ -0,0 +1,54 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAsyncStream(t *testing.T) {
+	failure := errors.New("failure")
+	stream := AsyncStream(context.Background(), func(_ context.Context, emitter *Emitter[int]) error {
+		for i := 0; i < 3; i++ {
+			if err := emitter.Emit(i); err != nil {
+				return err
+			}
+		}
+		return failure
+	}, WithBuffer(1))
+	var values []int
+	err := stream.ForEach(func(value int) {
+		values = append(values, value)
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, []int{0, 1, 2}, values)
+	assert.True(t, stream.IsDone())
+}
+
+func TestAsyncStream_Cancel(t *testing.T) {
+	stream := AsyncStream(context.Background(), func(_ context.Context, emitter *Emitter[int]) error {
+		for {
+			if err := emitter.Emit(0); err != nil {
+				return err
+			}
+		}
+	})
+	<-stream.Values()
+	stream.Cancel()
+	assert.ErrorIs(t, stream.Await(), context.Canceled)
+}
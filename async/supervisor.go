@@ -0,0 +1,171 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/perses/common/retry"
+)
+
+// RestartPolicy defines when a Supervisor restarts its task.
+type RestartPolicy int
+
+const (
+	// RestartOnError restarts the task only when it ended in error or panicked.
+	RestartOnError RestartPolicy = iota
+	// RestartAlways restarts the task whenever it ends, until the context is done.
+	RestartAlways
+)
+
+// SupervisorOption configures a Supervisor.
+type SupervisorOption func(s *Supervisor)
+
+// WithRestartPolicy sets when the task is restarted. By default, it is RestartOnError.
+func WithRestartPolicy(policy RestartPolicy) SupervisorOption {
+	return func(s *Supervisor) {
+		s.policy = policy
+	}
+}
+
+// WithMaxRestarts sets the number of restarts after which the supervisor gives up. By default, or if max <= 0, there is no limit.
+func WithMaxRestarts(max int) SupervisorOption {
+	return func(s *Supervisor) {
+		s.maxRestarts = max
+	}
+}
+
+// WithRestartBackoff sets the delay to wait before each restart. By default, it is exponential from 100ms up to 30s.
+func WithRestartBackoff(backoff retry.Backoff) SupervisorOption {
+	return func(s *Supervisor) {
+		s.backoff = backoff
+	}
+}
+
+// WithSupervisorLogger sets the Logger receiving an EventRestarted for each restart. By default, it uses logrus.
+func WithSupervisorLogger(logger Logger) SupervisorOption {
+	return func(s *Supervisor) {
+		s.logger = logger
+	}
+}
+
+// Supervisor is a Task executing another task and restarting it according to a RestartPolicy,
+// so a long-running task doesn't die silently because of a transient error.
+// If the task supervised is a Task, Initialize and Finalize are called once, not on every restart.
+type Supervisor struct {
+	Task
+	task        SimpleTask
+	policy      RestartPolicy
+	maxRestarts int
+	backoff     retry.Backoff
+	logger      Logger
+	// mutex protects restarts and lastErr, read concurrently by Restarts and LastError.
+	mutex    sync.Mutex
+	restarts int
+	lastErr  error
+}
+
+func NewSupervisor(task SimpleTask, opts ...SupervisorOption) *Supervisor {
+	s := &Supervisor{
+		task:    task,
+		policy:  RestartOnError,
+		backoff: retry.Exponential(100*time.Millisecond, 30*time.Second, 2),
+		logger:  NewLogrusLogger(nil),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+func (s *Supervisor) String() string {
+	return s.task.String()
+}
+
+// Unwrap returns the task supervised.
+func (s *Supervisor) Unwrap() SimpleTask {
+	return s.task
+}
+
+// Restarts returns the number of times the task has been restarted.
+func (s *Supervisor) Restarts() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.restarts
+}
+
+// LastError returns the error of the last execution of the task that failed, nil if it never failed.
+func (s *Supervisor) LastError() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return s.lastErr
+}
+
+func (s *Supervisor) Initialize() error {
+	if t, ok := s.task.(Task); ok {
+		return t.Initialize()
+	}
+	return nil
+}
+
+func (s *Supervisor) Finalize() error {
+	if t, ok := s.task.(Task); ok {
+		return t.Finalize()
+	}
+	return nil
+}
+
+// Execute runs the task and restarts it until the context is done, the policy doesn't require a restart
+// or the maximum number of restarts is reached. It returns the error of the last execution.
+func (s *Supervisor) Execute(ctx context.Context, cancelFunc context.CancelFunc) error {
+	name := s.task.String()
+	for restarts := 0; ; restarts++ {
+		err := protect(func() error {
+			return s.task.Execute(ctx, cancelFunc)
+		})
+		var panicked *ErrPanicked
+		if errors.As(err, &panicked) {
+			s.logger.Log(Event{Type: EventPanicked, Kind: KindTask, Name: name, Err: err, ExecutionID: ExecutionID(ctx)})
+		}
+		if err != nil {
+			s.mutex.Lock()
+			s.lastErr = err
+			s.mutex.Unlock()
+		}
+		if ctx.Err() != nil || (err == nil && s.policy == RestartOnError) {
+			return err
+		}
+		if s.maxRestarts > 0 && restarts >= s.maxRestarts {
+			if err != nil {
+				return fmt.Errorf("'%s' not restarted anymore after %d restarts: %w", name, restarts, err)
+			}
+			return nil
+		}
+		s.mutex.Lock()
+		s.restarts = restarts + 1
+		s.mutex.Unlock()
+		s.logger.Log(Event{Type: EventRestarted, Kind: KindTask, Name: name, Err: err, Attempt: restarts + 1, ExecutionID: ExecutionID(ctx)})
+		timer := time.NewTimer(s.backoff.Next(restarts + 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/perses/common/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+type flakyTask struct {
+	SimpleTask
+	executions int
+	failures   int
+}
+
+func (f *flakyTask) String() string {
+	return "flaky"
+}
+
+func (f *flakyTask) Execute(_ context.Context, _ context.CancelFunc) error {
+	f.executions++
+	if f.executions <= f.failures {
+		if f.executions == 1 {
+			panic("first execution panicked")
+		}
+		return fmt.Errorf("failure %d", f.executions)
+	}
+	return nil
+}
+
+func TestSupervisor_RestartOnError(t *testing.T) {
+	task := &flakyTask{failures: 2}
+	var mutex sync.Mutex
+	var events []EventType
+	s := NewSupervisor(task,
+		WithRestartBackoff(retry.Constant(0)),
+		WithSupervisorLogger(LoggerFunc(func(event Event) {
+			mutex.Lock()
+			defer mutex.Unlock()
+			events = append(events, event.Type)
+		})),
+	)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, s.Execute(ctx, cancel))
+	assert.Equal(t, 3, task.executions)
+	assert.Equal(t, []EventType{EventPanicked, EventRestarted, EventRestarted}, events)
+	assert.Equal(t, 2, s.Restarts())
+	assert.EqualError(t, s.LastError(), "failure 2")
+}
+
+func TestSupervisor_MaxRestarts(t *testing.T) {
+	task := &flakyTask{failures: 10}
+	s := NewSupervisor(task, WithRestartBackoff(retry.Constant(0)), WithMaxRestarts(2), WithSupervisorLogger(LoggerFunc(func(Event) {})))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.EqualError(t, s.Execute(ctx, cancel), "'flaky' not restarted anymore after 2 restarts: failure 3")
+	assert.Equal(t, 3, task.executions)
+}
+
+func TestSupervisor_RestartAlways(t *testing.T) {
+	task := &flakyTask{}
+	s := NewSupervisor(task, WithRestartPolicy(RestartAlways), WithRestartBackoff(retry.Constant(0)), WithMaxRestarts(4), WithSupervisorLogger(LoggerFunc(func(Event) {})))
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, s.Execute(ctx, cancel))
+	assert.Equal(t, 5, task.executions)
+}
@@ -40,3 +40,34 @@ type Task interface {
 	// Finalize is called by the runner when it ends (clean-up, wait children, ...)
 	Finalize() error
 }
+
+// Dependent is implemented by the SimpleTask or the Task that must start after other tasks.
+// The TaskManager of the package async/taskhelper starts the dependencies first and stops them last.
+type Dependent interface {
+	// DependsOn returns the names of the tasks this one depends on, as returned by their method String.
+	DependsOn() []string
+}
+
+// PanicAction is what the TaskManager of the package async/taskhelper does when a task panics.
+type PanicAction int
+
+const (
+	// PanicStopAll stops every task of the manager, like when a task ends in error. It is the default action.
+	PanicStopAll PanicAction = iota
+	// PanicRestart runs the task again. If the task is a Task, Initialize is called again.
+	PanicRestart
+	// PanicContinue marks the task as failed and lets the other tasks run.
+	PanicContinue
+)
+
+// PanicPolicy defines how a task recovers from a panic.
+type PanicPolicy struct {
+	Action PanicAction
+	// MaxPanics is the number of panics tolerated before escalating to PanicStopAll. By default, or if it is not strictly positive, there is no limit.
+	MaxPanics int
+}
+
+// PanicHandler is implemented by the SimpleTask or the Task that must not stop the whole TaskManager when they panic, like a best-effort task.
+type PanicHandler interface {
+	PanicPolicy() PanicPolicy
+}
@@ -0,0 +1,91 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskhelper
+
+import (
+	"fmt"
+
+	"github.com/perses/common/async"
+)
+
+// sortByDependencies orders the helpers according to the dependencies declared by their task (see async.Dependent).
+// It returns the indexes of the helpers grouped by level: a helper only depends on helpers of the previous levels.
+// It also returns the indexes of the dependencies of each helper.
+func sortByDependencies(helpers []Helper) ([][]int, [][]int, error) {
+	indexesByName := make(map[string][]int, len(helpers))
+	for i, helper := range helpers {
+		indexesByName[helper.String()] = append(indexesByName[helper.String()], i)
+	}
+	dependencies := make([][]int, len(helpers))
+	dependents := make([][]int, len(helpers))
+	remaining := make([]int, len(helpers))
+	for i, helper := range helpers {
+		r, ok := helper.(*runner)
+		if !ok {
+			continue
+		}
+		dependent, ok := r.unwrap().(async.Dependent)
+		if !ok {
+			continue
+		}
+		for _, name := range dependent.DependsOn() {
+			indexes, exists := indexesByName[name]
+			if !exists {
+				return nil, nil, fmt.Errorf("task '%s' depends on the unknown task '%s'", helper.String(), name)
+			}
+			for _, index := range indexes {
+				dependencies[i] = append(dependencies[i], index)
+				dependents[index] = append(dependents[index], i)
+				remaining[i]++
+			}
+		}
+	}
+	var levels [][]int
+	var current []int
+	for i := range helpers {
+		if remaining[i] == 0 {
+			current = append(current, i)
+		}
+	}
+	sorted := 0
+	for len(current) > 0 {
+		levels = append(levels, current)
+		sorted += len(current)
+		var next []int
+		for _, index := range current {
+			for _, dependent := range dependents[index] {
+				remaining[dependent]--
+				if remaining[dependent] == 0 {
+					next = append(next, dependent)
+				}
+			}
+		}
+		current = next
+	}
+	if sorted != len(helpers) {
+		return nil, nil, fmt.Errorf("the dependencies of the tasks form a cycle")
+	}
+	return levels, dependencies, nil
+}
+
+// ready returns a channel closed once the task executed by the helper is initialized.
+// The helpers that are not created by this package are considered ready as soon as they are started.
+func ready(helper Helper) <-chan struct{} {
+	if r, ok := helper.(*runner); ok {
+		return r.ready
+	}
+	closed := make(chan struct{})
+	close(closed)
+	return closed
+}
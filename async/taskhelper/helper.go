@@ -13,12 +13,14 @@
 
 // Package taskhelper provides struct and methods to help to synchronize different async.Task together and to simply start the async.Task properly
 // This package should mainly used through the package app and not directly by the developer.
+// If you are not using the package app, the TaskManager is the way to run a set of tasks and to be notified when one of them fails.
 package taskhelper
 
 import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/perses/common/async"
@@ -48,6 +50,7 @@ func New(task interface{}) (Helper, error) {
 		interval:     0,
 		task:         task,
 		isSimpleTask: isSimpleTask,
+		ready:        make(chan struct{}),
 		done:         make(chan struct{}),
 	}, nil
 }
@@ -71,6 +74,7 @@ func NewCron(task interface{}, interval time.Duration) (Helper, error) {
 		interval:     interval,
 		task:         task,
 		isSimpleTask: isSimpleTask,
+		ready:        make(chan struct{}),
 		done:         make(chan struct{}),
 	}, nil
 }
@@ -82,7 +86,11 @@ type runner struct {
 	// task can be a SimpleTask or a Task
 	task         interface{}
 	isSimpleTask bool
-	done         chan struct{}
+	// ready is closed once the task is initialized and about to be executed.
+	ready chan struct{}
+	done  chan struct{}
+	// executionID is the ID of the last execution of the task, see async.ExecutionID.
+	executionID atomic.Value
 }
 
 func (r *runner) Done() <-chan struct{} {
@@ -93,6 +101,29 @@ func (r *runner) String() string {
 	return r.task.(async.SimpleTask).String()
 }
 
+// unwrap returns the task executed by the runner. If it is an async.Supervisor, the task supervised is returned instead.
+func (r *runner) unwrap() interface{} {
+	task := r.task
+	for {
+		wrapper, ok := task.(interface{ Unwrap() async.SimpleTask })
+		if !ok {
+			return task
+		}
+		task = wrapper.Unwrap()
+	}
+}
+
+// renew returns a runner executing the same task, so it can be started again once this one is done.
+func (r *runner) renew() *runner {
+	return &runner{
+		interval:     r.interval,
+		task:         r.task,
+		isSimpleTask: r.isSimpleTask,
+		ready:        make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+}
+
 func (r *runner) Start(ctx context.Context, cancelFunc context.CancelFunc) (err error) {
 	// closing this channel will highlight the caller that the task is done.
 	defer close(r.done)
@@ -122,8 +153,9 @@ func (r *runner) Start(ctx context.Context, cancelFunc context.CancelFunc) (err
 		}
 	}
 
+	close(r.ready)
 	// then run the task
-	if executeErr := r.task.(async.SimpleTask).Execute(childCtx, cancelFunc); executeErr != nil {
+	if executeErr := r.execute(childCtx, cancelFunc); executeErr != nil {
 		err = fmt.Errorf("unable to call the execute method of the task: %w", executeErr)
 		return
 	}
@@ -132,6 +164,31 @@ func (r *runner) Start(ctx context.Context, cancelFunc context.CancelFunc) (err
 	return r.tick(childCtx, cancelFunc)
 }
 
+// execute calls the method Execute of the task with a new execution ID and reports the execution to the async.MetricsHook.
+func (r *runner) execute(ctx context.Context, cancelFunc context.CancelFunc) error {
+	simpleTask := r.task.(async.SimpleTask)
+	id := async.NewExecutionID()
+	r.executionID.Store(id)
+	// a panic is not recovered here, the TaskManager applies its PanicPolicy to it.
+	return async.Execute(ctx, async.KindTask, simpleTask.String(), id, false, func(ctx context.Context) error {
+		return simpleTask.Execute(ctx, cancelFunc)
+	})
+}
+
+// lastExecutionID returns the ID of the last execution of the task, or an empty string if it has not been executed yet.
+func (r *runner) lastExecutionID() string {
+	id, _ := r.executionID.Load().(string)
+	return id
+}
+
+// executionIDOf returns the ID of the last execution of the task run by the helper, if it is known.
+func executionIDOf(helper Helper) string {
+	if r, ok := helper.(*runner); ok {
+		return r.lastExecutionID()
+	}
+	return ""
+}
+
 func (r *runner) tick(ctx context.Context, cancelFunc context.CancelFunc) error {
 	simpleTask := r.task.(async.SimpleTask)
 	if r.interval <= 0 {
@@ -143,7 +200,7 @@ func (r *runner) tick(ctx context.Context, cancelFunc context.CancelFunc) error
 	for {
 		select {
 		case <-ticker.C:
-			if executeErr := simpleTask.Execute(ctx, cancelFunc); executeErr != nil {
+			if executeErr := r.execute(ctx, cancelFunc); executeErr != nil {
 				return fmt.Errorf("unable to call the execute method of the task %s: %w", simpleTask.String(), executeErr)
 			}
 		case <-ctx.Done():
@@ -164,12 +221,16 @@ func Run(ctx context.Context, cancelFunc context.CancelFunc, t Helper) {
 
 // JoinAll is waiting for context to be canceled.
 // A task that is ended and should stop the whole application, must have called the master cancelFunc shared by every TaskRunner which will closed the master context.
-func JoinAll(ctx context.Context, timeout time.Duration, helpers []Helper) {
+// It reports whether every task stopped within the timeout: it returns false if at least one of them didn't, true otherwise.
+func JoinAll(ctx context.Context, timeout time.Duration, helpers []Helper) bool {
 	<-ctx.Done()
-	waitAll(timeout, helpers)
+	return waitAll(timeout, helpers)
 }
 
-func waitAll(timeout time.Duration, helpers []Helper) {
+// waitAll waits for every helper to be done, each of them having at most the given timeout to end.
+// It returns false if at least one helper took too much time to stop.
+func waitAll(timeout time.Duration, helpers []Helper) bool {
+	var timedOut int32
 	waitGroup := &sync.WaitGroup{}
 	// set the number of goroutine to wait
 	waitGroup.Add(len(helpers))
@@ -180,6 +241,7 @@ func waitAll(timeout time.Duration, helpers []Helper) {
 			defer timeoutTicker.Stop()
 			select {
 			case <-timeoutTicker.C:
+				atomic.StoreInt32(&timedOut, 1)
 				logrus.Errorf("'%s' took too much time to stop", r.String())
 			case <-r.Done():
 				logrus.Debugf("'%s' has ended", r.String())
@@ -187,4 +249,5 @@ func waitAll(timeout time.Duration, helpers []Helper) {
 		}(helper, timeout)
 	}
 	waitGroup.Wait()
+	return atomic.LoadInt32(&timedOut) == 0
 }
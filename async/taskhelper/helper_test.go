@@ -87,3 +87,54 @@ func TestJoinAll(t *testing.T) {
 	JoinAll(ctx, 30*time.Second, []Helper{t1, t2})
 	assert.True(t, complexTask.counter >= 2)
 }
+
+// blockingTask ignores the cancellation of the context and only stops once released.
+type blockingTask struct {
+	async.SimpleTask
+	release chan struct{}
+}
+
+func (s *blockingTask) String() string {
+	return "blocking task"
+}
+
+func (s *blockingTask) Execute(_ context.Context, _ context.CancelFunc) error {
+	<-s.release
+	return nil
+}
+
+func TestJoinAll_Timeout(t *testing.T) {
+	task := &blockingTask{release: make(chan struct{})}
+	blocking, err := New(task)
+	assert.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	Run(ctx, cancel, blocking)
+	cancel()
+	assert.False(t, JoinAll(ctx, 50*time.Millisecond, []Helper{blocking}))
+
+	close(task.release)
+	assert.True(t, JoinAll(ctx, time.Second, []Helper{blocking}))
+}
+
+type executionIDTask struct {
+	ids []string
+}
+
+func (t *executionIDTask) String() string {
+	return "execution id task"
+}
+
+func (t *executionIDTask) Execute(ctx context.Context, _ context.CancelFunc) error {
+	t.ids = append(t.ids, async.ExecutionID(ctx))
+	return nil
+}
+
+func TestRunner_ExecutionID(t *testing.T) {
+	task := &executionIDTask{}
+	helper, err := New(task)
+	assert.NoError(t, err)
+	assert.NoError(t, helper.Start(context.Background(), func() {}))
+	assert.Len(t, task.ids, 1)
+	assert.NotEmpty(t, task.ids[0])
+	assert.Equal(t, task.ids[0], executionIDOf(helper))
+}
@@ -0,0 +1,432 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskhelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/perses/common/async"
+)
+
+// TaskManager runs a set of async.SimpleTask or async.Task sharing the same context.
+// When a task ends in error, the error is kept and the context shared by every task is cancelled, so the whole set of tasks stops.
+//
+//	manager := taskhelper.NewTaskManager(30 * time.Second)
+//	if err := manager.Add(myTask); err != nil {
+//		return err
+//	}
+//	if err := manager.Start(ctx); err != nil {
+//		return err
+//	}
+//	// blocks until a task fails or the context is cancelled, then waits for every task to stop
+//	return manager.Wait()
+type TaskManager struct {
+	// timeout is the amount of time given to each task to stop once the context is cancelled.
+	timeout time.Duration
+	mutex   sync.Mutex
+	helpers []Helper
+	// statuses holds the status of each helper, at the same index.
+	statuses []TaskStatus
+	// taskCancels holds the function cancelling the context of each helper only, at the same index.
+	taskCancels []context.CancelFunc
+	// levels holds the indexes of the helpers grouped by level of dependencies, see sortByDependencies.
+	levels [][]int
+	ctx    context.Context
+	cancel context.CancelFunc
+	// stopped is closed once every task is stopped. allStopped is false if some tasks took too much time to stop.
+	stopped    chan struct{}
+	allStopped bool
+	// err is the first error returned by a task.
+	err    error
+	logger async.Logger
+	// watchdogThreshold is the maximum age of the heartbeat of a task, see SetWatchdog. There is no watchdog if it is 0.
+	watchdogThreshold time.Duration
+	watchdogAction    WatchdogAction
+}
+
+// NewTaskManager creates a TaskManager. timeout is the amount of time given to each task to stop once the manager is stopped.
+func NewTaskManager(timeout time.Duration) *TaskManager {
+	return &TaskManager{
+		timeout: timeout,
+		logger:  async.NewLogrusLogger(nil),
+	}
+}
+
+// SetLogger sets the Logger notified when a task starts, stops or panics. By default, it is a logrus Logger.
+func (m *TaskManager) SetLogger(logger async.Logger) *TaskManager {
+	m.logger = logger
+	return m
+}
+
+// Add registers a task executed once. The task can be a SimpleTask or a Task. It returns an error if it's something different.
+func (m *TaskManager) Add(task interface{}) error {
+	helper, err := New(task)
+	if err != nil {
+		return err
+	}
+	return m.AddHelpers(helper)
+}
+
+// AddCron registers a task executed periodically. The task can be a SimpleTask or a Task. It returns an error if it's something different.
+func (m *TaskManager) AddCron(task interface{}, interval time.Duration) error {
+	helper, err := NewCron(task, interval)
+	if err != nil {
+		return err
+	}
+	return m.AddHelpers(helper)
+}
+
+// AddHelpers registers the given helpers. It returns an error if the manager is already started.
+func (m *TaskManager) AddHelpers(helpers ...Helper) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.ctx != nil {
+		return fmt.Errorf("cannot add a task to a manager already started")
+	}
+	m.helpers = append(m.helpers, helpers...)
+	for _, helper := range helpers {
+		m.statuses = append(m.statuses, TaskStatus{Name: helper.String(), State: TaskPending})
+	}
+	return nil
+}
+
+// Start runs every task registered. It doesn't block.
+// A task implementing async.Dependent is started once the tasks it depends on are initialized.
+// When the given context is cancelled, or when a task fails, the tasks are stopped in the reverse order:
+// the context of a task is cancelled once the tasks depending on it are stopped.
+// It returns an error if a task depends on an unknown task or if the dependencies form a cycle.
+func (m *TaskManager) Start(ctx context.Context) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.ctx != nil {
+		return fmt.Errorf("task manager already started")
+	}
+	levels, dependencies, err := sortByDependencies(m.helpers)
+	if err != nil {
+		return err
+	}
+	m.levels = levels
+	m.ctx, m.cancel = context.WithCancel(ctx)
+	m.taskCancels = make([]context.CancelFunc, len(m.helpers))
+	m.stopped = make(chan struct{})
+	for i := range m.helpers {
+		if len(dependencies[i]) == 0 {
+			m.launch(i)
+		} else {
+			go m.launchAfter(i, dependencies[i])
+		}
+	}
+	go m.shutdown()
+	if m.watchdogThreshold > 0 {
+		go m.watch()
+	}
+	return nil
+}
+
+// launchAfter launches the helper at the given index once its dependencies are ready.
+// It is not launched if the manager is stopped before.
+func (m *TaskManager) launchAfter(index int, dependencies []int) {
+	for _, dependency := range dependencies {
+		m.mutex.Lock()
+		helper := m.helpers[dependency]
+		m.mutex.Unlock()
+		select {
+		case <-ready(helper):
+		case <-m.ctx.Done():
+			return
+		}
+	}
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	if m.ctx.Err() == nil {
+		m.launch(index)
+	}
+}
+
+// shutdown waits for the manager to be stopped, then stops the tasks one level of dependencies after the other, starting by the last level.
+func (m *TaskManager) shutdown() {
+	<-m.ctx.Done()
+	allStopped := true
+	for i := len(m.levels) - 1; i >= 0; i-- {
+		var helpers []Helper
+		m.mutex.Lock()
+		for _, index := range m.levels[i] {
+			if m.statuses[index].State == TaskPending {
+				// the task has never been launched
+				continue
+			}
+			m.taskCancels[index]()
+			helpers = append(helpers, m.helpers[index])
+		}
+		m.mutex.Unlock()
+		if !waitAll(m.timeout, helpers) {
+			allStopped = false
+		}
+	}
+	m.mutex.Lock()
+	m.allStopped = allStopped
+	m.mutex.Unlock()
+	close(m.stopped)
+}
+
+// launch runs the helper at the given index with its own context. The mutex must be held.
+func (m *TaskManager) launch(index int) {
+	// the context is detached from the one of the manager, so the tasks are cancelled one level of dependencies after the other when it stops.
+	ctx, cancel := context.WithCancel(async.Detach(m.ctx))
+	m.taskCancels[index] = cancel
+	m.statuses[index].State = TaskRunning
+	m.statuses[index].StartedAt = time.Now()
+	m.statuses[index].StoppedAt = time.Time{}
+	m.statuses[index].Stalled = false
+	helper := m.helpers[index]
+	runLabeled(ctx, helper.String(), func(ctx context.Context) {
+		m.run(ctx, index, helper)
+	})
+}
+
+func (m *TaskManager) run(ctx context.Context, index int, helper Helper) {
+	m.logger.Log(async.Event{Type: async.EventStarted, Kind: async.KindTask, Name: helper.String()})
+	err := m.start(ctx, helper)
+	m.logger.Log(async.Event{Type: async.EventStopped, Kind: async.KindTask, Name: helper.String(), Err: err, ExecutionID: executionIDOf(helper)})
+	m.mutex.Lock()
+	status := &m.statuses[index]
+	status.StoppedAt = time.Now()
+	m.taskCancels[index]()
+	var panicked *async.ErrPanicked
+	if errors.As(err, &panicked) && status.State != TaskPaused {
+		status.Panics++
+		switch m.panicAction(index) {
+		case async.PanicRestart:
+			status.LastError = err
+			m.helpers[index] = helper.(*runner).renew()
+			m.launch(index)
+			m.mutex.Unlock()
+			m.logger.Log(async.Event{Type: async.EventRestarted, Kind: async.KindTask, Name: helper.String(), Err: err, Attempt: status.Panics})
+			return
+		case async.PanicContinue:
+			status.State = TaskFailed
+			status.LastError = err
+			m.mutex.Unlock()
+			return
+		}
+	}
+	if status.State == TaskPaused {
+		// the task has been stopped on purpose, it must not stop the other tasks.
+		if err != nil {
+			status.LastError = err
+			err = nil
+		}
+	} else if err != nil {
+		status.State = TaskFailed
+		status.LastError = err
+		if m.err == nil {
+			m.err = fmt.Errorf("'%s' ended in error: %w", helper.String(), err)
+		}
+	} else {
+		status.State = TaskStopped
+	}
+	m.mutex.Unlock()
+	if err != nil {
+		// propagate the failure to every other task
+		m.cancel()
+	}
+}
+
+// panicAction returns what to do with the helper at the given index that just panicked, according to the async.PanicPolicy of its task.
+// The mutex must be held.
+func (m *TaskManager) panicAction(index int) async.PanicAction {
+	r, ok := m.helpers[index].(*runner)
+	if !ok {
+		return async.PanicStopAll
+	}
+	handler, ok := r.unwrap().(async.PanicHandler)
+	if !ok {
+		return async.PanicStopAll
+	}
+	policy := handler.PanicPolicy()
+	if policy.MaxPanics > 0 && m.statuses[index].Panics > policy.MaxPanics {
+		// the task panics too often, it is not able to recover by itself.
+		return async.PanicStopAll
+	}
+	if policy.Action == async.PanicRestart && m.ctx.Err() != nil {
+		// the manager is stopping, there is no point restarting the task.
+		return async.PanicStopAll
+	}
+	return policy.Action
+}
+
+// start starts the helper and recovers it if it panics, so a task cannot crash the whole process.
+func (m *TaskManager) start(ctx context.Context, helper Helper) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &async.ErrPanicked{Value: r, Stack: debug.Stack()}
+			m.logger.Log(async.Event{Type: async.EventPanicked, Kind: async.KindTask, Name: helper.String(), Err: err, ExecutionID: executionIDOf(helper)})
+		}
+	}()
+	// the task is given the cancel function of the manager, so a critical task is still able to stop every task.
+	return helper.Start(ctx, m.cancel)
+}
+
+// Stop cancels the context shared by the tasks and waits for every task to stop.
+// It returns the first error returned by a task, or an error if a task took too much time to stop.
+func (m *TaskManager) Stop() error {
+	m.mutex.Lock()
+	if m.ctx == nil {
+		m.mutex.Unlock()
+		return fmt.Errorf("task manager not started")
+	}
+	m.mutex.Unlock()
+	m.cancel()
+	return m.Wait()
+}
+
+// Wait blocks until the manager is stopped, either by Stop, by the context given to Start or by a task failing, then waits for every task to stop.
+// It returns the first error returned by a task, or an error if a task took too much time to stop.
+func (m *TaskManager) Wait() error {
+	m.mutex.Lock()
+	if m.ctx == nil {
+		m.mutex.Unlock()
+		return fmt.Errorf("task manager not started")
+	}
+	stopped := m.stopped
+	m.mutex.Unlock()
+	<-stopped
+	if err := m.Err(); err != nil {
+		return err
+	}
+	m.mutex.Lock()
+	allStopped := m.allStopped
+	m.mutex.Unlock()
+	if !allStopped {
+		return fmt.Errorf("some tasks took too much time to stop")
+	}
+	return nil
+}
+
+// Progress returns the progress of every task registered implementing async.ProgressReporter, indexed by the name of the task.
+func (m *TaskManager) Progress() map[string]async.Progress {
+	m.mutex.Lock()
+	helpers := m.helpers
+	m.mutex.Unlock()
+	result := make(map[string]async.Progress)
+	for _, helper := range helpers {
+		r, ok := helper.(*runner)
+		if !ok {
+			continue
+		}
+		if reporter, ok := r.unwrap().(async.ProgressReporter); ok {
+			result[helper.String()] = reporter.Progress()
+		}
+	}
+	return result
+}
+
+// Err returns the first error returned by a task, nil if no task failed.
+func (m *TaskManager) Err() error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	return m.err
+}
+
+// List returns the status of every task registered, in the order they have been registered.
+func (m *TaskManager) List() []TaskStatus {
+	m.mutex.Lock()
+	helpers := m.helpers
+	statuses := make([]TaskStatus, len(m.statuses))
+	copy(statuses, m.statuses)
+	m.mutex.Unlock()
+	for i, helper := range helpers {
+		if r, ok := helper.(*runner); ok {
+			statuses[i].complete(r)
+		}
+	}
+	return statuses
+}
+
+// Pause stops the task with the given name, without stopping the other tasks, and waits for it to end.
+// The task is in the state TaskPaused until Resume is called. The error it ended with, if any, doesn't stop the other tasks.
+// It returns an error if the task is not running or if it took too much time to stop.
+func (m *TaskManager) Pause(name string) error {
+	m.mutex.Lock()
+	index, err := m.indexOf(name)
+	if err != nil {
+		m.mutex.Unlock()
+		return err
+	}
+	if state := m.statuses[index].State; state != TaskRunning {
+		m.mutex.Unlock()
+		return fmt.Errorf("cannot pause the task '%s', it is %s", name, state)
+	}
+	if _, ok := m.helpers[index].(*runner); !ok {
+		m.mutex.Unlock()
+		return fmt.Errorf("cannot pause the task '%s', its helper cannot be restarted", name)
+	}
+	m.statuses[index].State = TaskPaused
+	m.taskCancels[index]()
+	helper := m.helpers[index]
+	m.mutex.Unlock()
+	m.logger.Log(async.Event{Type: async.EventPaused, Kind: async.KindTask, Name: name})
+	if !waitAll(m.timeout, []Helper{helper}) {
+		return fmt.Errorf("the task '%s' took too much time to stop", name)
+	}
+	return nil
+}
+
+// Resume runs again the task with the given name paused by Pause. If the task is a Task, Initialize is called again.
+// It returns an error if the task is not paused, if it is still stopping or if the manager is stopped.
+func (m *TaskManager) Resume(name string) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	index, err := m.indexOf(name)
+	if err != nil {
+		return err
+	}
+	if state := m.statuses[index].State; state != TaskPaused {
+		return fmt.Errorf("cannot resume the task '%s', it is %s", name, state)
+	}
+	if m.ctx.Err() != nil {
+		return fmt.Errorf("cannot resume the task '%s', the task manager is stopped", name)
+	}
+	r, ok := m.helpers[index].(*runner)
+	if !ok {
+		return fmt.Errorf("cannot resume the task '%s', its helper cannot be restarted", name)
+	}
+	select {
+	case <-r.Done():
+	default:
+		return fmt.Errorf("cannot resume the task '%s', it is still stopping", name)
+	}
+	m.helpers[index] = r.renew()
+	m.launch(index)
+	m.logger.Log(async.Event{Type: async.EventResumed, Kind: async.KindTask, Name: name})
+	return nil
+}
+
+// indexOf returns the index of the task with the given name. The mutex must be held.
+func (m *TaskManager) indexOf(name string) (int, error) {
+	if m.ctx == nil {
+		return 0, fmt.Errorf("task manager not started")
+	}
+	for i, helper := range m.helpers {
+		if helper.String() == name {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("task '%s' not found", name)
+}
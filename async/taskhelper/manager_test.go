@@ -0,0 +1,343 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskhelper
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/perses/common/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+type failingTaskImpl struct {
+	async.SimpleTask
+}
+
+func (f *failingTaskImpl) String() string {
+	return "failing task"
+}
+
+func (f *failingTaskImpl) Execute(_ context.Context, _ context.CancelFunc) error {
+	return fmt.Errorf("failure")
+}
+
+type blockingTaskImpl struct {
+	async.SimpleTask
+}
+
+func (b *blockingTaskImpl) String() string {
+	return "blocking task"
+}
+
+func (b *blockingTaskImpl) Execute(ctx context.Context, _ context.CancelFunc) error {
+	<-ctx.Done()
+	return nil
+}
+
+func TestTaskManager_ErrorPropagation(t *testing.T) {
+	manager := NewTaskManager(time.Second)
+	assert.NoError(t, manager.Add(&blockingTaskImpl{}))
+	assert.NoError(t, manager.Add(&failingTaskImpl{}))
+	assert.NoError(t, manager.Start(context.Background()))
+	// the failing task must stop the blocking one
+	err := manager.Wait()
+	assert.ErrorContains(t, err, "failure")
+}
+
+func TestTaskManager_Stop(t *testing.T) {
+	complexTask := &complexTaskImpl{}
+	manager := NewTaskManager(time.Second)
+	assert.NoError(t, manager.Add(&blockingTaskImpl{}))
+	assert.NoError(t, manager.AddCron(complexTask, 10*time.Millisecond))
+	assert.NoError(t, manager.Start(context.Background()))
+	assert.Error(t, manager.Add(&blockingTaskImpl{}))
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, manager.Stop())
+}
+
+type panickingTaskImpl struct {
+	async.SimpleTask
+}
+
+func (p *panickingTaskImpl) String() string {
+	return "panicking task"
+}
+
+func (p *panickingTaskImpl) Execute(_ context.Context, _ context.CancelFunc) error {
+	panic("boom")
+}
+
+func TestTaskManager_Logger(t *testing.T) {
+	var mutex sync.Mutex
+	var events []async.Event
+	manager := NewTaskManager(time.Second).SetLogger(async.LoggerFunc(func(event async.Event) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		events = append(events, event)
+	}))
+	assert.NoError(t, manager.Add(&panickingTaskImpl{}))
+	assert.NoError(t, manager.Start(context.Background()))
+	err := manager.Wait()
+	var panicked *async.ErrPanicked
+	assert.True(t, errors.As(err, &panicked))
+	mutex.Lock()
+	defer mutex.Unlock()
+	assert.Len(t, events, 3)
+	assert.Equal(t, async.EventStarted, events[0].Type)
+	assert.Equal(t, async.EventPanicked, events[1].Type)
+	assert.Equal(t, async.EventStopped, events[2].Type)
+	assert.Equal(t, "panicking task", events[2].Name)
+}
+
+type reindexTaskImpl struct {
+	async.SimpleTask
+	*async.ProgressTracker
+}
+
+func (r *reindexTaskImpl) String() string {
+	return "reindex task"
+}
+
+func (r *reindexTaskImpl) Execute(ctx context.Context, _ context.CancelFunc) error {
+	r.SetStep("reindexing")
+	r.Advance(1)
+	<-ctx.Done()
+	return nil
+}
+
+func TestTaskManager_Progress(t *testing.T) {
+	manager := NewTaskManager(time.Second)
+	assert.NoError(t, manager.Add(&blockingTaskImpl{}))
+	assert.NoError(t, manager.Add(&reindexTaskImpl{ProgressTracker: async.NewProgressTracker(2)}))
+	assert.NoError(t, manager.Start(context.Background()))
+	assert.Eventually(t, func() bool {
+		progress, ok := manager.Progress()["reindex task"]
+		return ok && progress.Step == "reindexing" && progress.Percentage == 50
+	}, time.Second, 10*time.Millisecond)
+	// the blocking task doesn't report its progress
+	assert.Len(t, manager.Progress(), 1)
+	assert.NoError(t, manager.Stop())
+}
+
+type labeledTaskImpl struct {
+	blockingTaskImpl
+}
+
+func (l *labeledTaskImpl) String() string {
+	return "labeled task"
+}
+
+func (l *labeledTaskImpl) Labels() async.Labels {
+	return async.Labels{"component": "indexer"}
+}
+
+func TestTaskManager_List(t *testing.T) {
+	flaky := &flakyTaskImpl{}
+	manager := NewTaskManager(time.Second)
+	assert.NoError(t, manager.Add(&labeledTaskImpl{}))
+	assert.NoError(t, manager.Add(async.NewSupervisor(flaky, async.WithRestartBackoff(retry.Constant(0)), async.WithSupervisorLogger(async.LoggerFunc(func(async.Event) {})))))
+	statuses := manager.List()
+	assert.Len(t, statuses, 2)
+	assert.Equal(t, TaskPending, statuses[0].State)
+	assert.True(t, statuses[0].StartedAt.IsZero())
+
+	assert.NoError(t, manager.Start(context.Background()))
+	assert.Eventually(t, func() bool {
+		statuses = manager.List()
+		return statuses[0].State == TaskRunning && statuses[1].State == TaskStopped
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, "labeled task", statuses[0].Name)
+	assert.Equal(t, async.Labels{"component": "indexer"}, statuses[0].Labels)
+	assert.False(t, statuses[0].StartedAt.IsZero())
+	assert.Equal(t, "flaky task", statuses[1].Name)
+	assert.Equal(t, 2, statuses[1].Restarts)
+	assert.EqualError(t, statuses[1].LastError, "failure 2")
+
+	assert.NoError(t, manager.Stop())
+	statuses = manager.List()
+	assert.Equal(t, TaskStopped, statuses[0].State)
+	assert.False(t, statuses[0].StoppedAt.IsZero())
+}
+
+type flakyTaskImpl struct {
+	async.SimpleTask
+	executions int
+}
+
+func (f *flakyTaskImpl) String() string {
+	return "flaky task"
+}
+
+func (f *flakyTaskImpl) Execute(_ context.Context, _ context.CancelFunc) error {
+	f.executions++
+	if f.executions <= 2 {
+		return fmt.Errorf("failure %d", f.executions)
+	}
+	return nil
+}
+
+type consumerTaskImpl struct {
+	async.SimpleTask
+	executions int32
+}
+
+func (c *consumerTaskImpl) String() string {
+	return "consumer task"
+}
+
+func (c *consumerTaskImpl) Execute(ctx context.Context, _ context.CancelFunc) error {
+	atomic.AddInt32(&c.executions, 1)
+	<-ctx.Done()
+	return nil
+}
+
+func TestTaskManager_PauseResume(t *testing.T) {
+	consumer := &consumerTaskImpl{}
+	manager := NewTaskManager(time.Second)
+	assert.NoError(t, manager.Add(&blockingTaskImpl{}))
+	assert.NoError(t, manager.Add(consumer))
+	assert.Error(t, manager.Pause("consumer task"))
+	assert.NoError(t, manager.Start(context.Background()))
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&consumer.executions) == 1 }, time.Second, 10*time.Millisecond)
+
+	assert.Error(t, manager.Resume("consumer task"))
+	assert.Error(t, manager.Pause("unknown task"))
+	assert.NoError(t, manager.Pause("consumer task"))
+	statuses := manager.List()
+	assert.Equal(t, TaskRunning, statuses[0].State)
+	assert.Equal(t, TaskPaused, statuses[1].State)
+	assert.NoError(t, manager.Err())
+	assert.Error(t, manager.Pause("consumer task"))
+
+	assert.NoError(t, manager.Resume("consumer task"))
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&consumer.executions) == 2 }, time.Second, 10*time.Millisecond)
+	assert.Equal(t, TaskRunning, manager.List()[1].State)
+	assert.NoError(t, manager.Stop())
+}
+
+type dependentTaskImpl struct {
+	async.SimpleTask
+	name         string
+	dependencies []string
+	mutex        *sync.Mutex
+	events       *[]string
+}
+
+func (d *dependentTaskImpl) String() string {
+	return d.name
+}
+
+func (d *dependentTaskImpl) DependsOn() []string {
+	return d.dependencies
+}
+
+func (d *dependentTaskImpl) record(event string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	*d.events = append(*d.events, event)
+}
+
+func (d *dependentTaskImpl) Execute(ctx context.Context, _ context.CancelFunc) error {
+	d.record("start " + d.name)
+	<-ctx.Done()
+	// give a chance to the dependencies to stop too early, if they are not stopped in order.
+	time.Sleep(10 * time.Millisecond)
+	d.record("stop " + d.name)
+	return nil
+}
+
+func TestTaskManager_Dependencies(t *testing.T) {
+	mutex := &sync.Mutex{}
+	var events []string
+	newTask := func(name string, dependencies ...string) *dependentTaskImpl {
+		return &dependentTaskImpl{name: name, dependencies: dependencies, mutex: mutex, events: &events}
+	}
+	manager := NewTaskManager(time.Second)
+	// registered in the wrong order on purpose
+	assert.NoError(t, manager.Add(newTask("http", "cache")))
+	assert.NoError(t, manager.Add(newTask("cache", "db")))
+	assert.NoError(t, manager.Add(newTask("db")))
+	assert.NoError(t, manager.Start(context.Background()))
+	assert.Eventually(t, func() bool {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return len(events) == 3
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, manager.Stop())
+	assert.Equal(t, []string{"start db", "start cache", "start http", "stop http", "stop cache", "stop db"}, events)
+}
+
+func TestTaskManager_DependenciesError(t *testing.T) {
+	noop := func(name string, dependencies ...string) *dependentTaskImpl {
+		return &dependentTaskImpl{name: name, dependencies: dependencies}
+	}
+	manager := NewTaskManager(time.Second)
+	assert.NoError(t, manager.Add(noop("a", "unknown")))
+	assert.EqualError(t, manager.Start(context.Background()), "task 'a' depends on the unknown task 'unknown'")
+
+	manager = NewTaskManager(time.Second)
+	assert.NoError(t, manager.Add(noop("a", "b")))
+	assert.NoError(t, manager.Add(noop("b", "a")))
+	assert.EqualError(t, manager.Start(context.Background()), "the dependencies of the tasks form a cycle")
+}
+
+type bestEffortTaskImpl struct {
+	async.SimpleTask
+	policy async.PanicPolicy
+}
+
+func (b *bestEffortTaskImpl) String() string {
+	return "best-effort task"
+}
+
+func (b *bestEffortTaskImpl) PanicPolicy() async.PanicPolicy {
+	return b.policy
+}
+
+func (b *bestEffortTaskImpl) Execute(_ context.Context, _ context.CancelFunc) error {
+	panic("boom")
+}
+
+func TestTaskManager_PanicContinue(t *testing.T) {
+	manager := NewTaskManager(time.Second)
+	assert.NoError(t, manager.Add(&bestEffortTaskImpl{policy: async.PanicPolicy{Action: async.PanicContinue}}))
+	assert.NoError(t, manager.Add(&blockingTaskImpl{}))
+	assert.NoError(t, manager.Start(context.Background()))
+	assert.Eventually(t, func() bool {
+		return manager.List()[0].State == TaskFailed
+	}, time.Second, 10*time.Millisecond)
+	// the other task is still running
+	assert.Equal(t, TaskRunning, manager.List()[1].State)
+	assert.Equal(t, 1, manager.List()[0].Panics)
+	assert.NoError(t, manager.Stop())
+}
+
+func TestTaskManager_PanicRestartEscalation(t *testing.T) {
+	manager := NewTaskManager(time.Second)
+	assert.NoError(t, manager.Add(&bestEffortTaskImpl{policy: async.PanicPolicy{Action: async.PanicRestart, MaxPanics: 3}}))
+	assert.NoError(t, manager.Add(&blockingTaskImpl{}))
+	assert.NoError(t, manager.Start(context.Background()))
+	// once it panicked more than 3 times, the task stops the whole manager
+	err := manager.Wait()
+	var panicked *async.ErrPanicked
+	assert.True(t, errors.As(err, &panicked))
+	assert.Equal(t, 4, manager.List()[0].Panics)
+}
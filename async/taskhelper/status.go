@@ -0,0 +1,78 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskhelper
+
+import (
+	"time"
+
+	"github.com/perses/common/async"
+)
+
+// TaskState is the state of a task run by a TaskManager.
+type TaskState string
+
+const (
+	// TaskPending is the state of a task not started yet.
+	TaskPending TaskState = "pending"
+	// TaskRunning is the state of a task started and not ended yet.
+	TaskRunning TaskState = "running"
+	// TaskPaused is the state of a task stopped by TaskManager.Pause, until TaskManager.Resume is called.
+	TaskPaused TaskState = "paused"
+	// TaskStopped is the state of a task ended without error.
+	TaskStopped TaskState = "stopped"
+	// TaskFailed is the state of a task ended in error.
+	TaskFailed TaskState = "failed"
+)
+
+// TaskStatus describes a task run by a TaskManager. It is returned by TaskManager.List.
+type TaskStatus struct {
+	// Name is the name of the task, given by its method String.
+	Name string
+	// Labels are the labels of the task, if it implements async.Labeled.
+	Labels async.Labels
+	State  TaskState
+	// StartedAt is the time the task started. It is zero if the task is pending.
+	StartedAt time.Time
+	// StoppedAt is the time the task ended. It is zero if the task is pending or running.
+	StoppedAt time.Time
+	// LastError is the error the task ended with. For a task run by an async.Supervisor,
+	// it is the error of the last execution that failed, even if the task has been restarted since.
+	LastError error
+	// Restarts is the number of times the task has been restarted by its async.Supervisor.
+	Restarts int
+	// Panics is the number of times the task panicked, see async.PanicHandler.
+	Panics int
+	// LastHeartbeat is the time of the last heartbeat of the task, if it implements async.HeartbeatReporter.
+	LastHeartbeat time.Time
+	// Stalled is true when the watchdog found the heartbeat of the running task too old, see TaskManager.SetWatchdog.
+	Stalled bool
+}
+
+// complete fills the status with the information given by the task executed by the runner.
+func (s *TaskStatus) complete(r *runner) {
+	if labeled, ok := r.unwrap().(async.Labeled); ok {
+		s.Labels = labeled.Labels()
+	}
+	if reporter, ok := r.unwrap().(async.HeartbeatReporter); ok {
+		s.LastHeartbeat = reporter.LastHeartbeat()
+	}
+	supervisor, ok := r.task.(*async.Supervisor)
+	if !ok {
+		return
+	}
+	s.Restarts = supervisor.Restarts()
+	if s.LastError == nil {
+		s.LastError = supervisor.LastError()
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskhelper
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"runtime/pprof"
+	"time"
+
+	"github.com/perses/common/async"
+)
+
+// minWatchdogTick is the minimum interval between two checks of the heartbeats, whatever the threshold of the watchdog.
+const minWatchdogTick = time.Millisecond
+
+// taskLabel is the pprof label set on the go-routines of each task, so their stack trace can be found when the task is stalled.
+const taskLabel = "task"
+
+// WatchdogAction is what the TaskManager does with a task whose heartbeat is too old.
+type WatchdogAction int
+
+const (
+	// WatchdogFlag only marks the task as stalled in its TaskStatus and sends an async.EventStalled.
+	WatchdogFlag WatchdogAction = iota
+	// WatchdogRestart does the same as WatchdogFlag, then restarts the task like TaskManager.Pause and TaskManager.Resume would do.
+	WatchdogRestart
+)
+
+// SetWatchdog watches the tasks implementing async.HeartbeatReporter: once the last heartbeat of a running task is older than threshold,
+// an async.EventStalled holding the stack trace of the go-routines of the task is sent, then the action is applied.
+// A task that never sent a heartbeat is considered from the time it started. It must be called before Start.
+// The heartbeats are checked every quarter of the threshold, but not more often than every millisecond.
+func (m *TaskManager) SetWatchdog(threshold time.Duration, action WatchdogAction) *TaskManager {
+	m.watchdogThreshold = threshold
+	m.watchdogAction = action
+	return m
+}
+
+// watch checks the heartbeat of the tasks regularly until the manager is stopped.
+func (m *TaskManager) watch() {
+	tick := m.watchdogThreshold / 4
+	if tick < minWatchdogTick {
+		tick = minWatchdogTick
+	}
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-m.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, name := range m.stalledTasks() {
+				if m.watchdogAction == WatchdogRestart {
+					go m.restart(name)
+				}
+			}
+		}
+	}
+}
+
+// stalledTasks flags the running tasks whose heartbeat is too old and returns their names. A task already flagged is not returned again.
+func (m *TaskManager) stalledTasks() []string {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+	var names []string
+	for i, helper := range m.helpers {
+		status := &m.statuses[i]
+		r, ok := helper.(*runner)
+		if !ok || status.State != TaskRunning || status.Stalled {
+			continue
+		}
+		reporter, ok := r.unwrap().(async.HeartbeatReporter)
+		if !ok {
+			continue
+		}
+		last := reporter.LastHeartbeat()
+		if last.Before(status.StartedAt) {
+			last = status.StartedAt
+		}
+		if time.Since(last) <= m.watchdogThreshold {
+			continue
+		}
+		status.Stalled = true
+		err := &async.ErrStalled{LastHeartbeat: last, Stack: stackOf(helper.String())}
+		m.logger.Log(async.Event{Type: async.EventStalled, Kind: async.KindTask, Name: helper.String(), Err: err, ExecutionID: executionIDOf(helper)})
+		names = append(names, helper.String())
+	}
+	return names
+}
+
+// restart stops the task with the given name and runs it again.
+func (m *TaskManager) restart(name string) {
+	if err := m.Pause(name); err != nil {
+		m.logger.Log(async.Event{Type: async.EventStopped, Kind: async.KindTask, Name: name, Err: fmt.Errorf("unable to restart the stalled task: %w", err)})
+		return
+	}
+	if err := m.Resume(name); err != nil {
+		m.logger.Log(async.Event{Type: async.EventStopped, Kind: async.KindTask, Name: name, Err: fmt.Errorf("unable to restart the stalled task: %w", err)})
+	}
+}
+
+// runLabeled runs f in a go-routine labeled with the name of the task. The go-routines created by f inherit the label.
+func runLabeled(ctx context.Context, name string, f func(ctx context.Context)) {
+	go pprof.Do(ctx, pprof.Labels(taskLabel, name), f)
+}
+
+// stackOf returns the stack traces of the go-routines labeled with the name of the task.
+func stackOf(name string) []byte {
+	var buffer bytes.Buffer
+	if err := pprof.Lookup("goroutine").WriteTo(&buffer, 1); err != nil {
+		return nil
+	}
+	label := []byte(fmt.Sprintf("%q:%q", taskLabel, name))
+	var stack bytes.Buffer
+	// in this format, the go-routines sharing the same stack are grouped, one group after the other separated by an empty line.
+	for _, group := range bytes.Split(buffer.Bytes(), []byte("\n\n")) {
+		if bytes.Contains(group, label) {
+			stack.Write(group)
+			stack.WriteString("\n\n")
+		}
+	}
+	return stack.Bytes()
+}
@@ -0,0 +1,96 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package taskhelper
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/stretchr/testify/assert"
+)
+
+type wedgedTaskImpl struct {
+	async.SimpleTask
+	async.Heartbeater
+	executions int32
+}
+
+func (w *wedgedTaskImpl) String() string {
+	return "wedged task"
+}
+
+func (w *wedgedTaskImpl) Execute(ctx context.Context, _ context.CancelFunc) error {
+	atomic.AddInt32(&w.executions, 1)
+	w.Heartbeat()
+	// the task doesn't send any heartbeat anymore, like a consumer stuck on a message.
+	<-ctx.Done()
+	return nil
+}
+
+func TestTaskManager_Watchdog(t *testing.T) {
+	var mutex sync.Mutex
+	var stalled []*async.ErrStalled
+	logger := async.LoggerFunc(func(event async.Event) {
+		if event.Type != async.EventStalled {
+			return
+		}
+		var err *async.ErrStalled
+		if errors.As(event.Err, &err) {
+			mutex.Lock()
+			stalled = append(stalled, err)
+			mutex.Unlock()
+		}
+	})
+	task := &wedgedTaskImpl{}
+	manager := NewTaskManager(time.Second).SetLogger(logger).SetWatchdog(40*time.Millisecond, WatchdogFlag)
+	assert.NoError(t, manager.Add(task))
+	assert.NoError(t, manager.Start(context.Background()))
+	assert.Eventually(t, func() bool {
+		return manager.List()[0].Stalled
+	}, time.Second, 10*time.Millisecond)
+	mutex.Lock()
+	assert.Len(t, stalled, 1)
+	assert.Contains(t, string(stalled[0].Stack), "wedgedTaskImpl")
+	mutex.Unlock()
+	assert.False(t, manager.List()[0].LastHeartbeat.IsZero())
+	assert.NoError(t, manager.Stop())
+}
+
+func TestTaskManager_WatchdogRestart(t *testing.T) {
+	task := &wedgedTaskImpl{}
+	manager := NewTaskManager(time.Second).SetWatchdog(40*time.Millisecond, WatchdogRestart)
+	assert.NoError(t, manager.Add(task))
+	assert.NoError(t, manager.Start(context.Background()))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&task.executions) >= 2
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, manager.Stop())
+}
+
+func TestTaskManager_WatchdogTinyThreshold(t *testing.T) {
+	task := &wedgedTaskImpl{}
+	// a threshold too small to be split in four must not crash the manager
+	manager := NewTaskManager(time.Second).SetWatchdog(time.Nanosecond, WatchdogFlag)
+	assert.NoError(t, manager.Add(task))
+	assert.NoError(t, manager.Start(context.Background()))
+	assert.Eventually(t, func() bool {
+		return manager.List()[0].Stalled
+	}, time.Second, 10*time.Millisecond)
+	assert.NoError(t, manager.Stop())
+}
@@ -0,0 +1,83 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"time"
+)
+
+// Timeout returns a function calling f with a context cancelled once the timeout expires.
+// If f fails because of the timeout, the error returned is an *ErrTimedOut wrapping the error of f, reported by errors.Is as ErrExecutionTimeout.
+// The function must observe its context: it is not abandoned, Timeout still waits for it to return.
+//
+//	future := async.AsyncErrWithContext(ctx, async.Timeout(5*time.Second, fetch))
+func Timeout[T any](timeout time.Duration, f func(ctx context.Context) (T, error)) func(ctx context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		result, err := f(timeoutCtx)
+		if err != nil && ctx.Err() == nil && timeoutCtx.Err() == context.DeadlineExceeded {
+			return result, &ErrTimedOut{Timeout: timeout, Err: err}
+		}
+		return result, err
+	}
+}
+
+// TimeoutTask is a Task enforcing a timeout on each execution of another task, so the policy is declared once when the task is registered:
+//
+//	manager.AddCron(async.NewTimeoutTask(syncTask, 10*time.Second), time.Minute)
+//
+// If the task supervised is a Task, Initialize and Finalize are not subject to the timeout.
+type TimeoutTask struct {
+	Task
+	task    SimpleTask
+	timeout time.Duration
+}
+
+func NewTimeoutTask(task SimpleTask, timeout time.Duration) *TimeoutTask {
+	return &TimeoutTask{task: task, timeout: timeout}
+}
+
+func (t *TimeoutTask) String() string {
+	return t.task.String()
+}
+
+// Unwrap returns the task executed with a timeout.
+func (t *TimeoutTask) Unwrap() SimpleTask {
+	return t.task
+}
+
+func (t *TimeoutTask) Initialize() error {
+	if task, ok := t.task.(Task); ok {
+		return task.Initialize()
+	}
+	return nil
+}
+
+func (t *TimeoutTask) Finalize() error {
+	if task, ok := t.task.(Task); ok {
+		return task.Finalize()
+	}
+	return nil
+}
+
+// Execute executes the task with a context cancelled once the timeout expires.
+// If the task fails because of the timeout, the error returned wraps ErrExecutionTimeout.
+func (t *TimeoutTask) Execute(ctx context.Context, cancelFunc context.CancelFunc) error {
+	_, err := Timeout(t.timeout, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, t.task.Execute(ctx, cancelFunc)
+	})(ctx)
+	return err
+}
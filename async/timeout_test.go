@@ -0,0 +1,81 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func waitForContext(ctx context.Context) (int, error) {
+	<-ctx.Done()
+	return 0, ctx.Err()
+}
+
+func TestTimeout(t *testing.T) {
+	_, err := Timeout(10*time.Millisecond, waitForContext)(context.Background())
+	assert.ErrorIs(t, err, ErrExecutionTimeout)
+	// the error of the function is kept
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	var timedOut *ErrTimedOut
+	assert.ErrorAs(t, err, &timedOut)
+	assert.Equal(t, 10*time.Millisecond, timedOut.Timeout)
+
+	// the cancellation of the parent is not a timeout
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = Timeout(time.Hour, waitForContext)(ctx)
+	assert.Equal(t, context.Canceled, err)
+
+	result, err := Timeout(time.Hour, func(_ context.Context) (int, error) {
+		return 1, nil
+	})(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result)
+}
+
+type slowTaskImpl struct {
+	SimpleTask
+}
+
+func (s *slowTaskImpl) String() string {
+	return "slow task"
+}
+
+func (s *slowTaskImpl) Execute(ctx context.Context, _ context.CancelFunc) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestTimeoutTask(t *testing.T) {
+	task := NewTimeoutTask(&slowTaskImpl{}, 10*time.Millisecond)
+	assert.Equal(t, "slow task", task.String())
+	err := task.Execute(context.Background(), func() {})
+	assert.True(t, errors.Is(err, ErrExecutionTimeout))
+}
+
+func TestPool_WithJobTimeout(t *testing.T) {
+	var letters []DeadLetter
+	pool := NewPool(1, 1, WithJobTimeout(10*time.Millisecond), WithDeadLetter(func(letter DeadLetter) {
+		letters = append(letters, letter)
+	}))
+	_, err := Submit(pool, waitForContext).Await()
+	assert.ErrorIs(t, err, ErrExecutionTimeout)
+	assert.NoError(t, pool.Shutdown(context.Background()))
+	assert.Len(t, letters, 1)
+}
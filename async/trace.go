@@ -0,0 +1,48 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/perses/common/async"
+
+// AsyncTraced executes the asynchronous function inside a child span of the span held by the context.
+// The span is started in the go-routine executing the function and ended when the function returns.
+// As the context given to the function is derived from ctx, the trace and the baggage are propagated to it.
+// The span is recording the error returned by the function, or the panic.
+// The tracer used comes from the global TracerProvider of OpenTelemetry (see otel.SetTracerProvider).
+func AsyncTraced[T any](ctx context.Context, name string, f func(ctx context.Context) (T, error), opts ...trace.SpanStartOption) ErrFuture[T] {
+	tracer := otel.Tracer(tracerName)
+	return AsyncErrWithContext(ctx, func(ctx context.Context) (T, error) {
+		spanCtx, span := tracer.Start(ctx, name, opts...)
+		defer span.End()
+		var result T
+		err := protect(func() (err error) {
+			result, err = f(spanCtx)
+			return err
+		})
+		if err != nil {
+			span.RecordError(err, trace.WithTimestamp(time.Now()))
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return result, err
+	})
+}
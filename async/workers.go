@@ -0,0 +1,111 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"context"
+	"runtime"
+	"runtime/pprof"
+	"sync"
+)
+
+// sharedWorkers is a set of long-lived go-routines executing the asynchronous functions started by Go and its variants.
+type sharedWorkers struct {
+	// jobs is unbuffered: a job is sent only to a worker idle and waiting for it.
+	jobs chan func()
+	stop chan struct{}
+}
+
+func newSharedWorkers(n int) *sharedWorkers {
+	w := &sharedWorkers{
+		jobs: make(chan func()),
+		stop: make(chan struct{}),
+	}
+	for i := 0; i < n; i++ {
+		go w.work()
+	}
+	return w
+}
+
+func (w *sharedWorkers) work() {
+	for {
+		select {
+		case <-w.stop:
+			return
+		case job := <-w.jobs:
+			job()
+			// the job may have left its profiling labels on the worker.
+			pprof.SetGoroutineLabels(context.Background())
+		}
+	}
+}
+
+// dispatch gives the job to an idle worker. If every worker is busy, the job is executed by a new go-routine instead,
+// so a job waiting for another one cannot block the workers forever.
+func (w *sharedWorkers) dispatch(job func()) {
+	select {
+	case w.jobs <- job:
+	default:
+		go job()
+	}
+}
+
+var (
+	sharedWorkersMutex sync.RWMutex
+	workers            *sharedWorkers
+)
+
+// EnableSharedWorkers makes Go and its variants execute the asynchronous functions on n long-lived go-routines
+// instead of spawning a new go-routine for each of them. If n is not strictly positive, the number of CPUs is used instead.
+// It is worth it when hundreds of thousands of tiny futures are created per second: the cost of creating a go-routine
+// and growing its stack is then paid only once per worker.
+// When every worker is busy, a new go-routine is still spawned, so a future awaiting another one never deadlocks.
+// It should be called once when the application starts. Calling it again replaces the workers, the previous ones are stopped.
+func EnableSharedWorkers(n int) {
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+	w := newSharedWorkers(n)
+	sharedWorkersMutex.Lock()
+	previous := workers
+	workers = w
+	sharedWorkersMutex.Unlock()
+	if previous != nil {
+		close(previous.stop)
+	}
+}
+
+// DisableSharedWorkers stops the workers started by EnableSharedWorkers. A worker busy stops once its current function ends.
+// Go and its variants spawn a new go-routine for each asynchronous function again.
+func DisableSharedWorkers() {
+	sharedWorkersMutex.Lock()
+	previous := workers
+	workers = nil
+	sharedWorkersMutex.Unlock()
+	if previous != nil {
+		close(previous.stop)
+	}
+}
+
+// spawn executes the job, on a shared worker if EnableSharedWorkers has been called, otherwise on a new go-routine.
+func spawn(job func()) {
+	sharedWorkersMutex.RLock()
+	w := workers
+	sharedWorkersMutex.RUnlock()
+	if w == nil {
+		go job()
+		return
+	}
+	w.dispatch(job)
+}
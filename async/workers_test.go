@@ -0,0 +1,54 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package async
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSharedWorkers(t *testing.T) {
+	EnableSharedWorkers(1)
+	defer DisableSharedWorkers()
+	futures := make([]TypedFuture[int], 100)
+	for i := range futures {
+		value := i
+		futures[i] = Go(func() int { return value * 2 })
+	}
+	for i, future := range futures {
+		assert.Equal(t, i*2, future.Await())
+	}
+}
+
+func TestSharedWorkers_Nested(t *testing.T) {
+	EnableSharedWorkers(1)
+	defer DisableSharedWorkers()
+	// the single worker is busy awaiting the inner future, so the inner one must be executed elsewhere.
+	outer := Go(func() int {
+		return Go(func() int { return 1 }).Await() + 1
+	})
+	assert.Equal(t, 2, outer.AwaitWithTimeout(time.Second))
+}
+
+func TestSharedWorkers_Panic(t *testing.T) {
+	EnableSharedWorkers(1)
+	defer DisableSharedWorkers()
+	_, err := AsyncErr(func() (int, error) { panic("boom") }).Await()
+	var panicked *ErrPanicked
+	assert.ErrorAs(t, err, &panicked)
+	// the worker survived the panic
+	assert.Equal(t, 1, Go(func() int { return 1 }).Await())
+}
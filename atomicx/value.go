@@ -0,0 +1,113 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package atomicx provides typed wrappers around the package sync/atomic, so the values don't need to be cast.
+package atomicx
+
+import (
+	"sync/atomic"
+)
+
+// box holds the value, so atomic.Value always stores the same concrete type, even when T is an interface.
+type box[T any] struct {
+	value T
+}
+
+// Value holds a value of the type T that can be loaded and stored atomically. The zero value holds the zero value of T.
+// A Value must not be copied after first use.
+type Value[T any] struct {
+	v atomic.Value
+}
+
+// NewValue creates a Value holding the value given.
+func NewValue[T any](value T) *Value[T] {
+	v := &Value[T]{}
+	v.Store(value)
+	return v
+}
+
+func (v *Value[T]) load() *box[T] {
+	b, _ := v.v.Load().(*box[T])
+	return b
+}
+
+// Load returns the value held.
+func (v *Value[T]) Load() T {
+	if b := v.load(); b != nil {
+		return b.value
+	}
+	var zero T
+	return zero
+}
+
+// Store replaces the value held.
+func (v *Value[T]) Store(value T) {
+	v.v.Store(&box[T]{value: value})
+}
+
+// Swap replaces the value held and returns the previous one.
+func (v *Value[T]) Swap(value T) T {
+	previous, _ := v.v.Swap(&box[T]{value: value}).(*box[T])
+	if previous == nil {
+		var zero T
+		return zero
+	}
+	return previous.value
+}
+
+// Update replaces the value held by the result of f applied to it, and returns the new value.
+// If another go-routine changed the value in the meantime, f is called again with the new value, until the update succeeds.
+// f can therefore be called several times and must not have side effects.
+func (v *Value[T]) Update(f func(current T) T) T {
+	for {
+		current := v.load()
+		var value T
+		if current != nil {
+			value = current.value
+		}
+		updated := &box[T]{value: f(value)}
+		if current == nil {
+			if v.v.CompareAndSwap(nil, updated) {
+				return updated.value
+			}
+			continue
+		}
+		if v.v.CompareAndSwap(current, updated) {
+			return updated.value
+		}
+	}
+}
+
+// CompareAndSwap replaces the value held by new only if it is equal to old. It returns true if the value has been replaced.
+func CompareAndSwap[T comparable](v *Value[T], old T, new T) bool {
+	for {
+		current := v.load()
+		var value T
+		if current != nil {
+			value = current.value
+		}
+		if value != old {
+			return false
+		}
+		updated := &box[T]{value: new}
+		if current == nil {
+			if v.v.CompareAndSwap(nil, updated) {
+				return true
+			}
+			continue
+		}
+		if v.v.CompareAndSwap(current, updated) {
+			return true
+		}
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package atomicx
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValue(t *testing.T) {
+	v := &Value[error]{}
+	assert.Nil(t, v.Load())
+	failure := fmt.Errorf("failure")
+	assert.Nil(t, v.Swap(failure))
+	assert.Equal(t, failure, v.Load())
+	// a nil interface can be stored, unlike with atomic.Value
+	v.Store(nil)
+	assert.Nil(t, v.Load())
+
+	s := NewValue("a")
+	assert.False(t, CompareAndSwap(s, "b", "c"))
+	assert.True(t, CompareAndSwap(s, "a", "c"))
+	assert.Equal(t, "c", s.Load())
+	assert.True(t, CompareAndSwap(&Value[int]{}, 0, 1))
+}
+
+func TestValue_Update(t *testing.T) {
+	v := &Value[[]int]{}
+	wg := &sync.WaitGroup{}
+	wg.Add(100)
+	for i := 0; i < 100; i++ {
+		go func(i int) {
+			defer wg.Done()
+			v.Update(func(current []int) []int {
+				updated := make([]int, len(current), len(current)+1)
+				copy(updated, current)
+				return append(updated, i)
+			})
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, v.Load(), 100)
+}
@@ -0,0 +1,240 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package breaker provides a circuit breaker protecting a dependency that is failing.
+//
+// The breaker starts closed and lets every call go through. Once the number of consecutive failures reaches a threshold, it opens
+// and rejects every call with ErrOpen. After a cool-down, it becomes half-open and lets a limited number of trial calls go through:
+// if they succeed, the breaker closes again, otherwise it opens again for another cool-down.
+//
+//	b := breaker.New(breaker.WithFailureThreshold(5), breaker.WithCoolDown(30*time.Second))
+//	user, err := breaker.Execute(ctx, b, func(ctx context.Context) (*User, error) {
+//		return client.GetUser(ctx, id)
+//	})
+package breaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned when a call is rejected because the breaker is open.
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State is the state of a Breaker.
+type State int
+
+const (
+	// Closed lets every call go through.
+	Closed State = iota
+	// Open rejects every call.
+	Open
+	// HalfOpen lets a limited number of trial calls go through to decide if the breaker must be closed again.
+	HalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case Closed:
+		return "closed"
+	case Open:
+		return "open"
+	case HalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// Option configures a Breaker.
+type Option func(b *Breaker)
+
+// WithFailureThreshold sets the number of consecutive failures opening the breaker. By default, it is 5.
+func WithFailureThreshold(threshold int) Option {
+	return func(b *Breaker) {
+		if threshold > 0 {
+			b.failureThreshold = threshold
+		}
+	}
+}
+
+// WithSuccessThreshold sets the number of successful trial calls closing a half-open breaker. By default, it is 1.
+func WithSuccessThreshold(threshold int) Option {
+	return func(b *Breaker) {
+		if threshold > 0 {
+			b.successThreshold = threshold
+		}
+	}
+}
+
+// WithCoolDown sets the time the breaker stays open before becoming half-open. By default, it is 30 seconds.
+func WithCoolDown(coolDown time.Duration) Option {
+	return func(b *Breaker) {
+		if coolDown > 0 {
+			b.coolDown = coolDown
+		}
+	}
+}
+
+// WithHalfOpenMaxCalls sets the maximum number of trial calls running at the same time while the breaker is half-open. By default, it is 1.
+func WithHalfOpenMaxCalls(maxCalls int) Option {
+	return func(b *Breaker) {
+		if maxCalls > 0 {
+			b.halfOpenMaxCalls = maxCalls
+		}
+	}
+}
+
+// WithIsFailure sets the predicate deciding if an error counts as a failure.
+// By default, every error counts except context.Canceled, since a call cancelled by the caller says nothing about the dependency.
+func WithIsFailure(isFailure func(err error) bool) Option {
+	return func(b *Breaker) {
+		b.isFailure = isFailure
+	}
+}
+
+// Breaker is a circuit breaker. It is safe for concurrent use.
+type Breaker struct {
+	failureThreshold int
+	successThreshold int
+	halfOpenMaxCalls int
+	coolDown         time.Duration
+	isFailure        func(err error) bool
+
+	mutex     sync.Mutex
+	state     State
+	failures  int
+	successes int
+	// inFlight is the number of trial calls in progress while half-open.
+	inFlight int
+	openedAt time.Time
+	// generation changes every time the state changes. It is used to ignore the result of a call started in a previous state.
+	generation uint64
+}
+
+func New(opts ...Option) *Breaker {
+	b := &Breaker{
+		failureThreshold: 5,
+		successThreshold: 1,
+		halfOpenMaxCalls: 1,
+		coolDown:         30 * time.Second,
+		isFailure: func(err error) bool {
+			return !errors.Is(err, context.Canceled)
+		},
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// State returns the current state of the breaker.
+func (b *Breaker) State() State {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.refresh(time.Now())
+	return b.state
+}
+
+// refresh moves an open breaker to half-open once the cool-down is elapsed.
+func (b *Breaker) refresh(now time.Time) {
+	if b.state == Open && now.Sub(b.openedAt) >= b.coolDown {
+		b.setState(HalfOpen, now)
+	}
+}
+
+func (b *Breaker) setState(state State, now time.Time) {
+	b.state = state
+	b.failures = 0
+	b.successes = 0
+	b.inFlight = 0
+	b.generation++
+	if state == Open {
+		b.openedAt = now
+	}
+}
+
+func (b *Breaker) allow() (uint64, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.refresh(time.Now())
+	switch b.state {
+	case Open:
+		return 0, ErrOpen
+	case HalfOpen:
+		if b.inFlight >= b.halfOpenMaxCalls {
+			return 0, ErrOpen
+		}
+		b.inFlight++
+	}
+	return b.generation, nil
+}
+
+func (b *Breaker) record(generation uint64, failed bool) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if generation != b.generation {
+		// the state changed since the call started, its result is not relevant anymore.
+		return
+	}
+	now := time.Now()
+	switch b.state {
+	case Closed:
+		if !failed {
+			b.failures = 0
+			return
+		}
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.setState(Open, now)
+		}
+	case HalfOpen:
+		b.inFlight--
+		if failed {
+			b.setState(Open, now)
+			return
+		}
+		b.successes++
+		if b.successes >= b.successThreshold {
+			b.setState(Closed, now)
+		}
+	}
+}
+
+// Execute calls f if the breaker allows it and records its result. If the breaker is open, f is not called and ErrOpen is returned.
+func Execute[T any](ctx context.Context, b *Breaker, f func(ctx context.Context) (T, error)) (T, error) {
+	generation, err := b.allow()
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer func() {
+		// a panic is a failure, the call must be recorded so a trial call doesn't stay in flight forever.
+		if r := recover(); r != nil {
+			b.record(generation, true)
+			panic(r)
+		}
+	}()
+	result, err := f(ctx)
+	b.record(generation, err != nil && b.isFailure(err))
+	return result, err
+}
+
+// Wrap returns a function calling f through the breaker. It can be given directly to async.AsyncErrWithContext.
+func Wrap[T any](b *Breaker, f func(ctx context.Context) (T, error)) func(ctx context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		return Execute(ctx, b, f)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package breaker
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func call(b *Breaker, err error) error {
+	_, callErr := Execute(context.Background(), b, func(_ context.Context) (struct{}, error) {
+		return struct{}{}, err
+	})
+	return callErr
+}
+
+func TestBreaker(t *testing.T) {
+	failure := fmt.Errorf("failure")
+	b := New(WithFailureThreshold(2), WithCoolDown(20*time.Millisecond))
+	assert.Equal(t, failure, call(b, failure))
+	assert.Equal(t, Closed, b.State())
+	assert.Equal(t, failure, call(b, failure))
+	assert.Equal(t, Open, b.State())
+	assert.Equal(t, ErrOpen, call(b, nil))
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, HalfOpen, b.State())
+	// a failure of the trial call opens the breaker again
+	assert.Equal(t, failure, call(b, failure))
+	assert.Equal(t, Open, b.State())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, call(b, nil))
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreaker_SuccessResetsFailures(t *testing.T) {
+	failure := fmt.Errorf("failure")
+	b := New(WithFailureThreshold(2))
+	assert.Equal(t, failure, call(b, failure))
+	assert.NoError(t, call(b, nil))
+	assert.Equal(t, failure, call(b, failure))
+	assert.Equal(t, Closed, b.State())
+}
+
+func TestBreaker_PanickingTrial(t *testing.T) {
+	failure := fmt.Errorf("failure")
+	b := New(WithFailureThreshold(1), WithCoolDown(20*time.Millisecond))
+	assert.Equal(t, failure, call(b, failure))
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, HalfOpen, b.State())
+	assert.Panics(t, func() {
+		_, _ = Execute(context.Background(), b, func(_ context.Context) (struct{}, error) {
+			panic("boom")
+		})
+	})
+	// the panic is counted as a failure of the trial call
+	assert.Equal(t, Open, b.State())
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, call(b, nil))
+	assert.Equal(t, Closed, b.State())
+}
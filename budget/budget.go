@@ -0,0 +1,96 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package budget splits the deadline of a context across sequential phases, so each phase gets a fair share of the time left.
+//
+//	b, err := budget.Split(ctx, 0.6, 0.3, 0.1)
+//	if err != nil {
+//		return err
+//	}
+//	fetchCtx, cancel := b.Next()
+//	data, err := fetch(fetchCtx)
+//	cancel()
+//	...
+//	computeCtx, cancel := b.Next()
+//	result, err := compute(computeCtx, data)
+//	cancel()
+//
+// The share of a phase is computed when the phase starts, from the time left and the weights of the remaining phases.
+// It means the time not used by a phase is given to the next ones: with 10s and the weights 0.6, 0.3 and 0.1, the first phase gets 6s,
+// and if it ends after 2s, the second one gets 0.3/(0.3+0.1) of the 8s left, so 6s.
+package budget
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// Budget holds the phases sharing the deadline of a context.
+type Budget struct {
+	ctx     context.Context
+	weights []float64
+	mutex   sync.Mutex
+	// next is the index of the next phase.
+	next int
+}
+
+// Split creates a Budget sharing the deadline of the context across the phases of the given weights.
+// The weights are relative to each other, they don't have to sum to 1. It returns an error if no weight is given or if a weight is not strictly positive.
+func Split(ctx context.Context, weights ...float64) (*Budget, error) {
+	if len(weights) == 0 {
+		return nil, fmt.Errorf("at least one phase is required")
+	}
+	for i, weight := range weights {
+		if weight <= 0 {
+			return nil, fmt.Errorf("the weight of the phase %d must be strictly positive, found %g", i, weight)
+		}
+	}
+	return &Budget{ctx: ctx, weights: weights}, nil
+}
+
+// Next returns the context of the next phase, whose deadline is its share of the time left.
+// If the parent context has no deadline, the context returned has no deadline either.
+// Once every phase has been started, the context returned has the deadline of the parent context.
+// As with context.WithDeadline, the cancel function must be called once the phase ends.
+func (b *Budget) Next() (context.Context, context.CancelFunc) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	deadline, ok := b.ctx.Deadline()
+	if !ok || b.next >= len(b.weights) {
+		b.next++
+		return context.WithCancel(b.ctx)
+	}
+	var remainingWeights float64
+	for _, weight := range b.weights[b.next:] {
+		remainingWeights += weight
+	}
+	share := b.weights[b.next] / remainingWeights
+	b.next++
+	now := time.Now()
+	left := deadline.Sub(now)
+	if left <= 0 {
+		return context.WithCancel(b.ctx)
+	}
+	return context.WithDeadline(b.ctx, now.Add(time.Duration(float64(left)*share)))
+}
+
+// Remaining returns the time left before the deadline of the parent context. It returns false if the parent context has no deadline.
+func (b *Budget) Remaining() (time.Duration, bool) {
+	deadline, ok := b.ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline), true
+}
@@ -0,0 +1,72 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func assertDeadlineIn(t *testing.T, ctx context.Context, expected time.Duration) {
+	deadline, ok := ctx.Deadline()
+	assert.True(t, ok)
+	assert.InDelta(t, float64(expected), float64(time.Until(deadline)), float64(100*time.Millisecond))
+}
+
+func TestBudget(t *testing.T) {
+	parent, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	b, err := Split(parent, 0.6, 0.3, 0.1)
+	assert.NoError(t, err)
+
+	fetchCtx, cancelFetch := b.Next()
+	assertDeadlineIn(t, fetchCtx, 6*time.Second)
+	cancelFetch()
+	// the time unused by the first phase is given to the next ones
+	computeCtx, cancelCompute := b.Next()
+	assertDeadlineIn(t, computeCtx, 7500*time.Millisecond)
+	cancelCompute()
+	renderCtx, cancelRender := b.Next()
+	assertDeadlineIn(t, renderCtx, 10*time.Second)
+	cancelRender()
+	// once every phase is started, the deadline is the one of the parent
+	extraCtx, cancelExtra := b.Next()
+	assertDeadlineIn(t, extraCtx, 10*time.Second)
+	cancelExtra()
+
+	remaining, ok := b.Remaining()
+	assert.True(t, ok)
+	assert.InDelta(t, float64(10*time.Second), float64(remaining), float64(100*time.Millisecond))
+}
+
+func TestBudget_NoDeadline(t *testing.T) {
+	b, err := Split(context.Background(), 1, 1)
+	assert.NoError(t, err)
+	ctx, cancel := b.Next()
+	defer cancel()
+	_, ok := ctx.Deadline()
+	assert.False(t, ok)
+	_, ok = b.Remaining()
+	assert.False(t, ok)
+}
+
+func TestSplit_Error(t *testing.T) {
+	_, err := Split(context.Background())
+	assert.Error(t, err)
+	_, err = Split(context.Background(), 0.5, 0)
+	assert.Error(t, err)
+}
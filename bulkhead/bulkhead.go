@@ -0,0 +1,156 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package bulkhead provides a way to isolate the calls to the different dependencies of an application,
+// so a slow dependency cannot use every go-routine available and slow down the calls to the others.
+//
+// A Bulkhead caps the number of calls running at the same time. Once the cap is reached, the new calls are rejected with ErrFull,
+// or wait for a call to end if the bulkhead allows some callers to wait:
+//
+//	registry := bulkhead.NewRegistry(10, bulkhead.WithMaxWaiting(20))
+//	user, err := bulkhead.Execute(ctx, registry.Get("users-api"), func(ctx context.Context) (*User, error) {
+//		return client.GetUser(ctx, id)
+//	})
+package bulkhead
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrFull is returned when a call is rejected because the bulkhead has no room for it.
+var ErrFull = errors.New("bulkhead is full")
+
+// Option configures a Bulkhead.
+type Option func(b *Bulkhead)
+
+// WithMaxWaiting sets the number of calls allowed to wait for a running call to end once the bulkhead is saturated.
+// The calls beyond are rejected with ErrFull. By default, it is 0: the calls are rejected as soon as the bulkhead is saturated.
+func WithMaxWaiting(maxWaiting int) Option {
+	return func(b *Bulkhead) {
+		if maxWaiting > 0 {
+			b.maxWaiting = maxWaiting
+		}
+	}
+}
+
+// Bulkhead caps the number of calls running at the same time. It is safe for concurrent use.
+type Bulkhead struct {
+	// slots holds a token for each call running.
+	slots      chan struct{}
+	maxWaiting int
+	mutex      sync.Mutex
+	waiting    int
+}
+
+// New creates a Bulkhead letting at most maxConcurrent calls run at the same time. maxConcurrent is at least 1.
+func New(maxConcurrent int, opts ...Option) *Bulkhead {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	b := &Bulkhead{slots: make(chan struct{}, maxConcurrent)}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Acquire reserves a slot for a call. The function returned must be called once the call ended, to free the slot.
+// If the bulkhead is saturated, it waits for a slot when it is allowed to, otherwise it returns ErrFull.
+// It returns the error of the context if the context is done while waiting.
+func (b *Bulkhead) Acquire(ctx context.Context) (func(), error) {
+	release := func() { <-b.slots }
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	default:
+	}
+	b.mutex.Lock()
+	if b.waiting >= b.maxWaiting {
+		b.mutex.Unlock()
+		return nil, ErrFull
+	}
+	b.waiting++
+	b.mutex.Unlock()
+	defer func() {
+		b.mutex.Lock()
+		b.waiting--
+		b.mutex.Unlock()
+	}()
+	select {
+	case b.slots <- struct{}{}:
+		return release, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// InFlight returns the number of calls running.
+func (b *Bulkhead) InFlight() int {
+	return len(b.slots)
+}
+
+// Execute calls f if the bulkhead has room for it. Otherwise, f is not called and the error of Acquire is returned.
+func Execute[T any](ctx context.Context, b *Bulkhead, f func(ctx context.Context) (T, error)) (T, error) {
+	release, err := b.Acquire(ctx)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	defer release()
+	return f(ctx)
+}
+
+// Wrap returns a function calling f through the bulkhead.
+func Wrap[T any](b *Bulkhead, f func(ctx context.Context) (T, error)) func(ctx context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		return Execute(ctx, b, f)
+	}
+}
+
+// Registry holds a Bulkhead per named resource, all created with the same configuration.
+type Registry struct {
+	maxConcurrent int
+	opts          []Option
+	mutex         sync.Mutex
+	bulkheads     map[string]*Bulkhead
+}
+
+// NewRegistry creates a Registry creating its bulkheads with New(maxConcurrent, opts...).
+func NewRegistry(maxConcurrent int, opts ...Option) *Registry {
+	return &Registry{
+		maxConcurrent: maxConcurrent,
+		opts:          opts,
+		bulkheads:     make(map[string]*Bulkhead),
+	}
+}
+
+// Get returns the Bulkhead of the resource, created the first time it is requested.
+func (r *Registry) Get(name string) *Bulkhead {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	b, ok := r.bulkheads[name]
+	if !ok {
+		b = New(r.maxConcurrent, r.opts...)
+		r.bulkheads[name] = b
+	}
+	return b
+}
+
+// Set registers a Bulkhead with a specific configuration for the resource.
+func (r *Registry) Set(name string, b *Bulkhead) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.bulkheads[name] = b
+}
@@ -0,0 +1,69 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package bulkhead
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkhead_FailFast(t *testing.T) {
+	b := New(1)
+	release, err := b.Acquire(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, b.InFlight())
+	_, err = Execute(context.Background(), b, func(_ context.Context) (int, error) {
+		return 1, nil
+	})
+	assert.ErrorIs(t, err, ErrFull)
+	release()
+	result, err := Execute(context.Background(), b, func(_ context.Context) (int, error) {
+		return 1, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result)
+	assert.Equal(t, 0, b.InFlight())
+}
+
+func TestBulkhead_Waiting(t *testing.T) {
+	b := New(1, WithMaxWaiting(1))
+	release, _ := b.Acquire(context.Background())
+	done := make(chan error)
+	go func() {
+		_, err := Execute(context.Background(), b, func(_ context.Context) (int, error) {
+			return 1, nil
+		})
+		done <- err
+	}()
+	// wait for the previous call to be waiting, so this one is rejected
+	assert.Eventually(t, func() bool {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		return b.waiting == 1
+	}, time.Second, time.Millisecond)
+	_, err := b.Acquire(context.Background())
+	assert.ErrorIs(t, err, ErrFull)
+	// the call waiting is executed once the slot is free
+	release()
+	assert.NoError(t, <-done)
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry(2)
+	assert.Same(t, r.Get("db"), r.Get("db"))
+	assert.NotSame(t, r.Get("db"), r.Get("api"))
+}
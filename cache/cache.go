@@ -0,0 +1,176 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache provides generic in-memory caches, safe for concurrent use.
+//
+// TTL is a cache whose entries expire after a given duration:
+//
+//	users := cache.NewTTL[string, *User](5 * time.Minute)
+//	user, err := users.GetOrLoad(ctx, id, func() (*User, error) {
+//		return client.GetUser(ctx, id)
+//	})
+//
+// The expired entries are removed when they are read. To remove all of them periodically, run the janitor task:
+//
+//	app.NewRunner().WithCronTasks(time.Minute, users.Janitor()).Start()
+//
+// LRU is a cache bounded in number of entries or in bytes, evicting the least recently used entries first.
+// Its entries can also expire like in a TTL cache:
+//
+//	documents := cache.NewLRU(cache.LRUConfig[string, []byte]{
+//		MaxBytes: 64 << 20,
+//		Size:     func(_ string, document []byte) int64 { return int64(len(document)) },
+//		TTL:      time.Hour,
+//	}, cache.WithName("documents"))
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/perses/common/clock"
+)
+
+// EvictionReason explains why an entry has been removed from a cache.
+type EvictionReason string
+
+const (
+	// EvictedCapacity is the reason of an entry removed to respect the maximum size of the cache.
+	EvictedCapacity EvictionReason = "capacity"
+	// EvictedExpired is the reason of an entry removed because it expired.
+	EvictedExpired EvictionReason = "expired"
+	// EvictedDeleted is the reason of an entry removed with Delete.
+	EvictedDeleted EvictionReason = "deleted"
+)
+
+// MetricsHook is notified of the hits, misses and evictions of a cache, so they can be exported to a monitoring system.
+// It is called synchronously, so it must be fast.
+type MetricsHook interface {
+	Hit(cache string)
+	Miss(cache string)
+	Evicted(cache string, reason EvictionReason)
+}
+
+type noopMetricsHook struct{}
+
+func (noopMetricsHook) Hit(_ string)                       {}
+func (noopMetricsHook) Miss(_ string)                      {}
+func (noopMetricsHook) Evicted(_ string, _ EvictionReason) {}
+
+type options struct {
+	clock   clock.Clock
+	name    string
+	metrics MetricsHook
+}
+
+// Option configures a cache.
+type Option func(o *options)
+
+// WithClock sets the Clock used to know when an entry expires. By default, it is clock.Real.
+func WithClock(c clock.Clock) Option {
+	return func(o *options) {
+		o.clock = clock.OrReal(c)
+	}
+}
+
+// WithName sets the name identifying the cache in the MetricsHook. By default, it is "default".
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithMetricsHook sets the MetricsHook of the cache. By default, nothing is reported.
+func WithMetricsHook(hook MetricsHook) Option {
+	return func(o *options) {
+		if hook != nil {
+			o.metrics = hook
+		}
+	}
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{clock: clock.Real, name: "default", metrics: noopMetricsHook{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// getOrLoad returns the value of get, or loads it through the group and stores it with set when it is missing.
+func getOrLoad[K comparable, V any](ctx context.Context, group *async.KeyedSharedGroup[K, V], key K, get func(key K) (V, bool), set func(key K, value V), load func() (V, error)) (V, error) {
+	if value, ok := get(key); ok {
+		return value, nil
+	}
+	return group.Shared(key, func() (V, error) {
+		value, err := load()
+		if err == nil {
+			set(key, value)
+		}
+		return value, err
+	}).AwaitWithContext(ctx)
+}
+
+// janitor is the task purging a cache.
+type janitor struct {
+	name  string
+	purge func()
+}
+
+func (j *janitor) String() string {
+	return j.name
+}
+
+func (j *janitor) Execute(_ context.Context, _ context.CancelFunc) error {
+	j.purge()
+	return nil
+}
+
+// sweeper is the long-running task purging a cache at a fixed interval.
+type sweeper struct {
+	async.Task
+	name     string
+	interval time.Duration
+	clock    clock.Clock
+	purge    func()
+}
+
+func (s *sweeper) String() string {
+	return s.name
+}
+
+func (s *sweeper) Initialize() error {
+	if s.interval <= 0 {
+		return fmt.Errorf("the interval of the task '%s' must be strictly positive", s.name)
+	}
+	return nil
+}
+
+func (s *sweeper) Execute(ctx context.Context, _ context.CancelFunc) error {
+	ticker := s.clock.NewTicker(s.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C():
+			s.purge()
+		}
+	}
+}
+
+func (s *sweeper) Finalize() error {
+	return nil
+}
@@ -0,0 +1,211 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"container/list"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/perses/common/clock"
+)
+
+// LRUConfig is the configuration of an LRU cache. At least one of MaxEntries and MaxBytes should be set, otherwise the cache is unbounded.
+type LRUConfig[K comparable, V any] struct {
+	// MaxEntries is the maximum number of entries. 0 means no limit.
+	MaxEntries int
+	// MaxBytes is the maximum combined size of the entries, as computed by Size. 0 means no limit.
+	MaxBytes int64
+	// Size computes the size of an entry. It is required when MaxBytes is set.
+	Size func(key K, value V) int64
+	// TTL is the duration after which an entry expires. 0 means the entries don't expire.
+	TTL time.Duration
+	// OnEvict is called every time an entry is removed from the cache, outside the lock of the cache.
+	OnEvict func(key K, value V, reason EvictionReason)
+}
+
+type lruEntry[K comparable, V any] struct {
+	key   K
+	value V
+	size  int64
+	// expiration is the zero time for an entry that never expires.
+	expiration time.Time
+}
+
+type eviction[K comparable, V any] struct {
+	entry  *lruEntry[K, V]
+	reason EvictionReason
+}
+
+// LRU is a cache bounded in number of entries or in bytes. Once full, the least recently used entries are evicted first.
+type LRU[K comparable, V any] struct {
+	config  LRUConfig[K, V]
+	clock   clock.Clock
+	name    string
+	metrics MetricsHook
+	mutex   sync.Mutex
+	// order has the most recently used entry at the front.
+	order   *list.List
+	entries map[K]*list.Element
+	bytes   int64
+	loads   async.KeyedSharedGroup[K, V]
+}
+
+func NewLRU[K comparable, V any](config LRUConfig[K, V], opts ...Option) *LRU[K, V] {
+	o := newOptions(opts)
+	return &LRU[K, V]{
+		config:  config,
+		clock:   o.clock,
+		name:    o.name,
+		metrics: o.metrics,
+		order:   list.New(),
+		entries: make(map[K]*list.Element),
+	}
+}
+
+// Get returns the value of the key and true, or false if the key is missing or expired. The entry becomes the most recently used.
+func (c *LRU[K, V]) Get(key K) (V, bool) {
+	now := c.clock.Now()
+	c.mutex.Lock()
+	element, ok := c.entries[key]
+	if !ok {
+		c.mutex.Unlock()
+		c.metrics.Miss(c.name)
+		var zero V
+		return zero, false
+	}
+	entry := element.Value.(*lruEntry[K, V])
+	if !entry.expiration.IsZero() && !now.Before(entry.expiration) {
+		c.remove(element)
+		c.mutex.Unlock()
+		c.evicted([]eviction[K, V]{{entry: entry, reason: EvictedExpired}})
+		c.metrics.Miss(c.name)
+		var zero V
+		return zero, false
+	}
+	c.order.MoveToFront(element)
+	c.mutex.Unlock()
+	c.metrics.Hit(c.name)
+	return entry.value, true
+}
+
+// Set stores the value for the key as the most recently used entry, then evicts the least recently used entries while the cache is too big.
+func (c *LRU[K, V]) Set(key K, value V) {
+	entry := &lruEntry[K, V]{key: key, value: value}
+	if c.config.Size != nil {
+		entry.size = c.config.Size(key, value)
+	}
+	if c.config.TTL > 0 {
+		entry.expiration = c.clock.Now().Add(c.config.TTL)
+	}
+	c.mutex.Lock()
+	if element, ok := c.entries[key]; ok {
+		c.remove(element)
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	c.bytes += entry.size
+	var evictions []eviction[K, V]
+	for c.tooBig() {
+		oldest := c.order.Back()
+		c.remove(oldest)
+		evictions = append(evictions, eviction[K, V]{entry: oldest.Value.(*lruEntry[K, V]), reason: EvictedCapacity})
+	}
+	c.mutex.Unlock()
+	c.evicted(evictions)
+}
+
+// Delete removes the key from the cache.
+func (c *LRU[K, V]) Delete(key K) {
+	c.mutex.Lock()
+	element, ok := c.entries[key]
+	if !ok {
+		c.mutex.Unlock()
+		return
+	}
+	c.remove(element)
+	c.mutex.Unlock()
+	c.evicted([]eviction[K, V]{{entry: element.Value.(*lruEntry[K, V]), reason: EvictedDeleted}})
+}
+
+// Len returns the number of entries in the cache, including the expired ones not removed yet.
+func (c *LRU[K, V]) Len() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return len(c.entries)
+}
+
+// Bytes returns the combined size of the entries, as computed by LRUConfig.Size.
+func (c *LRU[K, V]) Bytes() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.bytes
+}
+
+// GetOrLoad returns the value of the key. If it is missing or expired, it is loaded with load and stored in the cache.
+// The concurrent loads of the same key are deduplicated, like with TTL.GetOrLoad.
+func (c *LRU[K, V]) GetOrLoad(ctx context.Context, key K, load func() (V, error)) (V, error) {
+	return getOrLoad(ctx, &c.loads, key, c.Get, c.Set, load)
+}
+
+// Purge removes every expired entry.
+func (c *LRU[K, V]) Purge() {
+	now := c.clock.Now()
+	c.mutex.Lock()
+	var evictions []eviction[K, V]
+	for element := c.order.Front(); element != nil; {
+		next := element.Next()
+		entry := element.Value.(*lruEntry[K, V])
+		if !entry.expiration.IsZero() && !now.Before(entry.expiration) {
+			c.remove(element)
+			evictions = append(evictions, eviction[K, V]{entry: entry, reason: EvictedExpired})
+		}
+		element = next
+	}
+	c.mutex.Unlock()
+	c.evicted(evictions)
+}
+
+// Janitor returns a task purging the cache each time it is executed. It is meant to be run periodically, with app.Runner.WithCronTasks for example.
+func (c *LRU[K, V]) Janitor() async.SimpleTask {
+	return &janitor{name: c.name + " cache janitor", purge: c.Purge}
+}
+
+// tooBig must be called with the mutex held.
+func (c *LRU[K, V]) tooBig() bool {
+	if c.order.Len() == 0 {
+		return false
+	}
+	return (c.config.MaxEntries > 0 && c.order.Len() > c.config.MaxEntries) ||
+		(c.config.MaxBytes > 0 && c.bytes > c.config.MaxBytes)
+}
+
+// remove must be called with the mutex held.
+func (c *LRU[K, V]) remove(element *list.Element) {
+	entry := element.Value.(*lruEntry[K, V])
+	c.order.Remove(element)
+	delete(c.entries, entry.key)
+	c.bytes -= entry.size
+}
+
+// evicted notifies the evictions. It must be called without the mutex held, so OnEvict is able to use the cache.
+func (c *LRU[K, V]) evicted(evictions []eviction[K, V]) {
+	for _, e := range evictions {
+		c.metrics.Evicted(c.name, e.reason)
+		if c.config.OnEvict != nil {
+			c.config.OnEvict(e.entry.key, e.entry.value, e.reason)
+		}
+	}
+}
@@ -0,0 +1,94 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/perses/common/async/asynctest"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingHook struct {
+	hits, misses int
+	evictions    map[EvictionReason]int
+}
+
+func (h *countingHook) Hit(_ string)  { h.hits++ }
+func (h *countingHook) Miss(_ string) { h.misses++ }
+func (h *countingHook) Evicted(_ string, reason EvictionReason) {
+	h.evictions[reason]++
+}
+
+func TestLRU_MaxEntries(t *testing.T) {
+	var evicted []string
+	hook := &countingHook{evictions: make(map[EvictionReason]int)}
+	c := NewLRU(LRUConfig[string, int]{
+		MaxEntries: 2,
+		OnEvict: func(key string, _ int, _ EvictionReason) {
+			evicted = append(evicted, key)
+		},
+	}, WithMetricsHook(hook))
+	c.Set("a", 1)
+	c.Set("b", 2)
+	// "a" becomes the most recently used, so "b" is evicted first
+	_, ok := c.Get("a")
+	assert.True(t, ok)
+	c.Set("c", 3)
+	_, ok = c.Get("b")
+	assert.False(t, ok)
+	assert.Equal(t, []string{"b"}, evicted)
+	assert.Equal(t, 2, c.Len())
+	c.Delete("a")
+	assert.Equal(t, []string{"b", "a"}, evicted)
+	assert.Equal(t, 1, hook.hits)
+	assert.Equal(t, 1, hook.misses)
+	assert.Equal(t, map[EvictionReason]int{EvictedCapacity: 1, EvictedDeleted: 1}, hook.evictions)
+}
+
+func TestLRU_MaxBytes(t *testing.T) {
+	c := NewLRU(LRUConfig[string, string]{
+		MaxBytes: 10,
+		Size: func(_ string, value string) int64 {
+			return int64(len(value))
+		},
+	})
+	c.Set("a", "12345")
+	c.Set("b", "1234")
+	assert.Equal(t, int64(9), c.Bytes())
+	c.Set("c", "123")
+	assert.Equal(t, int64(7), c.Bytes())
+	_, ok := c.Get("a")
+	assert.False(t, ok)
+	// replacing an entry updates the size
+	c.Set("b", "1")
+	assert.Equal(t, int64(4), c.Bytes())
+}
+
+func TestLRU_TTL(t *testing.T) {
+	clock := asynctest.NewFakeClock(time.Now())
+	c := NewLRU(LRUConfig[int, int]{MaxEntries: 10, TTL: time.Minute}, WithClock(clock))
+	c.Set(1, 1)
+	clock.Advance(30 * time.Second)
+	c.Set(2, 2)
+	clock.Advance(30 * time.Second)
+	_, ok := c.Get(1)
+	assert.False(t, ok)
+	c.Purge()
+	assert.Equal(t, 1, c.Len())
+	clock.Advance(30 * time.Second)
+	c.Purge()
+	assert.Equal(t, 0, c.Len())
+}
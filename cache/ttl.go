@@ -0,0 +1,181 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/perses/common/clock"
+)
+
+type ttlEntry[V any] struct {
+	value V
+	// expiration is the zero time for an entry that never expires.
+	expiration time.Time
+}
+
+func (e ttlEntry[V]) expired(now time.Time) bool {
+	return !e.expiration.IsZero() && !now.Before(e.expiration)
+}
+
+// TTL is a cache whose entries expire after a duration. It can also be used as an expiring map, for a deduplication window for example.
+//
+// The entries expire lazily: an expired entry is removed when it is read. They can also expire actively, by running
+// the Janitor periodically or the Sweeper as a long-running task, so the memory of the entries never read again is freed.
+type TTL[K comparable, V any] struct {
+	defaultTTL time.Duration
+	clock      clock.Clock
+	name       string
+	metrics    MetricsHook
+	mutex      sync.RWMutex
+	entries    map[K]ttlEntry[V]
+	loads      async.KeyedSharedGroup[K, V]
+}
+
+// NewTTL creates a TTL cache where the entries expire after defaultTTL, unless they are set with SetWithTTL.
+// A defaultTTL <= 0 means the entries don't expire by default.
+func NewTTL[K comparable, V any](defaultTTL time.Duration, opts ...Option) *TTL[K, V] {
+	o := newOptions(opts)
+	return &TTL[K, V]{
+		defaultTTL: defaultTTL,
+		clock:      o.clock,
+		name:       o.name,
+		metrics:    o.metrics,
+		entries:    make(map[K]ttlEntry[V]),
+	}
+}
+
+// Get returns the value of the key and true, or false if the key is missing or expired.
+func (c *TTL[K, V]) Get(key K) (V, bool) {
+	now := c.clock.Now()
+	c.mutex.RLock()
+	entry, ok := c.entries[key]
+	c.mutex.RUnlock()
+	if !ok {
+		c.metrics.Miss(c.name)
+		var zero V
+		return zero, false
+	}
+	if entry.expired(now) {
+		c.mutex.Lock()
+		// the entry may have been replaced in the meantime.
+		current, exists := c.entries[key]
+		evicted := exists && current.expired(now)
+		if evicted {
+			delete(c.entries, key)
+		}
+		c.mutex.Unlock()
+		if evicted {
+			c.metrics.Evicted(c.name, EvictedExpired)
+		}
+		c.metrics.Miss(c.name)
+		var zero V
+		return zero, false
+	}
+	c.metrics.Hit(c.name)
+	return entry.value, true
+}
+
+// Set stores the value for the key, expiring after the default TTL.
+func (c *TTL[K, V]) Set(key K, value V) {
+	c.SetWithTTL(key, value, c.defaultTTL)
+}
+
+// SetWithTTL stores the value for the key, expiring after the ttl given. A ttl <= 0 means the entry never expires.
+func (c *TTL[K, V]) SetWithTTL(key K, value V, ttl time.Duration) {
+	entry := ttlEntry[V]{value: value}
+	if ttl > 0 {
+		entry.expiration = c.clock.Now().Add(ttl)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.entries[key] = entry
+}
+
+// SetIfAbsent stores the value for the key, expiring after the ttl given, only if the key is missing or expired.
+// It returns true if the value has been stored. It is the way to check and mark a key atomically, like a request already seen:
+//
+//	if !seen.SetIfAbsent(requestID, struct{}{}, time.Minute) {
+//		return errDuplicate
+//	}
+func (c *TTL[K, V]) SetIfAbsent(key K, value V, ttl time.Duration) bool {
+	now := c.clock.Now()
+	entry := ttlEntry[V]{value: value}
+	if ttl > 0 {
+		entry.expiration = now.Add(ttl)
+	}
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if current, ok := c.entries[key]; ok && !current.expired(now) {
+		return false
+	}
+	c.entries[key] = entry
+	return true
+}
+
+// Delete removes the key from the cache.
+func (c *TTL[K, V]) Delete(key K) {
+	c.mutex.Lock()
+	_, exists := c.entries[key]
+	delete(c.entries, key)
+	c.mutex.Unlock()
+	if exists {
+		c.metrics.Evicted(c.name, EvictedDeleted)
+	}
+}
+
+// Len returns the number of entries in the cache, including the expired ones not removed yet.
+func (c *TTL[K, V]) Len() int {
+	c.mutex.RLock()
+	defer c.mutex.RUnlock()
+	return len(c.entries)
+}
+
+// GetOrLoad returns the value of the key. If it is missing or expired, it is loaded with load and stored in the cache with the default TTL.
+// The concurrent loads of the same key are deduplicated: load is called once and its result is shared by every caller.
+// A failed load is not stored. If the context is done before the load ends, the caller stops waiting and gets the error of the context.
+func (c *TTL[K, V]) GetOrLoad(ctx context.Context, key K, load func() (V, error)) (V, error) {
+	return getOrLoad(ctx, &c.loads, key, c.Get, c.Set, load)
+}
+
+// Purge removes every expired entry.
+func (c *TTL[K, V]) Purge() {
+	now := c.clock.Now()
+	c.mutex.Lock()
+	evicted := 0
+	for key, entry := range c.entries {
+		if entry.expired(now) {
+			delete(c.entries, key)
+			evicted++
+		}
+	}
+	c.mutex.Unlock()
+	for i := 0; i < evicted; i++ {
+		c.metrics.Evicted(c.name, EvictedExpired)
+	}
+}
+
+// Janitor returns a task purging the cache each time it is executed. It is meant to be run periodically, with app.Runner.WithCronTasks for example.
+func (c *TTL[K, V]) Janitor() async.SimpleTask {
+	return &janitor{name: c.name + " cache janitor", purge: c.Purge}
+}
+
+// Sweeper returns a long-running task purging the cache at every interval until its context is done.
+// Unlike Janitor, it doesn't need to be run periodically: it is meant to be given to app.Runner.WithTasks.
+func (c *TTL[K, V]) Sweeper(interval time.Duration) async.Task {
+	return &sweeper{name: c.name + " cache sweeper", interval: interval, clock: c.clock, purge: c.Purge}
+}
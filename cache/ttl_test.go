@@ -0,0 +1,112 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/perses/common/async/asynctest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTTL(t *testing.T) {
+	c := asynctest.NewFakeClock(time.Now())
+	cache := NewTTL[string, int](time.Minute, WithClock(c))
+	cache.Set("a", 1)
+	cache.SetWithTTL("b", 2, 2*time.Minute)
+	cache.SetWithTTL("forever", 3, 0)
+	value, ok := cache.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+
+	c.Advance(time.Minute)
+	_, ok = cache.Get("a")
+	assert.False(t, ok)
+	_, ok = cache.Get("b")
+	assert.True(t, ok)
+
+	c.Advance(time.Hour)
+	assert.Equal(t, 2, cache.Len())
+	assert.NoError(t, cache.Janitor().Execute(context.Background(), nil))
+	assert.Equal(t, 1, cache.Len())
+	value, ok = cache.Get("forever")
+	assert.True(t, ok)
+	assert.Equal(t, 3, value)
+	cache.Delete("forever")
+	assert.Equal(t, 0, cache.Len())
+}
+
+func TestTTL_GetOrLoad(t *testing.T) {
+	cache := NewTTL[int, string](time.Minute)
+	var loads int32
+	release := make(chan struct{})
+	load := func() (string, error) {
+		atomic.AddInt32(&loads, 1)
+		<-release
+		return "value", nil
+	}
+	wg := &sync.WaitGroup{}
+	wg.Add(5)
+	for i := 0; i < 5; i++ {
+		go func() {
+			defer wg.Done()
+			value, err := cache.GetOrLoad(context.Background(), 1, load)
+			assert.NoError(t, err)
+			assert.Equal(t, "value", value)
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&loads))
+
+	_, err := cache.GetOrLoad(context.Background(), 2, func() (string, error) {
+		return "", fmt.Errorf("failure")
+	})
+	assert.Error(t, err)
+	_, ok := cache.Get(2)
+	assert.False(t, ok)
+}
+
+func TestTTL_SetIfAbsent(t *testing.T) {
+	c := asynctest.NewFakeClock(time.Now())
+	seen := NewTTL[string, struct{}](0, WithClock(c))
+	assert.True(t, seen.SetIfAbsent("request", struct{}{}, time.Minute))
+	assert.False(t, seen.SetIfAbsent("request", struct{}{}, time.Minute))
+	c.Advance(time.Minute)
+	assert.True(t, seen.SetIfAbsent("request", struct{}{}, time.Minute))
+}
+
+func TestTTL_Sweeper(t *testing.T) {
+	c := asynctest.NewFakeClock(time.Now())
+	cache := NewTTL[string, int](time.Minute, WithClock(c))
+	cache.Set("a", 1)
+	sweeper := cache.Sweeper(time.Minute)
+	assert.NoError(t, sweeper.Initialize())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- sweeper.Execute(ctx, cancel) }()
+	c.BlockUntil(1)
+	c.Advance(time.Minute)
+	// the entry is removed without being read
+	assert.Eventually(t, func() bool { return cache.Len() == 0 }, time.Second, time.Millisecond)
+	cancel()
+	assert.NoError(t, <-done)
+	assert.Error(t, cache.Sweeper(0).Initialize())
+}
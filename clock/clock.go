@@ -0,0 +1,133 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package clock abstracts the time, so the code depending on it can be tested without waiting for real.
+//
+// The features of this repository depending on the time accept a Clock, and use Real by default.
+// In tests, a fake implementation can be given instead, like the one of the package async/asynctest.
+package clock
+
+import (
+	"time"
+)
+
+// Clock gives access to the current time and to the timers.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+	// Since returns the time elapsed since t.
+	Since(t time.Time) time.Duration
+	// After waits for the duration to elapse and then sends the current time on the channel returned.
+	After(d time.Duration) <-chan time.Time
+	// Sleep pauses the current go-routine for the duration.
+	Sleep(d time.Duration)
+	// NewTimer creates a Timer sending the current time on its channel once the duration elapsed.
+	NewTimer(d time.Duration) Timer
+	// AfterFunc calls f in its own go-routine once the duration elapsed. The Timer returned can be used to stop it.
+	AfterFunc(d time.Duration, f func()) Timer
+	// NewTicker creates a Ticker sending the current time on its channel every period.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer is the equivalent of time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is sent. It is nil for a timer created by AfterFunc.
+	C() <-chan time.Time
+	// Stop prevents the timer from firing. It returns false if the timer has already fired or been stopped.
+	Stop() bool
+	// Reset changes the timer to fire after the duration. It returns false if the timer had already fired or been stopped.
+	Reset(d time.Duration) bool
+}
+
+// Ticker is the equivalent of time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are sent.
+	C() <-chan time.Time
+	// Stop turns off the ticker.
+	Stop()
+	// Reset stops the ticker and resets its period to the duration.
+	Reset(d time.Duration)
+}
+
+// Real is the Clock relying on the package time.
+var Real Clock = realClock{}
+
+// OrReal returns c, or Real if c is nil.
+func OrReal(c Clock) Clock {
+	if c == nil {
+		return Real
+	}
+	return c
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) Since(t time.Time) time.Duration {
+	return time.Since(t)
+}
+
+func (realClock) After(d time.Duration) <-chan time.Time {
+	return time.After(d)
+}
+
+func (realClock) Sleep(d time.Duration) {
+	time.Sleep(d)
+}
+
+func (realClock) NewTimer(d time.Duration) Timer {
+	return &realTimer{timer: time.NewTimer(d)}
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return &realTimer{timer: time.AfterFunc(d, f)}
+}
+
+func (realClock) NewTicker(d time.Duration) Ticker {
+	return &realTicker{ticker: time.NewTicker(d)}
+}
+
+type realTimer struct {
+	timer *time.Timer
+}
+
+func (t *realTimer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t *realTimer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (t *realTimer) Reset(d time.Duration) bool {
+	return t.timer.Reset(d)
+}
+
+type realTicker struct {
+	ticker *time.Ticker
+}
+
+func (t *realTicker) C() <-chan time.Time {
+	return t.ticker.C
+}
+
+func (t *realTicker) Stop() {
+	t.ticker.Stop()
+}
+
+func (t *realTicker) Reset(d time.Duration) {
+	t.ticker.Reset(d)
+}
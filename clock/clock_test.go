@@ -0,0 +1,39 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package clock
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReal(t *testing.T) {
+	c := OrReal(nil)
+	start := c.Now()
+	timer := c.NewTimer(5 * time.Millisecond)
+	<-timer.C()
+	assert.False(t, timer.Stop())
+	assert.GreaterOrEqual(t, c.Since(start), 5*time.Millisecond)
+
+	fired := make(chan struct{})
+	c.AfterFunc(time.Millisecond, func() { close(fired) })
+	<-fired
+
+	ticker := c.NewTicker(time.Millisecond)
+	<-ticker.C()
+	<-ticker.C()
+	ticker.Stop()
+}
@@ -0,0 +1,191 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package eventbus provides an in-process publish/subscribe mechanism, with typed topics.
+//
+// A Topic is usually declared once and shared by the publishers and the subscribers:
+//
+//	var UserCreated = eventbus.NewTopic[User]("user_created")
+//
+//	subscription := UserCreated.SubscribeFunc(10, func(user User) {
+//		sendWelcomeEmail(user)
+//	})
+//	defer subscription.Unsubscribe()
+//
+//	err := UserCreated.Publish(ctx, user)
+//
+// Every subscriber has its own buffer. When the buffer of a subscriber is full, Publish waits for it to have some room,
+// so a slow subscriber slows down the publishers instead of losing events.
+package eventbus
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+
+	"github.com/perses/common/async"
+)
+
+// ErrTopicClosed is returned when publishing an event to a topic already closed.
+var ErrTopicClosed = fmt.Errorf("topic closed")
+
+// KindSubscriber is the kind of the events given to the Logger when the handler of a subscriber panicked.
+const KindSubscriber async.Kind = "subscriber"
+
+// Topic is a named stream of events of the type T.
+type Topic[T any] struct {
+	name        string
+	mutex       sync.RWMutex
+	seq         uint64
+	subscribers map[uint64]*Subscription[T]
+	closed      bool
+	logger      async.Logger
+}
+
+func NewTopic[T any](name string) *Topic[T] {
+	return &Topic[T]{
+		name:        name,
+		subscribers: make(map[uint64]*Subscription[T]),
+		logger:      async.NewLogrusLogger(nil),
+	}
+}
+
+// SetLogger sets the Logger receiving an async.EventPanicked each time the handler of a subscriber registered with SubscribeFunc panicked.
+// By default, it uses logrus. It must be called before the first subscription.
+func (t *Topic[T]) SetLogger(logger async.Logger) *Topic[T] {
+	t.logger = logger
+	return t
+}
+
+func (t *Topic[T]) String() string {
+	return t.name
+}
+
+// Publish sends the event to every subscriber of the topic.
+// It blocks while the buffer of a subscriber is full, until the context is done.
+func (t *Topic[T]) Publish(ctx context.Context, event T) error {
+	t.mutex.RLock()
+	defer t.mutex.RUnlock()
+	if t.closed {
+		return ErrTopicClosed
+	}
+	for _, s := range t.subscribers {
+		select {
+		case s.events <- event:
+		case <-s.done:
+			// the subscriber is leaving, it doesn't need the event anymore.
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// Subscribe registers a new subscriber having a buffer of the size given. The events are received through Subscription.C.
+// If the topic is already closed, the channel returned is closed too.
+func (t *Topic[T]) Subscribe(buffer int) *Subscription[T] {
+	if buffer < 0 {
+		buffer = 0
+	}
+	events := make(chan T, buffer)
+	s := &Subscription[T]{
+		C:      events,
+		topic:  t,
+		events: events,
+		done:   make(chan struct{}),
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if t.closed {
+		close(s.done)
+		close(events)
+		return s
+	}
+	t.seq++
+	s.id = t.seq
+	t.subscribers[s.id] = s
+	return s
+}
+
+// SubscribeFunc registers a new subscriber calling the handler for every event, in the order they have been published.
+// A panic in the handler is given to the Logger and doesn't stop the subscription.
+func (t *Topic[T]) SubscribeFunc(buffer int, handler func(event T)) *Subscription[T] {
+	s := t.Subscribe(buffer)
+	go func() {
+		for event := range s.C {
+			t.handle(handler, event)
+		}
+	}()
+	return s
+}
+
+// Close unsubscribes every subscriber and refuses any new event.
+func (t *Topic[T]) Close() {
+	t.mutex.RLock()
+	subscribers := make([]*Subscription[T], 0, len(t.subscribers))
+	for _, s := range t.subscribers {
+		subscribers = append(subscribers, s)
+	}
+	t.mutex.RUnlock()
+	for _, s := range subscribers {
+		s.leave()
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.closed = true
+	for id, s := range t.subscribers {
+		delete(t.subscribers, id)
+		close(s.events)
+	}
+}
+
+func (t *Topic[T]) handle(handler func(event T), event T) {
+	defer func() {
+		if r := recover(); r != nil {
+			t.logger.Log(async.Event{Type: async.EventPanicked, Kind: KindSubscriber, Name: t.name, Err: &async.ErrPanicked{Value: r, Stack: debug.Stack()}})
+		}
+	}()
+	handler(event)
+}
+
+// Subscription is the registration of a subscriber to a Topic.
+type Subscription[T any] struct {
+	// C is the channel receiving the events. It is closed once unsubscribed.
+	C      <-chan T
+	id     uint64
+	topic  *Topic[T]
+	events chan T
+	// done is closed when the subscriber is leaving, to release the publishers waiting for it.
+	done chan struct{}
+	once sync.Once
+}
+
+// Unsubscribe stops the subscription and closes the channel C. The events still in the buffer can be received.
+// It can be called several times.
+func (s *Subscription[T]) Unsubscribe() {
+	s.leave()
+	t := s.topic
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	if _, ok := t.subscribers[s.id]; ok {
+		delete(t.subscribers, s.id)
+		close(s.events)
+	}
+}
+
+func (s *Subscription[T]) leave() {
+	s.once.Do(func() {
+		close(s.done)
+	})
+}
@@ -0,0 +1,94 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package eventbus
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTopic_Subscribe(t *testing.T) {
+	topic := NewTopic[int]("test")
+	first := topic.Subscribe(5)
+	second := topic.Subscribe(5)
+	ctx := context.Background()
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, topic.Publish(ctx, i))
+	}
+	first.Unsubscribe()
+	assert.NoError(t, topic.Publish(ctx, 3))
+	topic.Close()
+	assert.ErrorIs(t, topic.Publish(ctx, 4), ErrTopicClosed)
+
+	var received []int
+	for event := range first.C {
+		received = append(received, event)
+	}
+	assert.Equal(t, []int{0, 1, 2}, received)
+	received = nil
+	for event := range second.C {
+		received = append(received, event)
+	}
+	assert.Equal(t, []int{0, 1, 2, 3}, received)
+}
+
+func TestTopic_SubscribeFunc(t *testing.T) {
+	events := make(chan async.Event, 1)
+	topic := NewTopic[string]("test").SetLogger(async.LoggerFunc(func(event async.Event) {
+		events <- event
+	}))
+	wg := &sync.WaitGroup{}
+	wg.Add(2)
+	var received []string
+	subscription := topic.SubscribeFunc(0, func(event string) {
+		defer wg.Done()
+		received = append(received, event)
+		if event == "panic" {
+			panic("handler failure")
+		}
+	})
+	defer subscription.Unsubscribe()
+	assert.NoError(t, topic.Publish(context.Background(), "panic"))
+	assert.NoError(t, topic.Publish(context.Background(), "event"))
+	wg.Wait()
+	assert.Equal(t, []string{"panic", "event"}, received)
+	event := <-events
+	assert.Equal(t, async.EventPanicked, event.Type)
+	assert.Equal(t, KindSubscriber, event.Kind)
+	assert.Equal(t, "test", event.Name)
+	var panicked *async.ErrPanicked
+	if assert.ErrorAs(t, event.Err, &panicked) {
+		assert.Equal(t, "handler failure", panicked.Value)
+	}
+}
+
+func TestTopic_PublishBlocked(t *testing.T) {
+	topic := NewTopic[int]("test")
+	subscription := topic.Subscribe(0)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	// nobody is reading the subscription, so the publisher waits until the context is done
+	assert.ErrorIs(t, topic.Publish(ctx, 1), context.DeadlineExceeded)
+	// unsubscribing releases the publishers waiting
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		subscription.Unsubscribe()
+	}()
+	assert.NoError(t, topic.Publish(context.Background(), 2))
+}
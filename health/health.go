@@ -0,0 +1,196 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package health keeps track of the health of the components of an application, usually its background tasks,
+// so the liveness and readiness probes of Kubernetes are able to detect a task that is stuck even when the HTTP server is fine.
+//
+// Each component registered reports its own state:
+//
+//	registry := health.NewRegistry()
+//	consumer := registry.Register("consumer", time.Minute)
+//	// in the loop of the task
+//	consumer.Beat()
+//	// once the task is able to process the messages
+//	consumer.SetReady(true)
+//
+// Then the state of the application is given by Live and Ready, or through HTTP by LiveHandler and ReadyHandler.
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/perses/common/async"
+)
+
+// Component is the health state of a single component, as reported by the component itself.
+type Component struct {
+	name string
+	// timeout is the maximum time between two calls to Beat before the component is considered stuck. 0 disables it.
+	timeout  time.Duration
+	mutex    sync.Mutex
+	lastBeat time.Time
+	ready    bool
+	err      error
+}
+
+// Beat reports that the component is still alive. It must be called more often than the timeout given at registration.
+func (c *Component) Beat() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.lastBeat = time.Now()
+}
+
+// SetReady reports whether the component is able to do its work.
+func (c *Component) SetReady(ready bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.ready = ready
+}
+
+// SetError reports that the component is failing. The component is not alive as long as the error is not reset with nil.
+func (c *Component) SetError(err error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.err = err
+}
+
+func (c *Component) live(now time.Time) error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	if c.err != nil {
+		return fmt.Errorf("component '%s' is failing: %w", c.name, c.err)
+	}
+	if c.timeout > 0 && now.Sub(c.lastBeat) > c.timeout {
+		return fmt.Errorf("component '%s' is stuck, last beat at %s", c.name, c.lastBeat.Format(time.RFC3339))
+	}
+	return nil
+}
+
+func (c *Component) isReady() bool {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.ready
+}
+
+// Registry holds every component of the application.
+type Registry struct {
+	mutex      sync.RWMutex
+	components map[string]*Component
+}
+
+func NewRegistry() *Registry {
+	return &Registry{
+		components: make(map[string]*Component),
+	}
+}
+
+// Register adds a new component, or returns the existing one with the same name.
+// timeout is the maximum time between two calls to Component.Beat, 0 if the component doesn't send any beat.
+// The component starts alive but not ready.
+func (r *Registry) Register(name string, timeout time.Duration) *Component {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if c, ok := r.components[name]; ok {
+		return c
+	}
+	c := &Component{name: name, timeout: timeout, lastBeat: time.Now()}
+	r.components[name] = c
+	return c
+}
+
+// Unregister removes the component, for example once its task is stopped on purpose.
+func (r *Registry) Unregister(name string) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	delete(r.components, name)
+}
+
+// Live returns an error when at least one component is failing or stuck.
+func (r *Registry) Live() error {
+	now := time.Now()
+	var errs async.MultiError
+	for _, c := range r.list() {
+		if err := c.live(now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errorOrNil(errs)
+}
+
+// Ready returns an error when at least one component is not alive or not ready.
+func (r *Registry) Ready() error {
+	now := time.Now()
+	var errs async.MultiError
+	for _, c := range r.list() {
+		if err := c.live(now); err != nil {
+			errs = append(errs, err)
+		} else if !c.isReady() {
+			errs = append(errs, fmt.Errorf("component '%s' is not ready", c.name))
+		}
+	}
+	return errorOrNil(errs)
+}
+
+// Check is the same as Ready: it returns an error when the application is not able to do its work.
+func (r *Registry) Check() error {
+	return r.Ready()
+}
+
+// LiveHandler returns an http.Handler answering 200 when Live doesn't return any error, 503 otherwise.
+func (r *Registry) LiveHandler() http.Handler {
+	return handler(r.Live)
+}
+
+// ReadyHandler returns an http.Handler answering 200 when Ready doesn't return any error, 503 otherwise.
+func (r *Registry) ReadyHandler() http.Handler {
+	return handler(r.Ready)
+}
+
+// list returns the components sorted by name, so the errors are always given in the same order.
+func (r *Registry) list() []*Component {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	result := make([]*Component, 0, len(r.components))
+	for _, c := range r.components {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].name < result[j].name
+	})
+	return result
+}
+
+func handler(check func() error) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		if err := check(); err != nil {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			_, _ = w.Write([]byte(err.Error()))
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("ok"))
+	})
+}
+
+// errorOrNil avoids to return a non-nil error interface holding an empty MultiError.
+func errorOrNil(errs async.MultiError) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	return errs
+}
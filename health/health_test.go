@@ -0,0 +1,56 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	consumer := r.Register("consumer", 20*time.Millisecond)
+	assert.NoError(t, r.Live())
+	assert.EqualError(t, r.Check(), "component 'consumer' is not ready")
+	consumer.SetReady(true)
+	assert.NoError(t, r.Check())
+
+	consumer.SetError(fmt.Errorf("connection lost"))
+	assert.EqualError(t, r.Live(), "component 'consumer' is failing: connection lost")
+	consumer.SetError(nil)
+	assert.NoError(t, r.Live())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Error(t, r.Live())
+	consumer.Beat()
+	assert.NoError(t, r.Live())
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	c := r.Register("consumer", 0)
+	recorder := httptest.NewRecorder()
+	r.ReadyHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	c.SetReady(true)
+	recorder = httptest.NewRecorder()
+	r.ReadyHandler().ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "ok", recorder.Body.String())
+}
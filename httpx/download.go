@@ -0,0 +1,223 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+
+	"github.com/perses/common/async"
+	"github.com/perses/common/retry"
+)
+
+const defaultChunkSize = 8 << 20
+
+// ErrRangesNotSupported is the error returned by Download when the server doesn't tell the size of the resource or doesn't accept byte ranges.
+var ErrRangesNotSupported = errors.New("server doesn't support byte-range requests for this resource")
+
+// Checkpoint records which chunks of a resource have been downloaded, so a failed download can be resumed without fetching them again.
+// Its fields are exported so it can be persisted between two executions.
+type Checkpoint struct {
+	URL       string `json:"url"`
+	Size      int64  `json:"size"`
+	ChunkSize int64  `json:"chunk_size"`
+	// Completed tells for each chunk if it has been written to the destination.
+	Completed []bool `json:"completed"`
+}
+
+// Done returns true once every chunk has been downloaded.
+func (c *Checkpoint) Done() bool {
+	for _, completed := range c.Completed {
+		if !completed {
+			return false
+		}
+	}
+	return true
+}
+
+// Downloader fetches a large resource by byte-range chunks, executed concurrently by an async.Pool.
+//
+//	pool := async.NewPool(8, 16)
+//	defer pool.Shutdown(context.Background())
+//	downloader := httpx.NewDownloader(pool, httpx.WithChunkSize(16<<20))
+//	checkpoint, err := downloader.Download(ctx, url, file)
+//	if err != nil {
+//		// later, once the failure is fixed
+//		checkpoint, err = downloader.Resume(ctx, checkpoint, file)
+//	}
+type Downloader struct {
+	pool      *async.Pool
+	client    *http.Client
+	chunkSize int64
+	retryOpts []retry.Option
+}
+
+// DownloadOption configures a Downloader.
+type DownloadOption func(d *Downloader)
+
+// WithChunkSize sets the number of bytes of each chunk. By default, it is 8MiB.
+func WithChunkSize(size int64) DownloadOption {
+	return func(d *Downloader) {
+		if size > 0 {
+			d.chunkSize = size
+		}
+	}
+}
+
+// WithDownloadClient sets the client sending the requests. By default, it is http.DefaultClient.
+func WithDownloadClient(client *http.Client) DownloadOption {
+	return func(d *Downloader) {
+		d.client = client
+	}
+}
+
+// WithChunkRetry retries a chunk that failed, according to the options of the package retry. By default, a chunk is not retried.
+func WithChunkRetry(opts ...retry.Option) DownloadOption {
+	return func(d *Downloader) {
+		d.retryOpts = append([]retry.Option{}, opts...)
+	}
+}
+
+func NewDownloader(pool *async.Pool, opts ...DownloadOption) *Downloader {
+	d := &Downloader{
+		pool:      pool,
+		client:    http.DefaultClient,
+		chunkSize: defaultChunkSize,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Download fetches the resource and writes it to the destination, each chunk at its offset.
+// It returns the Checkpoint of the download, to give to Resume if an error is returned.
+// The error is an async.MultiError of *async.ChunkError, whose Start and End are the bounds of the chunk in bytes.
+func (d *Downloader) Download(ctx context.Context, url string, dst io.WriterAt) (*Checkpoint, error) {
+	size, err := d.size(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	checkpoint := &Checkpoint{
+		URL:       url,
+		Size:      size,
+		ChunkSize: d.chunkSize,
+		Completed: make([]bool, (size+d.chunkSize-1)/d.chunkSize),
+	}
+	return d.Resume(ctx, checkpoint, dst)
+}
+
+// Resume fetches the chunks the Checkpoint doesn't mark as completed and writes them to the destination.
+// The destination must be the one given to the previous attempts, since the chunks completed are not written again.
+func (d *Downloader) Resume(ctx context.Context, checkpoint *Checkpoint, dst io.WriterAt) (*Checkpoint, error) {
+	var opts []async.SubmitOption
+	if d.retryOpts != nil {
+		opts = append(opts, async.WithRetry(d.retryOpts...))
+	}
+	// mutex protects checkpoint.Completed, updated by the workers of the pool.
+	var mutex sync.Mutex
+	indexes := make([]int, 0, len(checkpoint.Completed))
+	futures := make([]async.ErrFuture[struct{}], 0, len(checkpoint.Completed))
+	for i, completed := range checkpoint.Completed {
+		if completed {
+			continue
+		}
+		index := i
+		start, end := checkpoint.bounds(index)
+		future := async.SubmitWithContext(ctx, d.pool, func(_ context.Context) (struct{}, error) {
+			// the context of the caller is used rather than the one of the pool, so the download stops when the caller gives up.
+			if err := d.fetchChunk(ctx, checkpoint.URL, start, end, dst); err != nil {
+				return struct{}{}, err
+			}
+			mutex.Lock()
+			checkpoint.Completed[index] = true
+			mutex.Unlock()
+			return struct{}{}, nil
+		}, opts...)
+		indexes = append(indexes, index)
+		futures = append(futures, future)
+	}
+	var errs async.MultiError
+	for k, future := range futures {
+		if _, err := future.Await(); err != nil {
+			start, end := checkpoint.bounds(indexes[k])
+			errs = append(errs, &async.ChunkError{Index: indexes[k], Start: int(start), End: int(end), Err: err})
+		}
+	}
+	if len(errs) > 0 {
+		return checkpoint, errs
+	}
+	return checkpoint, nil
+}
+
+// bounds returns the offset of the first byte of the chunk and the offset following its last byte.
+func (c *Checkpoint) bounds(index int) (int64, int64) {
+	start := int64(index) * c.ChunkSize
+	end := start + c.ChunkSize
+	if end > c.Size {
+		end = c.Size
+	}
+	return start, end
+}
+
+func (d *Downloader) size(ctx context.Context, url string) (int64, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodHead, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	response, err := d.client.Do(request)
+	if err != nil {
+		return 0, err
+	}
+	_ = response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return 0, &StatusError{StatusCode: response.StatusCode}
+	}
+	if response.Header.Get("Accept-Ranges") != "bytes" || response.ContentLength < 0 {
+		return 0, ErrRangesNotSupported
+	}
+	return response.ContentLength, nil
+}
+
+func (d *Downloader) fetchChunk(ctx context.Context, url string, start int64, end int64, dst io.WriterAt) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end-1))
+	response, err := d.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusPartialContent {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+		return &StatusError{StatusCode: response.StatusCode, Body: body}
+	}
+	// the chunk is read entirely before being written, so a chunk partially received is never written.
+	buffer := bytes.NewBuffer(make([]byte, 0, end-start))
+	if _, err := io.Copy(buffer, io.LimitReader(response.Body, end-start)); err != nil {
+		return err
+	}
+	if int64(buffer.Len()) != end-start {
+		return fmt.Errorf("chunk truncated, received %d bytes instead of %d", buffer.Len(), end-start)
+	}
+	_, err = dst.WriteAt(buffer.Bytes(), start)
+	return err
+}
@@ -0,0 +1,72 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpx
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryFile is an io.WriterAt in memory.
+type memoryFile struct {
+	mutex sync.Mutex
+	data  []byte
+}
+
+func (f *memoryFile) WriteAt(p []byte, offset int64) (int, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+	if end := int(offset) + len(p); end > len(f.data) {
+		f.data = append(f.data, make([]byte, end-len(f.data))...)
+	}
+	return copy(f.data[offset:], p), nil
+}
+
+func TestDownloader(t *testing.T) {
+	content := strings.Repeat("0123456789", 10)
+	// failures is the number of range requests that still have to fail.
+	failures := int32(2)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" && atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		http.ServeContent(w, r, "content", time.Time{}, bytes.NewReader([]byte(content)))
+	}))
+	defer server.Close()
+	pool := async.NewPool(2, 4)
+	defer pool.Shutdown(context.Background())
+	downloader := NewDownloader(pool, WithChunkSize(30))
+	file := &memoryFile{}
+
+	checkpoint, err := downloader.Download(context.Background(), server.URL, file)
+	assert.Error(t, err)
+	assert.Len(t, checkpoint.Completed, 4)
+	assert.False(t, checkpoint.Done())
+
+	checkpoint, err = downloader.Resume(context.Background(), checkpoint, file)
+	assert.NoError(t, err)
+	assert.True(t, checkpoint.Done())
+	assert.Equal(t, content, string(file.data))
+}
@@ -0,0 +1,166 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package httpx provides helpers to send HTTP requests concurrently on top of the package async.
+//
+//	results := httpx.FetchAll(ctx, map[string]*http.Request{
+//		"dashboards":  dashboardsRequest,
+//		"datasources": datasourcesRequest,
+//	}, httpx.JSON[[]Resource](), httpx.WithConcurrency(4), httpx.WithRequestTimeout(5*time.Second))
+//	if err := results["dashboards"].Err; err != nil {
+//		return err
+//	}
+//	dashboards := results["dashboards"].Value
+package httpx
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/perses/common/async"
+)
+
+// maxErrorBody is the maximum number of bytes of the body kept in a StatusError.
+const maxErrorBody = 4096
+
+// StatusError is the error of a request whose response doesn't have a 2xx status code.
+type StatusError struct {
+	StatusCode int
+	// Body is the beginning of the body of the response, to help understanding the failure.
+	Body []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("unexpected status code %d: %s", e.StatusCode, e.Body)
+}
+
+// Decoder converts the response of a request with a 2xx status code into a value. The body is closed once it returns.
+type Decoder[T any] func(response *http.Response) (T, error)
+
+// JSON returns a Decoder unmarshalling the body of the response as JSON.
+func JSON[T any]() Decoder[T] {
+	return func(response *http.Response) (T, error) {
+		var value T
+		err := json.NewDecoder(response.Body).Decode(&value)
+		return value, err
+	}
+}
+
+// Bytes returns a Decoder reading the whole body of the response.
+func Bytes() Decoder[[]byte] {
+	return func(response *http.Response) ([]byte, error) {
+		return io.ReadAll(response.Body)
+	}
+}
+
+// Result is the outcome of a request sent by FetchAll.
+type Result[T any] struct {
+	Value T
+	Err   error
+}
+
+type config struct {
+	client      *http.Client
+	concurrency int
+	timeout     time.Duration
+}
+
+// Option configures how the requests are sent.
+type Option func(c *config)
+
+// WithClient sets the client sending the requests. By default, it is http.DefaultClient.
+func WithClient(client *http.Client) Option {
+	return func(c *config) {
+		c.client = client
+	}
+}
+
+// WithConcurrency sets the maximum number of requests sent concurrently. By default, or if n is not strictly positive, there is no limit.
+func WithConcurrency(n int) Option {
+	return func(c *config) {
+		c.concurrency = n
+	}
+}
+
+// WithRequestTimeout sets the maximum duration of each request, including the time to decode its response. By default, there is none.
+func WithRequestTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.timeout = timeout
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{client: http.DefaultClient}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// FetchAll sends every request concurrently and returns the result of each of them under the same key.
+// A response without a 2xx status code gives a *StatusError, otherwise its body is decoded by the Decoder.
+// If the context is done, the requests not sent yet fail with the error of the context.
+func FetchAll[K comparable, T any](ctx context.Context, requests map[K]*http.Request, decode Decoder[T], opts ...Option) map[K]Result[T] {
+	c := newConfig(opts)
+	keys := make([]K, 0, len(requests))
+	for key := range requests {
+		keys = append(keys, key)
+	}
+	var mutex sync.Mutex
+	results := make(map[K]Result[T], len(requests))
+	_ = async.ForEach(ctx, keys, c.concurrency, func(ctx context.Context, key K) error {
+		value, err := fetch(ctx, c, requests[key], decode)
+		mutex.Lock()
+		defer mutex.Unlock()
+		results[key] = Result[T]{Value: value, Err: err}
+		return nil
+	})
+	for _, key := range keys {
+		if _, ok := results[key]; !ok {
+			results[key] = Result[T]{Err: ctx.Err()}
+		}
+	}
+	return results
+}
+
+// Fetch sends the request in a new go-routine and returns the ErrFuture holding its decoded response.
+func Fetch[T any](ctx context.Context, request *http.Request, decode Decoder[T], opts ...Option) async.ErrFuture[T] {
+	c := newConfig(opts)
+	return async.AsyncErrWithContext(ctx, func(ctx context.Context) (T, error) {
+		return fetch(ctx, c, request, decode)
+	})
+}
+
+func fetch[T any](ctx context.Context, c *config, request *http.Request, decode Decoder[T]) (T, error) {
+	var zero T
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+	response, err := c.client.Do(request.WithContext(ctx))
+	if err != nil {
+		return zero, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(response.Body, maxErrorBody))
+		return zero, &StatusError{StatusCode: response.StatusCode, Body: body}
+	}
+	return decode(response)
+}
@@ -0,0 +1,74 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package httpx
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newRequest(t *testing.T, url string) *http.Request {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	assert.NoError(t, err)
+	return request
+}
+
+func TestFetchAll(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+			_, _ = w.Write([]byte(`{"name":"perses"}`))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			_, _ = w.Write([]byte(`{}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte("not found"))
+		}
+	}))
+	defer server.Close()
+
+	type project struct {
+		Name string `json:"name"`
+	}
+	results := FetchAll(context.Background(), map[string]*http.Request{
+		"ok":      newRequest(t, server.URL+"/ok"),
+		"missing": newRequest(t, server.URL+"/missing"),
+		"slow":    newRequest(t, server.URL+"/slow"),
+	}, JSON[project](), WithConcurrency(2), WithRequestTimeout(50*time.Millisecond))
+
+	assert.NoError(t, results["ok"].Err)
+	assert.Equal(t, "perses", results["ok"].Value.Name)
+	var statusErr *StatusError
+	assert.True(t, errors.As(results["missing"].Err, &statusErr))
+	assert.Equal(t, http.StatusNotFound, statusErr.StatusCode)
+	assert.Equal(t, "not found", string(statusErr.Body))
+	assert.ErrorIs(t, results["slow"].Err, context.DeadlineExceeded)
+}
+
+func TestFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+	defer server.Close()
+	body, err := Fetch(context.Background(), newRequest(t, server.URL), Bytes()).Await()
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(body))
+}
@@ -0,0 +1,85 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+// FileElector elects a leader among the processes running on the same host, using an exclusive lock on a file.
+// The lock is released by the operating system if the leader process dies, so another candidate is elected.
+type FileElector struct {
+	path string
+	// pollInterval is the time between two attempts to lock the file.
+	pollInterval time.Duration
+}
+
+// NewFileElector creates a FileElector locking the file at the given path. The file is created if it doesn't exist.
+// pollInterval is the time a candidate waits between two attempts to lock the file, by default it is 1 second.
+func NewFileElector(path string, pollInterval time.Duration) *FileElector {
+	if pollInterval <= 0 {
+		pollInterval = time.Second
+	}
+	return &FileElector{path: path, pollInterval: pollInterval}
+}
+
+func (e *FileElector) Campaign(ctx context.Context) (Term, error) {
+	file, err := os.OpenFile(e.path, os.O_CREATE|os.O_RDWR, 0600)
+	if err != nil {
+		return nil, err
+	}
+	ticker := time.NewTicker(e.pollInterval)
+	defer ticker.Stop()
+	for {
+		locked, lockErr := tryLock(file)
+		if lockErr != nil {
+			_ = file.Close()
+			return nil, lockErr
+		}
+		if locked {
+			return &fileTerm{file: file, lost: make(chan struct{})}, nil
+		}
+		select {
+		case <-ctx.Done():
+			_ = file.Close()
+			return nil, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+type fileTerm struct {
+	file *os.File
+	lost chan struct{}
+	once sync.Once
+}
+
+func (t *fileTerm) Lost() <-chan struct{} {
+	return t.lost
+}
+
+func (t *fileTerm) Resign() error {
+	var err error
+	t.once.Do(func() {
+		close(t.lost)
+		err = unlock(t.file)
+		if closeErr := t.file.Close(); err == nil {
+			err = closeErr
+		}
+	})
+	return err
+}
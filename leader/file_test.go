@@ -0,0 +1,46 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFileElector(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "leader.lock")
+	elector := NewFileElector(path, 10*time.Millisecond)
+	term, err := elector.Campaign(context.Background())
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = NewFileElector(path, 10*time.Millisecond).Campaign(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	elected := make(chan Term)
+	go func() {
+		other, campaignErr := NewFileElector(path, 10*time.Millisecond).Campaign(context.Background())
+		assert.NoError(t, campaignErr)
+		elected <- other
+	}()
+	assert.NoError(t, term.Resign())
+	<-term.Lost()
+	other := <-elected
+	assert.NoError(t, other.Resign())
+}
@@ -0,0 +1,30 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !(linux || darwin || freebsd || netbsd || openbsd || dragonfly)
+
+package leader
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+)
+
+func tryLock(_ *os.File) (bool, error) {
+	return false, fmt.Errorf("the file elector is not supported on %s", runtime.GOOS)
+}
+
+func unlock(_ *os.File) error {
+	return nil
+}
@@ -0,0 +1,205 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package leader provides a leader election abstraction, so a task is executed by exactly one replica of an application at a time.
+//
+//	elector := leader.NewFileElector("/var/lock/my-app.lock", 0)
+//	s := scheduler.New()
+//	if err := s.Cron("0 2 * * *", leader.RunWhenLeader(elector, myNightlyTask, leader.WithSkipWhenFollower())); err != nil {
+//		logrus.Fatal(err)
+//	}
+//
+// The package provides an Elector in-memory, for the tasks running in the same process, and an Elector based on a file lock,
+// for the replicas running on the same host. An Elector relying on etcd or on a Kubernetes lease can be plugged by implementing the interface.
+package leader
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/sirupsen/logrus"
+)
+
+// Elector elects a leader among several candidates.
+type Elector interface {
+	// Campaign blocks until the caller is elected leader or until the context is done, in which case the error of the context is returned.
+	Campaign(ctx context.Context) (Term, error)
+}
+
+// Term is the period during which a candidate is the leader.
+type Term interface {
+	// Lost returns a channel closed once the leadership is lost, either because Resign has been called or because
+	// the Elector cannot guarantee it anymore (like a lease that couldn't be renewed).
+	Lost() <-chan struct{}
+	// Resign gives up the leadership, so another candidate can be elected.
+	Resign() error
+}
+
+// Option configures the task returned by RunWhenLeader.
+type Option func(t *leaderTask)
+
+// WithSkipWhenFollower makes Execute return immediately when the replica is not the leader, instead of waiting to be elected.
+// It is the option to use for a task executed periodically, like by a scheduler.Scheduler: only the leader executes it.
+func WithSkipWhenFollower() Option {
+	return func(t *leaderTask) {
+		t.skipWhenFollower = true
+	}
+}
+
+// WithRetryDelay sets the time to wait before campaigning again when the Elector failed. By default, it is 1 second.
+func WithRetryDelay(delay time.Duration) Option {
+	return func(t *leaderTask) {
+		t.retryDelay = delay
+	}
+}
+
+type leaderTask struct {
+	async.Task
+	elector          Elector
+	task             async.SimpleTask
+	skipWhenFollower bool
+	retryDelay       time.Duration
+	// cancel stops the campaign, done is closed once it is stopped.
+	cancel context.CancelFunc
+	done   chan struct{}
+	mutex  sync.Mutex
+	// term is the current term, nil when the replica is not the leader.
+	term Term
+	// elected is closed once the replica is elected. It is replaced every time the leadership is lost.
+	elected chan struct{}
+}
+
+// RunWhenLeader returns a Task executing the given task only while the replica is the leader.
+// The replica campaigns from Initialize to Finalize, so the returned Task must be run through the package async/taskhelper,
+// the package app or a scheduler.Scheduler, which are all calling these methods.
+// By default, Execute waits for the replica to be elected. The context given to the task is cancelled when the leadership is lost,
+// then Execute returns what the task returned: wrap the Task in an async.Supervisor with async.RestartAlways to wait for the next election.
+func RunWhenLeader(elector Elector, task async.SimpleTask, opts ...Option) async.Task {
+	t := &leaderTask{
+		elector:    elector,
+		task:       task,
+		retryDelay: time.Second,
+		elected:    make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(t)
+	}
+	return t
+}
+
+func (t *leaderTask) String() string {
+	return t.task.String()
+}
+
+func (t *leaderTask) Initialize() error {
+	if task, ok := t.task.(async.Task); ok {
+		if err := task.Initialize(); err != nil {
+			return err
+		}
+	}
+	var ctx context.Context
+	ctx, t.cancel = context.WithCancel(context.Background())
+	t.done = make(chan struct{})
+	go t.campaign(ctx)
+	return nil
+}
+
+func (t *leaderTask) Finalize() error {
+	if t.cancel != nil {
+		t.cancel()
+		<-t.done
+	}
+	if task, ok := t.task.(async.Task); ok {
+		return task.Finalize()
+	}
+	return nil
+}
+
+// campaign keeps campaigning until the context is done. Once done, it resigns if the replica is the leader.
+func (t *leaderTask) campaign(ctx context.Context) {
+	defer close(t.done)
+	for {
+		term, err := t.elector.Campaign(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			logrus.WithError(err).Errorf("unable to campaign for the leadership of the task '%s'", t.String())
+			timer := time.NewTimer(t.retryDelay)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-timer.C:
+			}
+			continue
+		}
+		logrus.Debugf("elected leader of the task '%s'", t.String())
+		t.mutex.Lock()
+		t.term = term
+		close(t.elected)
+		t.mutex.Unlock()
+		select {
+		case <-term.Lost():
+			logrus.Warningf("leadership of the task '%s' lost", t.String())
+		case <-ctx.Done():
+			if resignErr := term.Resign(); resignErr != nil {
+				logrus.WithError(resignErr).Errorf("unable to resign the leadership of the task '%s'", t.String())
+			}
+		}
+		t.mutex.Lock()
+		t.term = nil
+		t.elected = make(chan struct{})
+		t.mutex.Unlock()
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func (t *leaderTask) Execute(ctx context.Context, cancelFunc context.CancelFunc) error {
+	for {
+		t.mutex.Lock()
+		term := t.term
+		elected := t.elected
+		t.mutex.Unlock()
+		if term != nil {
+			return t.execute(ctx, cancelFunc, term)
+		}
+		if t.skipWhenFollower {
+			logrus.Debugf("task '%s' skipped, the replica is not the leader", t.String())
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-elected:
+		}
+	}
+}
+
+// execute executes the task with a context cancelled once the term is lost.
+func (t *leaderTask) execute(ctx context.Context, cancelFunc context.CancelFunc, term Term) error {
+	taskCtx, cancelTask := context.WithCancel(ctx)
+	defer cancelTask()
+	go func() {
+		select {
+		case <-term.Lost():
+			cancelTask()
+		case <-taskCtx.Done():
+		}
+	}()
+	return t.task.Execute(taskCtx, cancelFunc)
+}
@@ -0,0 +1,101 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/perses/common/async"
+	"github.com/stretchr/testify/assert"
+)
+
+type blockingTask struct {
+	async.SimpleTask
+	started chan struct{}
+}
+
+func (b *blockingTask) String() string {
+	return "blocking task"
+}
+
+func (b *blockingTask) Execute(ctx context.Context, _ context.CancelFunc) error {
+	b.started <- struct{}{}
+	<-ctx.Done()
+	return nil
+}
+
+func TestRunWhenLeader(t *testing.T) {
+	elector := NewMemoryElector()
+	firstTask := &blockingTask{started: make(chan struct{}, 1)}
+	secondTask := &blockingTask{started: make(chan struct{}, 1)}
+	first := RunWhenLeader(elector, firstTask)
+	second := RunWhenLeader(elector, secondTask)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.NoError(t, first.Initialize())
+	firstDone := make(chan error)
+	go func() { firstDone <- first.Execute(ctx, cancel) }()
+	<-firstTask.started
+
+	assert.NoError(t, second.Initialize())
+	secondDone := make(chan error)
+	go func() { secondDone <- second.Execute(ctx, cancel) }()
+	select {
+	case <-secondTask.started:
+		t.Fatal("the second task must not be executed while the first one is the leader")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// once the first replica stops, it resigns and the second one is elected
+	assert.NoError(t, first.Finalize())
+	assert.NoError(t, <-firstDone)
+	<-secondTask.started
+	cancel()
+	assert.NoError(t, <-secondDone)
+	assert.NoError(t, second.Finalize())
+}
+
+func TestRunWhenLeader_SkipWhenFollower(t *testing.T) {
+	elector := NewMemoryElector()
+	term, err := elector.Campaign(context.Background())
+	assert.NoError(t, err)
+	task := &blockingTask{started: make(chan struct{}, 1)}
+	follower := RunWhenLeader(elector, task, WithSkipWhenFollower())
+	assert.NoError(t, follower.Initialize())
+	defer follower.Finalize()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	assert.NoError(t, follower.Execute(ctx, cancel))
+	assert.Empty(t, task.started)
+
+	// the leadership is given to the follower, it now executes the task until the leadership is lost again
+	assert.NoError(t, term.Resign())
+	lt := follower.(*leaderTask)
+	assert.Eventually(t, func() bool {
+		lt.mutex.Lock()
+		defer lt.mutex.Unlock()
+		return lt.term != nil
+	}, time.Second, 10*time.Millisecond)
+	done := make(chan error)
+	go func() { done <- follower.Execute(ctx, cancel) }()
+	<-task.started
+	lt.mutex.Lock()
+	current := lt.term
+	lt.mutex.Unlock()
+	assert.NoError(t, current.Resign())
+	assert.NoError(t, <-done)
+}
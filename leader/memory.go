@@ -0,0 +1,56 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package leader
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryElector elects a leader among the candidates of the same process. It is mostly useful in tests.
+type MemoryElector struct {
+	// token is held by the leader.
+	token chan struct{}
+}
+
+func NewMemoryElector() *MemoryElector {
+	return &MemoryElector{token: make(chan struct{}, 1)}
+}
+
+func (e *MemoryElector) Campaign(ctx context.Context) (Term, error) {
+	select {
+	case e.token <- struct{}{}:
+		return &memoryTerm{elector: e, lost: make(chan struct{})}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+type memoryTerm struct {
+	elector *MemoryElector
+	lost    chan struct{}
+	once    sync.Once
+}
+
+func (t *memoryTerm) Lost() <-chan struct{} {
+	return t.lost
+}
+
+func (t *memoryTerm) Resign() error {
+	t.once.Do(func() {
+		close(t.lost)
+		<-t.elector.token
+	})
+	return nil
+}
@@ -0,0 +1,247 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package lock provides a distributed lock abstraction, so tasks running in different processes can guard a shared resource.
+//
+// A lock is a lease stored in a Store with a time-to-live. While the lock is held, a go-routine renews the lease,
+// so a process that dies without releasing its lock doesn't block the others for longer than the time-to-live.
+//
+//	locker := lock.NewLocker(store)
+//	l, err := locker.Acquire(ctx, "reindex", 30*time.Second)
+//	if err != nil {
+//		return err
+//	}
+//	defer l.Release(context.Background())
+//	select {
+//	case <-l.Lost():
+//		// the lease couldn't be renewed, another process may now hold the lock
+//	case <-reindex(ctx):
+//	}
+//
+// The package ships a Store in-memory, for the tasks running in the same process. A Store relying on etcd or on a database
+// can be plugged by implementing the interface, the renewal being handled by the Locker.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/perses/common/clock"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrNotHeld is returned by a Store when the lease is not held by the owner anymore.
+var ErrNotHeld = errors.New("lock is not held")
+
+// Locker acquires locks.
+type Locker interface {
+	// Acquire blocks until the lock of the key is acquired or until the context is done, in which case the error of the context is returned.
+	// The lease of the lock lasts ttl and is renewed until the lock is released. ttl must be strictly positive.
+	Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error)
+}
+
+// Lock is a lock held.
+type Lock interface {
+	Key() string
+	// Lost returns a channel closed once the lock is not held anymore, either because Release has been called or because the lease couldn't be renewed.
+	Lost() <-chan struct{}
+	// Release releases the lock, so another process can acquire it.
+	Release(ctx context.Context) error
+}
+
+// Store stores the leases of the locks. Its methods must be atomic, as they are called concurrently by several processes.
+type Store interface {
+	// TryAcquire sets the lease of the key for the owner if nobody else holds a lease not expired. It returns false if somebody else holds it.
+	TryAcquire(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error)
+	// Renew extends the lease of the key held by the owner. It returns ErrNotHeld if the owner doesn't hold it anymore.
+	Renew(ctx context.Context, key string, owner string, ttl time.Duration) error
+	// Release removes the lease of the key held by the owner. It returns ErrNotHeld if the owner doesn't hold it anymore.
+	Release(ctx context.Context, key string, owner string) error
+}
+
+// Option configures a Locker.
+type Option func(l *locker)
+
+// WithRetryInterval sets the time to wait between two attempts to acquire a lock held by somebody else. By default, it is 100ms.
+func WithRetryInterval(interval time.Duration) Option {
+	return func(l *locker) {
+		if interval > 0 {
+			l.retryInterval = interval
+		}
+	}
+}
+
+// WithClock sets the Clock used to wait between two attempts and to renew the leases. By default, it is clock.Real.
+func WithClock(c clock.Clock) Option {
+	return func(l *locker) {
+		l.clock = clock.OrReal(c)
+	}
+}
+
+type locker struct {
+	store         Store
+	retryInterval time.Duration
+	clock         clock.Clock
+}
+
+// NewLocker creates a Locker storing the leases in the given Store.
+// The leases are renewed every third of their time-to-live. If they cannot be renewed before they expire, the locks are lost.
+func NewLocker(store Store, opts ...Option) Locker {
+	l := &locker{
+		store:         store,
+		retryInterval: 100 * time.Millisecond,
+		clock:         clock.Real,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// NewLocalLocker creates a Locker for the tasks running in the same process.
+func NewLocalLocker(opts ...Option) Locker {
+	l := NewLocker(nil, opts...).(*locker)
+	l.store = NewMemoryStore(l.clock)
+	return l
+}
+
+func (l *locker) Acquire(ctx context.Context, key string, ttl time.Duration) (Lock, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("the time-to-live of the lock '%s' must be strictly positive", key)
+	}
+	owner, err := newOwner()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		acquired, err := l.store.TryAcquire(ctx, key, owner, ttl)
+		if err != nil {
+			return nil, err
+		}
+		if acquired {
+			return l.newLock(key, owner, ttl), nil
+		}
+		timer := l.clock.NewTimer(l.retryInterval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C():
+		}
+	}
+}
+
+// newOwner returns a random identifier of the owner of a lock.
+func newOwner() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type heldLock struct {
+	locker *locker
+	key    string
+	owner  string
+	ttl    time.Duration
+	lost   chan struct{}
+	once   sync.Once
+	// released guarantees the lock is released only once.
+	released sync.Once
+	// stop stops the renewal, done is closed once the renewal is stopped.
+	stop chan struct{}
+	done chan struct{}
+}
+
+func (l *locker) newLock(key string, owner string, ttl time.Duration) *heldLock {
+	lk := &heldLock{
+		locker: l,
+		key:    key,
+		owner:  owner,
+		ttl:    ttl,
+		lost:   make(chan struct{}),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+	}
+	go lk.renew()
+	return lk
+}
+
+func (l *heldLock) Key() string {
+	return l.key
+}
+
+func (l *heldLock) Lost() <-chan struct{} {
+	return l.lost
+}
+
+func (l *heldLock) markLost() {
+	l.once.Do(func() {
+		close(l.lost)
+	})
+}
+
+// renew renews the lease every third of its time-to-live, until the lock is released or the lease expired.
+func (l *heldLock) renew() {
+	defer close(l.done)
+	interval := l.ttl / 3
+	if interval <= 0 {
+		// the time-to-live is too short to be split, the ticker requires a strictly positive interval.
+		interval = l.ttl
+	}
+	ticker := l.locker.clock.NewTicker(interval)
+	defer ticker.Stop()
+	lastRenewal := l.locker.clock.Now()
+	for {
+		select {
+		case <-l.stop:
+			return
+		case <-ticker.C():
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		err := l.locker.store.Renew(ctx, l.key, l.owner, l.ttl)
+		cancel()
+		if err == nil {
+			lastRenewal = l.locker.clock.Now()
+			continue
+		}
+		if errors.Is(err, ErrNotHeld) || l.locker.clock.Since(lastRenewal) >= l.ttl {
+			logrus.WithError(err).Errorf("lock '%s' lost, its lease couldn't be renewed", l.key)
+			l.markLost()
+			return
+		}
+		logrus.WithError(err).Warningf("unable to renew the lease of the lock '%s', it will be retried", l.key)
+	}
+}
+
+func (l *heldLock) Release(ctx context.Context) (err error) {
+	l.released.Do(func() {
+		close(l.stop)
+		<-l.done
+		select {
+		case <-l.lost:
+			// the lease couldn't be renewed, there is nothing to release anymore.
+			return
+		default:
+		}
+		l.markLost()
+		err = l.locker.store.Release(ctx, l.key, l.owner)
+	})
+	return err
+}
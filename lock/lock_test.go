@@ -0,0 +1,82 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const ttl = 60 * time.Millisecond
+
+func TestLocker(t *testing.T) {
+	locker := NewLocalLocker(WithRetryInterval(5 * time.Millisecond))
+	l, err := locker.Acquire(context.Background(), "key", ttl)
+	assert.NoError(t, err)
+	assert.Equal(t, "key", l.Key())
+
+	// the lease is renewed, so the lock is still held long after its time-to-live
+	ctx, cancel := context.WithTimeout(context.Background(), 3*ttl)
+	defer cancel()
+	_, err = locker.Acquire(ctx, "key", ttl)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	// the other keys are not locked
+	other, err := locker.Acquire(context.Background(), "other", ttl)
+	assert.NoError(t, err)
+	assert.NoError(t, other.Release(context.Background()))
+
+	acquired := make(chan Lock)
+	go func() {
+		next, acquireErr := locker.Acquire(context.Background(), "key", ttl)
+		assert.NoError(t, acquireErr)
+		acquired <- next
+	}()
+	assert.NoError(t, l.Release(context.Background()))
+	<-l.Lost()
+	// releasing twice has no effect
+	assert.NoError(t, l.Release(context.Background()))
+	next := <-acquired
+	assert.NoError(t, next.Release(context.Background()))
+}
+
+func TestLocker_InvalidTTL(t *testing.T) {
+	locker := NewLocalLocker()
+	_, err := locker.Acquire(context.Background(), "key", 0)
+	assert.Error(t, err)
+	_, err = locker.Acquire(context.Background(), "key", -time.Second)
+	assert.Error(t, err)
+	// a time-to-live too short to be split in three doesn't crash the renewal
+	l, err := locker.Acquire(context.Background(), "key", time.Nanosecond)
+	assert.NoError(t, err)
+	_ = l.Release(context.Background())
+}
+
+func TestLocker_Lost(t *testing.T) {
+	store := NewMemoryStore(nil)
+	l, err := NewLocker(store).Acquire(context.Background(), "key", ttl)
+	assert.NoError(t, err)
+	// simulate the lease being taken over by somebody else
+	store.mutex.Lock()
+	store.leases["key"] = lease{owner: "somebody else", expiry: time.Now().Add(time.Hour)}
+	store.mutex.Unlock()
+	select {
+	case <-l.Lost():
+	case <-time.After(time.Second):
+		t.Fatal("the lock should have been lost")
+	}
+	assert.NoError(t, l.Release(context.Background()))
+}
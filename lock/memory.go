@@ -0,0 +1,76 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/perses/common/clock"
+)
+
+type lease struct {
+	owner  string
+	expiry time.Time
+}
+
+// MemoryStore is a Store keeping the leases in memory, so the locks are shared by the tasks of the same process only.
+type MemoryStore struct {
+	clock  clock.Clock
+	mutex  sync.Mutex
+	leases map[string]lease
+}
+
+// NewMemoryStore creates a MemoryStore using the given Clock to know when the leases expire. If c is nil, clock.Real is used.
+func NewMemoryStore(c clock.Clock) *MemoryStore {
+	return &MemoryStore{
+		clock:  clock.OrReal(c),
+		leases: make(map[string]lease),
+	}
+}
+
+func (s *MemoryStore) TryAcquire(_ context.Context, key string, owner string, ttl time.Duration) (bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := s.clock.Now()
+	if l, ok := s.leases[key]; ok && l.owner != owner && now.Before(l.expiry) {
+		return false, nil
+	}
+	s.leases[key] = lease{owner: owner, expiry: now.Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryStore) Renew(_ context.Context, key string, owner string, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := s.clock.Now()
+	l, ok := s.leases[key]
+	if !ok || l.owner != owner || !now.Before(l.expiry) {
+		return ErrNotHeld
+	}
+	s.leases[key] = lease{owner: owner, expiry: now.Add(ttl)}
+	return nil
+}
+
+func (s *MemoryStore) Release(_ context.Context, key string, owner string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	l, ok := s.leases[key]
+	if !ok || l.owner != owner {
+		return ErrNotHeld
+	}
+	delete(s.leases, key)
+	return nil
+}
@@ -0,0 +1,46 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/perses/common/async/asynctest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryStore(t *testing.T) {
+	c := asynctest.NewFakeClock(time.Now())
+	store := NewMemoryStore(c)
+	ctx := context.Background()
+	acquired, err := store.TryAcquire(ctx, "key", "a", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	acquired, err = store.TryAcquire(ctx, "key", "b", time.Minute)
+	assert.NoError(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, ErrNotHeld, store.Renew(ctx, "key", "b", time.Minute))
+	assert.Equal(t, ErrNotHeld, store.Release(ctx, "key", "b"))
+	assert.NoError(t, store.Renew(ctx, "key", "a", time.Minute))
+
+	// once the lease is expired, somebody else can acquire it
+	c.Advance(2 * time.Minute)
+	assert.Equal(t, ErrNotHeld, store.Renew(ctx, "key", "a", time.Minute))
+	acquired, err = store.TryAcquire(ctx, "key", "b", time.Minute)
+	assert.NoError(t, err)
+	assert.True(t, acquired)
+	assert.NoError(t, store.Release(ctx, "key", "b"))
+}
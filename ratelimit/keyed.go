@@ -0,0 +1,118 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/perses/common/async"
+)
+
+type keyedEntry struct {
+	limiter Limiter
+	// lastUsed is the last time the limiter has been used.
+	lastUsed time.Time
+}
+
+// Keyed maintains a Limiter per key, like a tenant or an IP address. The limiter of a key is created the first time the key is used,
+// and evicted once it has not been used for the idle duration, so the keys seen only once don't leak.
+//
+//	limiters := ratelimit.NewKeyed(10*time.Minute, func(tenant string) ratelimit.Limiter {
+//		return ratelimit.NewTokenBucket(100, 20)
+//	})
+//	app.NewRunner().WithCronTasks(time.Minute, limiters.Janitor()).Start()
+//	...
+//	if !limiters.Allow(tenant) {
+//		return echo.ErrTooManyRequests
+//	}
+//
+// A limiter evicted and created again starts from scratch, so the idle duration must be longer than the time a limiter needs to recover.
+type Keyed[K comparable] struct {
+	mutex      sync.Mutex
+	idle       time.Duration
+	newLimiter func(key K) Limiter
+	limiters   map[K]*keyedEntry
+}
+
+// NewKeyed creates a Keyed creating the limiter of each key with newLimiter, and evicting it once it has not been used for the idle duration.
+func NewKeyed[K comparable](idle time.Duration, newLimiter func(key K) Limiter) *Keyed[K] {
+	return &Keyed[K]{
+		idle:       idle,
+		newLimiter: newLimiter,
+		limiters:   make(map[K]*keyedEntry),
+	}
+}
+
+// limiter returns the limiter of the key, creating it if needed.
+func (k *Keyed[K]) limiter(key K) Limiter {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	entry, ok := k.limiters[key]
+	if !ok {
+		entry = &keyedEntry{limiter: k.newLimiter(key)}
+		k.limiters[key] = entry
+	}
+	entry.lastUsed = time.Now()
+	return entry.limiter
+}
+
+// Allow returns true if an event can happen now for the given key. It never blocks.
+func (k *Keyed[K]) Allow(key K) bool {
+	return k.limiter(key).Allow()
+}
+
+// Wait blocks until an event can happen for the given key. It returns an error if the context is done before.
+func (k *Keyed[K]) Wait(ctx context.Context, key K) error {
+	return k.limiter(key).Wait(ctx)
+}
+
+// Len returns the number of limiters currently maintained.
+func (k *Keyed[K]) Len() int {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	return len(k.limiters)
+}
+
+// Evict removes the limiters not used for the idle duration.
+func (k *Keyed[K]) Evict() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	now := time.Now()
+	for key, entry := range k.limiters {
+		if now.Sub(entry.lastUsed) > k.idle {
+			delete(k.limiters, key)
+		}
+	}
+}
+
+// Janitor returns a task calling Evict each time it is executed. It is meant to be run periodically, with app.Runner.WithCronTasks for example.
+func (k *Keyed[K]) Janitor() async.SimpleTask {
+	return &janitor{evict: k.Evict}
+}
+
+// janitor is the task evicting the idle limiters of a Keyed.
+type janitor struct {
+	evict func()
+}
+
+func (j *janitor) String() string {
+	return "keyed rate limiters janitor"
+}
+
+func (j *janitor) Execute(_ context.Context, _ context.CancelFunc) error {
+	j.evict()
+	return nil
+}
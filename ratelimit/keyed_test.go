@@ -0,0 +1,42 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyed(t *testing.T) {
+	created := 0
+	limiters := NewKeyed(20*time.Millisecond, func(_ string) Limiter {
+		created++
+		return NewTokenBucket(1, 1)
+	})
+	assert.True(t, limiters.Allow("a"))
+	assert.False(t, limiters.Allow("a"))
+	// each key has its own limiter
+	assert.True(t, limiters.Allow("b"))
+	assert.Equal(t, 2, limiters.Len())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, limiters.Janitor().Execute(context.Background(), func() {}))
+	assert.Equal(t, 0, limiters.Len())
+	// the limiter evicted is created again from scratch
+	assert.True(t, limiters.Allow("a"))
+	assert.Equal(t, 3, created)
+}
@@ -0,0 +1,191 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ratelimit provides rate limiters sharing the same semantic:
+// Allow never blocks and tells if an event can happen now, Wait blocks until an event can happen or the context is done.
+//
+// Both limiters implement async.RateLimiter, so they can be used to throttle the jobs of an async.Pool:
+//
+//	pool := async.NewPool(10, 100, async.WithRateLimiter(ratelimit.NewTokenBucket(50, 10)))
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// minRate is the rate used instead of a rate not strictly positive: one event per day, the limiter is almost closed.
+const minRate = 1.0 / (24 * 60 * 60)
+
+// validRate returns the rate, raised to minRate if it is not strictly positive, so the delays computed from it stay finite.
+func validRate(rate float64) float64 {
+	if rate < minRate {
+		return minRate
+	}
+	return rate
+}
+
+// ErrLimitExceeded is returned by Wait when waiting is useless: the event would happen after the deadline of the context, or the queue of a LeakyBucket is full.
+var ErrLimitExceeded = errors.New("rate limit exceeded")
+
+// Limiter is the interface implemented by every rate limiter of this package.
+type Limiter interface {
+	// Allow returns true if an event can happen now. It never blocks.
+	Allow() bool
+	// Wait blocks until an event can happen. It returns an error if the context is done before.
+	Wait(ctx context.Context) error
+}
+
+// sleep waits until the given time or until the context is done.
+// If the context has a deadline before the given time, it returns ErrLimitExceeded immediately.
+func sleep(ctx context.Context, until time.Time) error {
+	delay := time.Until(until)
+	if delay <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(until) {
+		return fmt.Errorf("%w: waiting %s would exceed the deadline of the context", ErrLimitExceeded, delay)
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// TokenBucket is a limiter that allows events at a rate of r per second, with bursts of at most b events.
+// The bucket is filled with r tokens per second up to b tokens, and each event consumes one token.
+type TokenBucket struct {
+	mutex  sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+// NewTokenBucket creates a full TokenBucket allowing rate events per second with bursts of at most burst events.
+// A rate lower than one event per day, including a rate not strictly positive, is raised to one event per day.
+func NewTokenBucket(rate float64, burst int) *TokenBucket {
+	if burst < 1 {
+		burst = 1
+	}
+	return &TokenBucket{
+		rate:   validRate(rate),
+		burst:  float64(burst),
+		tokens: float64(burst),
+		last:   time.Now(),
+	}
+}
+
+// refill adds the tokens earned since the last call. The tokens can be negative when some are reserved by Wait.
+func (t *TokenBucket) refill(now time.Time) {
+	t.tokens += now.Sub(t.last).Seconds() * t.rate
+	if t.tokens > t.burst {
+		t.tokens = t.burst
+	}
+	t.last = now
+}
+
+func (t *TokenBucket) Allow() bool {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.refill(time.Now())
+	if t.tokens < 1 {
+		return false
+	}
+	t.tokens--
+	return true
+}
+
+func (t *TokenBucket) Wait(ctx context.Context) error {
+	t.mutex.Lock()
+	now := time.Now()
+	t.refill(now)
+	// the token is reserved right now, so the waiters are served in order.
+	t.tokens--
+	var until time.Time
+	if t.tokens < 0 {
+		until = now.Add(time.Duration(-t.tokens / t.rate * float64(time.Second)))
+	}
+	t.mutex.Unlock()
+	if err := sleep(ctx, until); err != nil {
+		// give back the token reserved since it won't be used
+		t.mutex.Lock()
+		t.tokens++
+		t.mutex.Unlock()
+		return err
+	}
+	return nil
+}
+
+// LeakyBucket is a limiter that spaces the events evenly at a rate of r per second, without any burst.
+// At most capacity events can wait for their turn, once reached Wait returns ErrLimitExceeded.
+type LeakyBucket struct {
+	mutex    sync.Mutex
+	interval time.Duration
+	capacity int
+	waiting  int
+	// next is the time the next event can happen.
+	next time.Time
+}
+
+// NewLeakyBucket creates a LeakyBucket allowing rate events per second, with at most capacity events waiting.
+// A rate lower than one event per day, including a rate not strictly positive, is raised to one event per day.
+func NewLeakyBucket(rate float64, capacity int) *LeakyBucket {
+	return &LeakyBucket{
+		interval: time.Duration(float64(time.Second) / validRate(rate)),
+		capacity: capacity,
+	}
+}
+
+func (l *LeakyBucket) Allow() bool {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	now := time.Now()
+	if l.next.After(now) {
+		return false
+	}
+	l.next = now.Add(l.interval)
+	return true
+}
+
+func (l *LeakyBucket) Wait(ctx context.Context) error {
+	l.mutex.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	if l.next.After(now) && l.waiting >= l.capacity {
+		l.mutex.Unlock()
+		return fmt.Errorf("%w: %d events are already waiting", ErrLimitExceeded, l.waiting)
+	}
+	until := l.next
+	l.next = l.next.Add(l.interval)
+	l.waiting++
+	l.mutex.Unlock()
+	err := sleep(ctx, until)
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.waiting--
+	if err != nil && l.next.Equal(until.Add(l.interval)) {
+		// this event was the last one scheduled, its slot can be given back.
+		l.next = until
+	}
+	return err
+}
@@ -0,0 +1,74 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTokenBucket_Allow(t *testing.T) {
+	limiter := NewTokenBucket(10, 3)
+	for i := 0; i < 3; i++ {
+		assert.True(t, limiter.Allow())
+	}
+	assert.False(t, limiter.Allow())
+	time.Sleep(110 * time.Millisecond)
+	assert.True(t, limiter.Allow())
+}
+
+func TestTokenBucket_Wait(t *testing.T) {
+	limiter := NewTokenBucket(100, 1)
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		assert.NoError(t, limiter.Wait(context.Background()))
+	}
+	// the first event consumes the burst, the 4 others have to wait 10ms each.
+	assert.GreaterOrEqual(t, time.Since(start), 35*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	slow := NewTokenBucket(0.1, 1)
+	assert.True(t, slow.Allow())
+	assert.True(t, errors.Is(slow.Wait(ctx), ErrLimitExceeded))
+}
+
+func TestInvalidRate(t *testing.T) {
+	for _, rate := range []float64{0, -1} {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		tokens := NewTokenBucket(rate, 1)
+		assert.True(t, tokens.Allow())
+		// the bucket is almost never refilled, so Wait must not return right away
+		assert.True(t, errors.Is(tokens.Wait(ctx), ErrLimitExceeded))
+		leaky := NewLeakyBucket(rate, 1)
+		assert.True(t, leaky.Allow())
+		assert.False(t, leaky.Allow())
+		assert.True(t, errors.Is(leaky.Wait(ctx), ErrLimitExceeded))
+		cancel()
+	}
+}
+
+func TestLeakyBucket(t *testing.T) {
+	limiter := NewLeakyBucket(100, 2)
+	assert.True(t, limiter.Allow())
+	assert.False(t, limiter.Allow())
+	start := time.Now()
+	assert.NoError(t, limiter.Wait(context.Background()))
+	assert.NoError(t, limiter.Wait(context.Background()))
+	assert.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+}
@@ -0,0 +1,145 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff computes the time to wait before the next attempt.
+type Backoff interface {
+	// Next returns the delay to wait after the given attempt failed. attempt starts at 1.
+	Next(attempt int) time.Duration
+}
+
+type constant struct {
+	delay time.Duration
+}
+
+// Constant returns a Backoff waiting always the same delay between two attempts.
+func Constant(delay time.Duration) Backoff {
+	return &constant{delay: delay}
+}
+
+func (c *constant) Next(_ int) time.Duration {
+	return c.delay
+}
+
+type exponential struct {
+	initial    time.Duration
+	max        time.Duration
+	multiplier float64
+}
+
+// Exponential returns a Backoff multiplying the delay by multiplier after each attempt, starting at initial and never exceeding max.
+// If multiplier is lower or equal to 1, it is set to 2.
+func Exponential(initial time.Duration, max time.Duration, multiplier float64) Backoff {
+	if multiplier <= 1 {
+		multiplier = 2
+	}
+	return &exponential{
+		initial:    initial,
+		max:        max,
+		multiplier: multiplier,
+	}
+}
+
+func (e *exponential) Next(attempt int) time.Duration {
+	delay := float64(e.initial) * math.Pow(e.multiplier, float64(attempt-1))
+	if delay > float64(e.max) {
+		return e.max
+	}
+	return time.Duration(delay)
+}
+
+type jitter struct {
+	backoff Backoff
+	factor  float64
+}
+
+// Jitter randomizes the delay of the given Backoff by +/- factor (between 0 and 1).
+// For example a factor of 0.2 gives a delay between 80% and 120% of the original one.
+// It avoids many clients retrying at the exact same time.
+func Jitter(backoff Backoff, factor float64) Backoff {
+	if factor < 0 {
+		factor = 0
+	} else if factor > 1 {
+		factor = 1
+	}
+	return &jitter{backoff: backoff, factor: factor}
+}
+
+func (j *jitter) Next(attempt int) time.Duration {
+	delay := float64(j.backoff.Next(attempt))
+	// rand.Float64 is in [0,1), so the delta is in [-factor, factor)
+	delta := (rand.Float64()*2 - 1) * j.factor
+	return time.Duration(delay * (1 + delta))
+}
+
+type fullJitter struct {
+	backoff Backoff
+}
+
+// FullJitter randomizes the delay of the given Backoff between 0 and the original delay.
+// It spreads the retries of many clients much more than Jitter, at the price of sometimes retrying right away.
+//
+//	retry.WithBackoff(retry.FullJitter(retry.Exponential(100*time.Millisecond, 10*time.Second, 2)))
+func FullJitter(backoff Backoff) Backoff {
+	return &fullJitter{backoff: backoff}
+}
+
+func (f *fullJitter) Next(attempt int) time.Duration {
+	return time.Duration(rand.Float64() * float64(f.backoff.Next(attempt)))
+}
+
+// StatefulBackoff is a Backoff whose delays depend on the previous ones, so it cannot be shared by several sequences of attempts.
+// Do and DoValue call Fresh once per call, to get an instance dedicated to their attempts.
+type StatefulBackoff interface {
+	Backoff
+	// Fresh returns a new instance of the Backoff, without any previous delay.
+	Fresh() Backoff
+}
+
+type decorrelatedJitter struct {
+	initial time.Duration
+	max     time.Duration
+	// previous is the last delay returned.
+	previous time.Duration
+}
+
+// DecorrelatedJitter returns a Backoff picking each delay randomly between initial and three times the previous delay, never exceeding max.
+// The delays grow like an exponential backoff, but the retries of many clients are not synchronized.
+// It is a StatefulBackoff.
+func DecorrelatedJitter(initial time.Duration, max time.Duration) Backoff {
+	return &decorrelatedJitter{initial: initial, max: max}
+}
+
+func (d *decorrelatedJitter) Fresh() Backoff {
+	return &decorrelatedJitter{initial: d.initial, max: d.max}
+}
+
+func (d *decorrelatedJitter) Next(attempt int) time.Duration {
+	if attempt <= 1 || d.previous < d.initial {
+		d.previous = d.initial
+	}
+	upper := float64(d.previous) * 3
+	delay := float64(d.initial) + rand.Float64()*(upper-float64(d.initial))
+	if delay > float64(d.max) {
+		delay = float64(d.max)
+	}
+	d.previous = time.Duration(delay)
+	return d.previous
+}
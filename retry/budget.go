@@ -0,0 +1,93 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrBudgetExhausted is reported by errors.Is for the error of a function not retried because the retry Budget is exhausted.
+// The error still wraps the last error returned by the function.
+var ErrBudgetExhausted = errors.New("retry budget exhausted")
+
+// Budget caps the ratio of retries to successful calls, shared by every call site using it, so the retries cannot amplify an outage.
+// It is a token bucket: each successful call deposits ratio tokens, each retry withdraws one. When there is no token left, the functions are not retried anymore.
+//
+//	budget := retry.NewBudget(0.1, 10)
+//	err := retry.Do(ctx, f, retry.WithBudget(budget))
+//
+// With a ratio of 0.1, there is at most one retry for ten successful calls, plus the tokens available at the beginning.
+// A Budget is safe for concurrent use.
+type Budget struct {
+	mutex     sync.Mutex
+	ratio     float64
+	maxTokens float64
+	tokens    float64
+}
+
+// NewBudget creates a Budget depositing ratio tokens for each successful call and holding at most maxTokens tokens.
+// It starts full, so a few retries are possible before any call succeeded.
+func NewBudget(ratio float64, maxTokens float64) *Budget {
+	if maxTokens < 1 {
+		maxTokens = 1
+	}
+	return &Budget{ratio: ratio, maxTokens: maxTokens, tokens: maxTokens}
+}
+
+// Deposit records a successful call. It is called by Do and DoValue.
+func (b *Budget) Deposit() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > b.maxTokens {
+		b.tokens = b.maxTokens
+	}
+}
+
+// Withdraw takes the token required to retry a call. It returns false if there is none left. It is called by Do and DoValue.
+func (b *Budget) Withdraw() bool {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// Tokens returns the number of tokens available.
+func (b *Budget) Tokens() float64 {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	return b.tokens
+}
+
+type budgetError struct {
+	attempts int
+	err      error
+}
+
+func (e *budgetError) Error() string {
+	return fmt.Sprintf("giving up after %d attempts, the retry budget is exhausted: %s", e.attempts, e.err)
+}
+
+func (e *budgetError) Unwrap() error {
+	return e.err
+}
+
+func (e *budgetError) Is(target error) bool {
+	return target == ErrBudgetExhausted
+}
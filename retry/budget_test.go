@@ -0,0 +1,61 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBudget(t *testing.T) {
+	budget := NewBudget(0.5, 2)
+	failing := func(_ context.Context) error {
+		return errTransient
+	}
+	opts := []Option{WithBudget(budget), WithBackoff(Constant(time.Millisecond)), WithMaxAttempts(10)}
+	// the two tokens available at the beginning allow two retries
+	err := Do(context.Background(), failing, opts...)
+	assert.ErrorIs(t, err, ErrBudgetExhausted)
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, float64(0), budget.Tokens())
+
+	// the budget is shared: the next call is not retried at all
+	attempts := 0
+	err = Do(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return failing(ctx)
+	}, opts...)
+	assert.ErrorIs(t, err, ErrBudgetExhausted)
+	assert.Equal(t, 1, attempts)
+
+	// two successful calls give back a token
+	for i := 0; i < 2; i++ {
+		assert.NoError(t, Do(context.Background(), func(_ context.Context) error { return nil }, opts...))
+	}
+	assert.Equal(t, float64(1), budget.Tokens())
+}
+
+func TestBudget_MaxElapsedTime(t *testing.T) {
+	budget := NewBudget(0.5, 2)
+	err := Do(context.Background(), func(_ context.Context) error {
+		return errTransient
+	}, WithBudget(budget), WithBackoff(Constant(time.Hour)), WithMaxElapsedTime(time.Minute))
+	assert.ErrorIs(t, err, errTransient)
+	assert.NotErrorIs(t, err, ErrBudgetExhausted)
+	// the call gave up because of the maximum elapsed time, no token has been taken
+	assert.Equal(t, float64(2), budget.Tokens())
+}
@@ -0,0 +1,205 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package retry provides a way to call a function several times until it succeeds, waiting between two attempts according to a Backoff.
+//
+//	err := retry.Do(ctx, func(ctx context.Context) error {
+//		return client.Ping(ctx)
+//	}, retry.WithMaxAttempts(5), retry.WithBackoff(retry.Jitter(retry.Exponential(100*time.Millisecond, 5*time.Second, 2), 0.2)))
+//
+// It integrates with the package async by wrapping the function given to a future, so the future retries its work transparently:
+//
+//	future := async.AsyncErrWithContext(ctx, retry.Wrap(func(ctx context.Context) (*Response, error) {
+//		return client.Get(ctx, id)
+//	}, retry.WithMaxAttempts(3)))
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/perses/common/clock"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultInitial     = 100 * time.Millisecond
+	defaultMax         = 10 * time.Second
+)
+
+type config struct {
+	maxAttempts int
+	backoff     Backoff
+	retryIf     func(err error) bool
+	onRetry     func(attempt int, err error, delay time.Duration)
+	clock       clock.Clock
+	// maxElapsed is the maximum time spent retrying, 0 if there is no limit.
+	maxElapsed time.Duration
+	budget     *Budget
+}
+
+// Option configures how the function is retried.
+type Option func(c *config)
+
+// WithMaxAttempts sets the maximum number of calls of the function, including the first one. By default, it is 3.
+// A value lower or equal to 0 means there is no limit, the function is retried until it succeeds or the context is done.
+func WithMaxAttempts(maxAttempts int) Option {
+	return func(c *config) {
+		c.maxAttempts = maxAttempts
+	}
+}
+
+// WithBackoff sets the Backoff used to compute the delay between two attempts.
+// By default, it is an exponential backoff starting at 100ms with a maximum of 10s.
+func WithBackoff(backoff Backoff) Option {
+	return func(c *config) {
+		c.backoff = backoff
+	}
+}
+
+// WithMaxElapsedTime stops retrying once the given duration has elapsed since the first attempt, or would have elapsed after waiting for the next one.
+// By default, there is no limit.
+func WithMaxElapsedTime(maxElapsed time.Duration) Option {
+	return func(c *config) {
+		c.maxElapsed = maxElapsed
+	}
+}
+
+// WithBudget shares the given Budget with the other calls using it: a function is retried only if the budget has a token left,
+// and each successful call refills it. By default, there is no budget.
+func WithBudget(budget *Budget) Option {
+	return func(c *config) {
+		c.budget = budget
+	}
+}
+
+// WithRetryIf sets the predicate deciding if an error must be retried. By default, every error is retried.
+func WithRetryIf(retryIf func(err error) bool) Option {
+	return func(c *config) {
+		c.retryIf = retryIf
+	}
+}
+
+// WithOnRetry sets a function called each time an attempt failed and is going to be retried after the given delay.
+// It is the hook to log the retries, for example with an async.Logger.
+func WithOnRetry(onRetry func(attempt int, err error, delay time.Duration)) Option {
+	return func(c *config) {
+		c.onRetry = onRetry
+	}
+}
+
+// WithClock sets the Clock used to wait between two attempts. By default, it is clock.Real.
+func WithClock(c clock.Clock) Option {
+	return func(cfg *config) {
+		cfg.clock = clock.OrReal(c)
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		maxAttempts: defaultMaxAttempts,
+		backoff:     Exponential(defaultInitial, defaultMax, 2),
+		retryIf: func(_ error) bool {
+			return true
+		},
+		clock: clock.Real,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Do calls the function until it succeeds, the error is not retryable, the maximum number of attempts is reached, or the context is done.
+// It returns nil if the function succeeded, otherwise the last error returned by the function.
+func Do(ctx context.Context, f func(ctx context.Context) error, opts ...Option) error {
+	_, err := DoValue(ctx, func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, f(ctx)
+	}, opts...)
+	return err
+}
+
+// DoValue is the equivalent of Do for a function returning a value.
+func DoValue[T any](ctx context.Context, f func(ctx context.Context) (T, error), opts ...Option) (T, error) {
+	c := newConfig(opts)
+	backoff := c.backoff
+	if stateful, ok := backoff.(StatefulBackoff); ok {
+		backoff = stateful.Fresh()
+	}
+	start := c.clock.Now()
+	var zero T
+	for attempt := 1; ; attempt++ {
+		result, err := f(ctx)
+		if err == nil {
+			if c.budget != nil {
+				c.budget.Deposit()
+			}
+			return result, nil
+		}
+		if !c.retryIf(err) {
+			return zero, err
+		}
+		if c.maxAttempts > 0 && attempt >= c.maxAttempts {
+			return zero, fmt.Errorf("giving up after %d attempts: %w", attempt, err)
+		}
+		delay := backoff.Next(attempt)
+		if c.maxElapsed > 0 && c.clock.Since(start)+delay > c.maxElapsed {
+			return zero, fmt.Errorf("giving up after %d attempts, the maximum elapsed time %s is reached: %w", attempt, c.maxElapsed, err)
+		}
+		// the token is only taken once the retry is certain to happen.
+		if c.budget != nil && !c.budget.Withdraw() {
+			return zero, &budgetError{attempts: attempt, err: err}
+		}
+		if c.onRetry != nil {
+			c.onRetry(attempt, err, delay)
+		}
+		timer := c.clock.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, &cancelledError{ctxErr: ctx.Err(), attempts: attempt, err: err}
+		case <-timer.C():
+		}
+	}
+}
+
+// cancelledError is returned when the context is done while waiting for the next attempt.
+// errors.Is reports it as the error of the context, and errors.Unwrap gives back the last error of the function.
+type cancelledError struct {
+	ctxErr   error
+	attempts int
+	err      error
+}
+
+func (e *cancelledError) Error() string {
+	return fmt.Sprintf("%s after %d attempts, last error: %s", e.ctxErr, e.attempts, e.err)
+}
+
+func (e *cancelledError) Unwrap() error {
+	return e.err
+}
+
+func (e *cancelledError) Is(target error) bool {
+	return errors.Is(e.ctxErr, target)
+}
+
+// Wrap returns a function calling f through DoValue. It is the way to make a future retrying its work:
+//
+//	future := async.AsyncErrWithContext(ctx, retry.Wrap(f))
+func Wrap[T any](f func(ctx context.Context) (T, error), opts ...Option) func(ctx context.Context) (T, error) {
+	return func(ctx context.Context) (T, error) {
+		return DoValue(ctx, f, opts...)
+	}
+}
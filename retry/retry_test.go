@@ -0,0 +1,128 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient error")
+
+func TestDo(t *testing.T) {
+	attempts := 0
+	err := Do(context.Background(), func(_ context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return errTransient
+		}
+		return nil
+	}, WithBackoff(Constant(time.Millisecond)))
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func TestDo_MaxAttempts(t *testing.T) {
+	attempts := 0
+	err := Do(context.Background(), func(_ context.Context) error {
+		attempts++
+		return errTransient
+	}, WithBackoff(Constant(time.Millisecond)), WithMaxAttempts(4))
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 4, attempts)
+}
+
+func TestDo_RetryIf(t *testing.T) {
+	attempts := 0
+	permanent := fmt.Errorf("permanent error")
+	err := Do(context.Background(), func(_ context.Context) error {
+		attempts++
+		return permanent
+	}, WithRetryIf(func(err error) bool {
+		return errors.Is(err, errTransient)
+	}))
+	assert.Equal(t, permanent, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestWrap(t *testing.T) {
+	attempts := 0
+	f := Wrap(func(_ context.Context) (int, error) {
+		attempts++
+		if attempts < 2 {
+			return 0, errTransient
+		}
+		return 42, nil
+	}, WithBackoff(Constant(time.Millisecond)))
+	result, err := f(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 42, result)
+}
+
+func TestExponential(t *testing.T) {
+	backoff := Exponential(100*time.Millisecond, time.Second, 2)
+	assert.Equal(t, 100*time.Millisecond, backoff.Next(1))
+	assert.Equal(t, 400*time.Millisecond, backoff.Next(3))
+	assert.Equal(t, time.Second, backoff.Next(10))
+}
+
+func TestDo_MaxElapsedTime(t *testing.T) {
+	attempts := 0
+	err := Do(context.Background(), func(_ context.Context) error {
+		attempts++
+		return errTransient
+	}, WithBackoff(Constant(30*time.Millisecond)), WithMaxAttempts(0), WithMaxElapsedTime(50*time.Millisecond))
+	assert.ErrorIs(t, err, errTransient)
+	// the third attempt would have started after 60ms
+	assert.Equal(t, 2, attempts)
+}
+
+func TestDo_ContextCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	attempts := 0
+	err := Do(ctx, func(_ context.Context) error {
+		attempts++
+		cancel()
+		return errTransient
+	}, WithBackoff(Constant(time.Hour)))
+	// the cancellation and the last error of the function are both kept
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.ErrorIs(t, err, errTransient)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestFullJitter(t *testing.T) {
+	backoff := FullJitter(Constant(100 * time.Millisecond))
+	for i := 0; i < 100; i++ {
+		delay := backoff.Next(1)
+		assert.True(t, delay >= 0 && delay < 100*time.Millisecond)
+	}
+}
+
+func TestDecorrelatedJitter(t *testing.T) {
+	backoff := DecorrelatedJitter(10*time.Millisecond, time.Second).(StatefulBackoff).Fresh()
+	previous := 10 * time.Millisecond
+	for attempt := 1; attempt <= 20; attempt++ {
+		delay := backoff.Next(attempt)
+		assert.True(t, delay >= 10*time.Millisecond)
+		assert.True(t, delay <= 3*previous)
+		assert.True(t, delay <= time.Second)
+		previous = delay
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"context"
+	"sync"
+)
+
+// Latch lets some go-routines wait until a given number of events happened, like every cache being warmed before serving.
+// Unlike a sync.WaitGroup, the waiting can be stopped with a context and the count is fixed at creation.
+type Latch struct {
+	mutex sync.Mutex
+	count int
+	// done is closed once the count reaches 0.
+	done chan struct{}
+}
+
+// NewLatch creates a Latch released once CountDown has been called count times.
+func NewLatch(count int) *Latch {
+	l := &Latch{count: count, done: make(chan struct{})}
+	if count <= 0 {
+		l.count = 0
+		close(l.done)
+	}
+	return l
+}
+
+// CountDown decrements the count, and releases the go-routines waiting once it reaches 0.
+// Calling it once the latch is released has no effect.
+func (l *Latch) CountDown() {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if l.count == 0 {
+		return
+	}
+	l.count--
+	if l.count == 0 {
+		close(l.done)
+	}
+}
+
+// Count returns the number of CountDown calls still expected.
+func (l *Latch) Count() int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	return l.count
+}
+
+// Done returns a channel closed once the latch is released.
+func (l *Latch) Done() <-chan struct{} {
+	return l.done
+}
+
+// Wait blocks until the latch is released or until the context is done, in which case it returns the error of the context.
+func (l *Latch) Wait(ctx context.Context) error {
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Barrier lets a fixed number of go-routines wait for each other: each one calling Wait is blocked until all of them called it.
+// The barrier is then reset, so it can be used again for the next phase.
+type Barrier struct {
+	parties int
+	mutex   sync.Mutex
+	waiting int
+	// release is closed once every party is waiting. A new one is created for the next phase.
+	release chan struct{}
+}
+
+// NewBarrier creates a Barrier for the given number of parties. parties is at least 1.
+func NewBarrier(parties int) *Barrier {
+	if parties < 1 {
+		parties = 1
+	}
+	return &Barrier{parties: parties, release: make(chan struct{})}
+}
+
+// Wait blocks until every party called Wait or until the context is done.
+// When the context is done, the caller stops waiting and is no longer counted, and the error of the context is returned.
+func (b *Barrier) Wait(ctx context.Context) error {
+	b.mutex.Lock()
+	b.waiting++
+	if b.waiting == b.parties {
+		close(b.release)
+		b.release = make(chan struct{})
+		b.waiting = 0
+		b.mutex.Unlock()
+		return nil
+	}
+	release := b.release
+	b.mutex.Unlock()
+	select {
+	case <-release:
+		return nil
+	case <-ctx.Done():
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+		select {
+		case <-release:
+			// the last party arrived in the meantime.
+			return nil
+		default:
+		}
+		b.waiting--
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLatch(t *testing.T) {
+	l := NewLatch(2)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, l.Wait(ctx), context.DeadlineExceeded)
+	l.CountDown()
+	assert.Equal(t, 1, l.Count())
+	go l.CountDown()
+	assert.NoError(t, l.Wait(context.Background()))
+	l.CountDown()
+	assert.Equal(t, 0, l.Count())
+	assert.NoError(t, NewLatch(0).Wait(context.Background()))
+}
+
+func TestBarrier(t *testing.T) {
+	b := NewBarrier(3)
+	var phase int32
+	wg := &sync.WaitGroup{}
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		go func() {
+			defer wg.Done()
+			for p := int32(1); p <= 2; p++ {
+				atomic.AddInt32(&phase, 1)
+				assert.NoError(t, b.Wait(context.Background()))
+				// nobody goes to the next phase before every party ended the current one
+				assert.GreaterOrEqual(t, atomic.LoadInt32(&phase), 3*p)
+				assert.NoError(t, b.Wait(context.Background()))
+			}
+		}()
+	}
+	wg.Wait()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, b.Wait(ctx), context.DeadlineExceeded)
+	assert.Equal(t, 0, b.waiting)
+}
@@ -0,0 +1,117 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+type lazyCall[T any] struct {
+	// done is closed once value and err are set.
+	done  chan struct{}
+	value T
+	err   error
+	// waiters is the number of callers waiting for the computation. It is protected by the mutex of the Lazy.
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// Lazy holds a value computed the first time it is requested, like a compiled set of regex or a warmed client.
+// It is safe for concurrent use: the callers arriving while the value is computed wait for the same computation.
+type Lazy[T any] struct {
+	f       func(ctx context.Context) (T, error)
+	mutex   sync.Mutex
+	done    bool
+	value   T
+	pending *lazyCall[T]
+}
+
+// NewLazy creates a Lazy computing its value with f.
+func NewLazy[T any](f func(ctx context.Context) (T, error)) *Lazy[T] {
+	return &Lazy[T]{f: f}
+}
+
+// Get returns the value, computing it if it is not available yet.
+// The computation runs on its own context, so it isn't stopped when the caller that triggered it leaves while others are still waiting.
+// A caller whose context is done stops waiting and gets the error of its context. Once every caller has left,
+// the context of the computation is cancelled and the next call to Get starts a new one.
+// The values of the context of the caller are not given to the computation.
+// Only a successful computation is kept: if it fails, the error is given to the callers waiting and the next call to Get computes the value again.
+func (l *Lazy[T]) Get(ctx context.Context) (T, error) {
+	l.mutex.Lock()
+	if l.done {
+		value := l.value
+		l.mutex.Unlock()
+		return value, nil
+	}
+	c := l.pending
+	if c == nil {
+		var computeCtx context.Context
+		c = &lazyCall[T]{done: make(chan struct{})}
+		computeCtx, c.cancel = context.WithCancel(context.Background())
+		l.pending = c
+		go l.compute(computeCtx, c)
+	}
+	c.waiters++
+	l.mutex.Unlock()
+	select {
+	case <-c.done:
+		return c.value, c.err
+	case <-ctx.Done():
+		l.leave(c)
+		var zero T
+		return zero, ctx.Err()
+	}
+}
+
+// leave is called when a caller stops waiting for the computation. The last one to leave cancels it.
+func (l *Lazy[T]) leave(c *lazyCall[T]) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	c.waiters--
+	if c.waiters > 0 {
+		return
+	}
+	c.cancel()
+	if l.pending == c {
+		l.pending = nil
+	}
+}
+
+func (l *Lazy[T]) compute(ctx context.Context, c *lazyCall[T]) {
+	defer c.cancel()
+	value, err := l.call(ctx)
+	l.mutex.Lock()
+	if err == nil {
+		l.done = true
+		l.value = value
+	}
+	if l.pending == c {
+		l.pending = nil
+	}
+	l.mutex.Unlock()
+	c.value, c.err = value, err
+	close(c.done)
+}
+
+func (l *Lazy[T]) call(ctx context.Context) (value T, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return l.f(ctx)
+}
@@ -0,0 +1,96 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLazy(t *testing.T) {
+	var calls int32
+	l := NewLazy(func(_ context.Context) (int, error) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			return 0, fmt.Errorf("failure")
+		}
+		return 42, nil
+	})
+	_, err := l.Get(context.Background())
+	assert.EqualError(t, err, "failure")
+	for i := 0; i < 3; i++ {
+		value, err := l.Get(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 42, value)
+	}
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestLazy_ContextDone(t *testing.T) {
+	var calls int32
+	release := make(chan struct{})
+	l := NewLazy(func(ctx context.Context) (string, error) {
+		atomic.AddInt32(&calls, 1)
+		select {
+		case <-release:
+			return "value", nil
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	})
+	result := make(chan string)
+	go func() {
+		value, _ := l.Get(context.Background())
+		result <- value
+	}()
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := l.Get(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	// another caller is still waiting, so the computation goes on
+	close(release)
+	assert.Equal(t, "value", <-result)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestLazy_EveryCallerLeft(t *testing.T) {
+	cancelled := make(chan struct{})
+	l := NewLazy(func(ctx context.Context) (string, error) {
+		select {
+		case <-cancelled:
+			return "value", nil
+		case <-ctx.Done():
+			close(cancelled)
+			return "", ctx.Err()
+		}
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := l.Get(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	// the only caller has left, so the computation is cancelled
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("the computation has not been cancelled")
+	}
+	value, err := l.Get(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, "value", value)
+}
@@ -0,0 +1,223 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sync"
+	"unsafe"
+)
+
+const defaultShards = 32
+
+type shard[K comparable, V any] struct {
+	mutex  sync.RWMutex
+	values map[K]V
+}
+
+// Map is a typed map safe for concurrent use. It is split in shards, each one having its own lock,
+// so the writes on different keys rarely wait for each other.
+type Map[K comparable, V any] struct {
+	hash   func(key K) uint64
+	shards []*shard[K, V]
+}
+
+// NewMap creates a Map with the given number of shards, using hash to pick the shard of a key.
+// If shards is not strictly positive, 32 shards are used.
+// If hash is nil, a default hash is used. It spreads the keys among the shards when they are strings, numbers, booleans or pointers.
+// For any other type of key, like a struct, every key ends up in the same shard, so hash should be given.
+func NewMap[K comparable, V any](shards int, hash func(key K) uint64) *Map[K, V] {
+	if shards <= 0 {
+		shards = defaultShards
+	}
+	if hash == nil {
+		hash = defaultHash[K]()
+	}
+	m := &Map[K, V]{hash: hash, shards: make([]*shard[K, V], shards)}
+	for i := range m.shards {
+		m.shards[i] = &shard[K, V]{values: make(map[K]V)}
+	}
+	return m
+}
+
+// defaultHash picks the hash of the keys once, from their type. The integers, booleans and pointers are hashed from their bits,
+// the strings with StringHash. The named types are resolved from their underlying kind.
+// The keys of any other type, like the structs and the arrays, all have the same hash.
+func defaultHash[K comparable]() func(key K) uint64 {
+	var zero K
+	switch any(zero).(type) {
+	case string:
+		return as[K](StringHash)
+	case float32:
+		return as[K](func(f float32) uint64 { return floatHash(float64(f)) })
+	case float64:
+		return as[K](floatHash)
+	case int64, uint64:
+		return as[K](func(x uint64) uint64 { return mix(x) })
+	case int32, uint32:
+		return as[K](func(x uint32) uint64 { return mix(uint64(x)) })
+	}
+	keyType := reflect.TypeOf((*K)(nil)).Elem()
+	switch keyType.Kind() {
+	case reflect.String:
+		return as[K](StringHash)
+	case reflect.Float32:
+		return as[K](func(f float32) uint64 { return floatHash(float64(f)) })
+	case reflect.Float64:
+		return as[K](floatHash)
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr,
+		reflect.Ptr, reflect.UnsafePointer, reflect.Chan:
+		switch keyType.Size() {
+		case 1:
+			return as[K](func(x uint8) uint64 { return mix(uint64(x)) })
+		case 2:
+			return as[K](func(x uint16) uint64 { return mix(uint64(x)) })
+		case 4:
+			return as[K](func(x uint32) uint64 { return mix(uint64(x)) })
+		case 8:
+			return as[K](func(x uint64) uint64 { return mix(x) })
+		}
+	}
+	return func(_ K) uint64 {
+		return 0
+	}
+}
+
+// as returns a hash reading the key as a T. T must have the same memory layout as K.
+func as[K comparable, T any](hash func(value T) uint64) func(key K) uint64 {
+	return func(key K) uint64 {
+		return hash(*(*T)(unsafe.Pointer(&key)))
+	}
+}
+
+// floatHash hashes the bits of the float. The zeros are equal whatever their sign, so they have the same hash.
+func floatHash(f float64) uint64 {
+	if f == 0 {
+		return 0
+	}
+	return mix(math.Float64bits(f))
+}
+
+// mix spreads the bits of the integer, so the consecutive integers and the aligned addresses don't end up in the same shard.
+func mix(x uint64) uint64 {
+	x ^= x >> 33
+	x *= 0xff51afd7ed558ccd
+	x ^= x >> 33
+	x *= 0xc4ceb9fe1a85ec53
+	x ^= x >> 33
+	return x
+}
+
+// StringHash is the FNV-1a hash of the string. It can be given to NewMap for a Map having string keys.
+func StringHash(key string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return h.Sum64()
+}
+
+func (m *Map[K, V]) shard(key K) *shard[K, V] {
+	return m.shards[m.hash(key)%uint64(len(m.shards))]
+}
+
+// Load returns the value stored for the key and true, or false if there is none.
+func (m *Map[K, V]) Load(key K) (V, bool) {
+	s := m.shard(key)
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// Store sets the value for the key.
+func (m *Map[K, V]) Store(key K, value V) {
+	s := m.shard(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.values[key] = value
+}
+
+// Delete removes the key.
+func (m *Map[K, V]) Delete(key K) {
+	s := m.shard(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.values, key)
+}
+
+// LoadOrStore returns the value already stored for the key and true. Otherwise, it stores the value given and returns it with false.
+func (m *Map[K, V]) LoadOrStore(key K, value V) (V, bool) {
+	s := m.shard(key)
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if existing, ok := s.values[key]; ok {
+		return existing, true
+	}
+	s.values[key] = value
+	return value, false
+}
+
+// GetOrCompute returns the value stored for the key. Otherwise, it stores and returns the value computed by f.
+// f is called at most once per key, with the lock of the shard held: it must be fast and must not use the Map.
+func (m *Map[K, V]) GetOrCompute(key K, f func() V) V {
+	s := m.shard(key)
+	s.mutex.RLock()
+	value, ok := s.values[key]
+	s.mutex.RUnlock()
+	if ok {
+		return value
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if value, ok = s.values[key]; ok {
+		return value
+	}
+	value = f()
+	s.values[key] = value
+	return value
+}
+
+// Range calls f for each key and value, until f returns false.
+// Each shard is locked while it is iterated, so f must not modify the Map.
+func (m *Map[K, V]) Range(f func(key K, value V) bool) {
+	for _, s := range m.shards {
+		if !s.iterate(f) {
+			return
+		}
+	}
+}
+
+// Len returns the number of keys.
+func (m *Map[K, V]) Len() int {
+	n := 0
+	for _, s := range m.shards {
+		s.mutex.RLock()
+		n += len(s.values)
+		s.mutex.RUnlock()
+	}
+	return n
+}
+
+func (s *shard[K, V]) iterate(f func(key K, value V) bool) bool {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	for key, value := range s.values {
+		if !f(key, value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,119 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMap(t *testing.T) {
+	m := NewMap[string, int](4, StringHash)
+	m.Store("a", 1)
+	value, ok := m.Load("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	value, loaded := m.LoadOrStore("a", 2)
+	assert.True(t, loaded)
+	assert.Equal(t, 1, value)
+	value, loaded = m.LoadOrStore("b", 2)
+	assert.False(t, loaded)
+	assert.Equal(t, 2, value)
+	assert.Equal(t, 2, m.Len())
+
+	sum := 0
+	m.Range(func(_ string, value int) bool {
+		sum += value
+		return true
+	})
+	assert.Equal(t, 3, sum)
+	m.Delete("a")
+	_, ok = m.Load("a")
+	assert.False(t, ok)
+}
+
+func TestMap_GetOrCompute(t *testing.T) {
+	type key struct {
+		id   int
+		name string
+	}
+	m := NewMap[key, int](0, func(k key) uint64 {
+		return StringHash(k.name)
+	})
+	var calls int32
+	wg := &sync.WaitGroup{}
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		go func() {
+			defer wg.Done()
+			value := m.GetOrCompute(key{id: 1, name: "a"}, func() int {
+				atomic.AddInt32(&calls, 1)
+				return 42
+			})
+			assert.Equal(t, 42, value)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&calls))
+}
+
+func TestMap_DefaultHash(t *testing.T) {
+	first, second := 1, 1
+	pointers := NewMap[*int, string](0, nil)
+	pointers.Store(&first, "first")
+	pointers.Store(&second, "second")
+	// the pointers are different keys even if the values pointed are equal
+	assert.Equal(t, 2, pointers.Len())
+	value, ok := pointers.Load(&second)
+	assert.True(t, ok)
+	assert.Equal(t, "second", value)
+
+	type id uint16
+	ids := NewMap[id, int](0, nil)
+	for i := 0; i < 100; i++ {
+		ids.Store(id(i), i)
+	}
+	assert.Equal(t, 100, ids.Len())
+	names := NewMap[string, int](0, nil)
+	names.Store("a", 1)
+	count, ok := names.Load("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, count)
+
+	zeros := NewMap[float64, string](0, nil)
+	zeros.Store(0.0, "zero")
+	value, ok = zeros.Load(math.Copysign(0, -1))
+	assert.True(t, ok)
+	assert.Equal(t, "zero", value)
+
+	type key struct {
+		id   int
+		name string
+	}
+	keys := NewMap[key, int](0, nil)
+	keys.Store(key{id: 1, name: "a"}, 1)
+	keys.Store(key{id: 2, name: "b"}, 2)
+	count, ok = keys.Load(key{id: 2, name: "b"})
+	assert.True(t, ok)
+	assert.Equal(t, 2, count)
+	flags := NewMap[bool, int](0, nil)
+	flags.Store(true, 1)
+	count, ok = flags.Load(true)
+	assert.True(t, ok)
+	assert.Equal(t, 1, count)
+}
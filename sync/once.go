@@ -0,0 +1,75 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/perses/common/retry"
+)
+
+// OnceWithRetry executes an initialization function until it succeeds once.
+// Unlike sync.Once, a failed initialization is not cached: the next call to Do tries again.
+// The zero value retries immediately, NewOnceWithRetry is able to wait between two attempts.
+type OnceWithRetry struct {
+	// done is set atomically once the function succeeded.
+	done    uint32
+	mutex   sync.Mutex
+	backoff retry.Backoff
+	// failures is the number of attempts that failed in a row.
+	failures int
+	// retryAt is the time before which the function is not called again, the last error being returned instead.
+	retryAt time.Time
+	lastErr error
+}
+
+// NewOnceWithRetry creates a OnceWithRetry waiting between two attempts according to the backoff.
+// A call to Do happening before the end of the delay returns the last error without calling the function.
+func NewOnceWithRetry(backoff retry.Backoff) *OnceWithRetry {
+	return &OnceWithRetry{backoff: backoff}
+}
+
+// Do calls f if no previous call succeeded, and returns its error. Once f succeeded, Do returns nil without calling it anymore.
+// The calls are serialized: a concurrent call waits for the attempt in progress and benefits from its success.
+func (o *OnceWithRetry) Do(f func() error) error {
+	if atomic.LoadUint32(&o.done) == 1 {
+		return nil
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	if o.done == 1 {
+		return nil
+	}
+	if o.lastErr != nil && time.Now().Before(o.retryAt) {
+		return o.lastErr
+	}
+	if err := f(); err != nil {
+		o.failures++
+		o.lastErr = err
+		if o.backoff != nil {
+			o.retryAt = time.Now().Add(o.backoff.Next(o.failures))
+		}
+		return err
+	}
+	o.lastErr = nil
+	atomic.StoreUint32(&o.done, 1)
+	return nil
+}
+
+// Done returns true once the function succeeded.
+func (o *OnceWithRetry) Done() bool {
+	return atomic.LoadUint32(&o.done) == 1
+}
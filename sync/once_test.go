@@ -0,0 +1,61 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/perses/common/retry"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOnceWithRetry(t *testing.T) {
+	o := &OnceWithRetry{}
+	calls := 0
+	init := func() error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("failure %d", calls)
+		}
+		return nil
+	}
+	assert.EqualError(t, o.Do(init), "failure 1")
+	assert.EqualError(t, o.Do(init), "failure 2")
+	assert.False(t, o.Done())
+	assert.NoError(t, o.Do(init))
+	assert.NoError(t, o.Do(init))
+	assert.True(t, o.Done())
+	assert.Equal(t, 3, calls)
+}
+
+func TestOnceWithRetry_Backoff(t *testing.T) {
+	o := NewOnceWithRetry(retry.Constant(20 * time.Millisecond))
+	calls := 0
+	init := func() error {
+		calls++
+		if calls == 1 {
+			return fmt.Errorf("failure")
+		}
+		return nil
+	}
+	assert.Error(t, o.Do(init))
+	// during the delay, the last error is returned without calling the function
+	assert.Error(t, o.Do(init))
+	assert.Equal(t, 1, calls)
+	time.Sleep(25 * time.Millisecond)
+	assert.NoError(t, o.Do(init))
+	assert.Equal(t, 2, calls)
+}
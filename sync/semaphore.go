@@ -0,0 +1,122 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sync provides synchronization primitives complementing the ones of the standard library, all of them aware of the context.
+package sync
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+type waiter struct {
+	n int64
+	// ready is closed once the weight has been acquired for the waiter.
+	ready chan struct{}
+}
+
+// Weighted is a semaphore where each caller acquires a weight, so a caller can reserve a bigger share of the resource than another.
+// The callers waiting are served in order: a big weight waiting is not starved by smaller ones arriving after it.
+type Weighted struct {
+	size    int64
+	mutex   sync.Mutex
+	current int64
+	waiters list.List
+}
+
+// NewWeighted creates a Weighted semaphore with the given maximum combined weight.
+func NewWeighted(size int64) *Weighted {
+	return &Weighted{size: size}
+}
+
+// Acquire acquires the weight n, blocking until it is available or until the context is done.
+// On failure, it returns the error of the context and nothing is acquired.
+func (s *Weighted) Acquire(ctx context.Context, n int64) error {
+	s.mutex.Lock()
+	if s.size-s.current >= n && s.waiters.Len() == 0 {
+		s.current += n
+		s.mutex.Unlock()
+		return nil
+	}
+	if n > s.size {
+		// this weight can never be acquired, no need to wait in the queue.
+		s.mutex.Unlock()
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	ready := make(chan struct{})
+	element := s.waiters.PushBack(waiter{n: n, ready: ready})
+	s.mutex.Unlock()
+
+	select {
+	case <-ready:
+		return nil
+	case <-ctx.Done():
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		select {
+		case <-ready:
+			// the weight has been acquired right after the context was done, it is kept rather than released.
+			return nil
+		default:
+		}
+		isFront := s.waiters.Front() == element
+		s.waiters.Remove(element)
+		if isFront && s.size > s.current {
+			// this waiter may have blocked smaller ones behind it.
+			s.notifyWaiters()
+		}
+		return ctx.Err()
+	}
+}
+
+// TryAcquire acquires the weight n without blocking. It returns false if it is not available, in which case nothing is acquired.
+func (s *Weighted) TryAcquire(n int64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.size-s.current >= n && s.waiters.Len() == 0 {
+		s.current += n
+		return true
+	}
+	return false
+}
+
+// Release releases the weight n. It panics when releasing more than what is held.
+func (s *Weighted) Release(n int64) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.current -= n
+	if s.current < 0 {
+		panic("semaphore: released more than held")
+	}
+	s.notifyWaiters()
+}
+
+// notifyWaiters gives the weight available to the waiters, in order. It must be called with the mutex held.
+func (s *Weighted) notifyWaiters() {
+	for {
+		next := s.waiters.Front()
+		if next == nil {
+			return
+		}
+		w := next.Value.(waiter)
+		if s.size-s.current < w.n {
+			// the first waiter is served first, even if a waiter behind it needs less.
+			return
+		}
+		s.current += w.n
+		s.waiters.Remove(next)
+		close(w.ready)
+	}
+}
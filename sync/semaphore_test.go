@@ -0,0 +1,68 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWeighted(t *testing.T) {
+	s := NewWeighted(3)
+	assert.NoError(t, s.Acquire(context.Background(), 2))
+	assert.True(t, s.TryAcquire(1))
+	assert.False(t, s.TryAcquire(1))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, s.Acquire(ctx, 1), context.DeadlineExceeded)
+
+	acquired := make(chan struct{})
+	go func() {
+		_ = s.Acquire(context.Background(), 3)
+		close(acquired)
+	}()
+	s.Release(1)
+	select {
+	case <-acquired:
+		t.Fatal("the weight must not be acquired while some is still held")
+	case <-time.After(10 * time.Millisecond):
+	}
+	s.Release(2)
+	<-acquired
+	assert.Panics(t, func() { s.Release(4) })
+}
+
+func TestWeighted_Order(t *testing.T) {
+	s := NewWeighted(2)
+	assert.NoError(t, s.Acquire(context.Background(), 2))
+	big := make(chan struct{})
+	go func() {
+		_ = s.Acquire(context.Background(), 2)
+		close(big)
+	}()
+	// wait for the big weight to be in the queue
+	assert.Eventually(t, func() bool {
+		s.mutex.Lock()
+		defer s.mutex.Unlock()
+		return s.waiters.Len() == 1
+	}, time.Second, time.Millisecond)
+	// a small weight must not overtake the big one waiting
+	assert.False(t, s.TryAcquire(1))
+	s.Release(2)
+	<-big
+}
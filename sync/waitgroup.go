@@ -0,0 +1,125 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// WaitGroup runs functions in their own go-routine and waits for them, like a sync.WaitGroup combined with the errors of the functions.
+// Unlike a sync.WaitGroup, the waiting can be stopped with a context, so a stuck go-routine doesn't block the caller forever.
+// The zero value is ready to use.
+type WaitGroup struct {
+	wg    sync.WaitGroup
+	mutex sync.Mutex
+	errs  []error
+}
+
+// Go runs f in a new go-routine. Its error is collected and returned by Wait. A panic is recovered and collected as an error.
+func (g *WaitGroup) Go(f func() error) {
+	g.wg.Add(1)
+	go func() {
+		defer g.wg.Done()
+		if err := g.call(f); err != nil {
+			g.mutex.Lock()
+			g.errs = append(g.errs, err)
+			g.mutex.Unlock()
+		}
+	}()
+}
+
+// Wait blocks until every function ended or until the context is done.
+// It returns the errors of the functions joined in a single error, or nil if none failed.
+// When the context is done first, the error of the context is joined to the errors of the functions already ended.
+func (g *WaitGroup) Wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+	var ctxErr error
+	select {
+	case <-done:
+	case <-ctx.Done():
+		ctxErr = ctx.Err()
+	}
+	g.mutex.Lock()
+	errs := make([]error, len(g.errs), len(g.errs)+1)
+	copy(errs, g.errs)
+	g.mutex.Unlock()
+	if ctxErr != nil {
+		errs = append(errs, ctxErr)
+	}
+	return join(errs)
+}
+
+func (g *WaitGroup) call(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return f()
+}
+
+// joinedError holds several errors. It implements the methods Is and As, so errors.Is and errors.As look into each error.
+type joinedError []error
+
+func (j joinedError) Error() string {
+	messages := make([]string, 0, len(j))
+	for _, err := range j {
+		messages = append(messages, err.Error())
+	}
+	return strings.Join(messages, "\n")
+}
+
+func (j joinedError) Unwrap() []error {
+	return j
+}
+
+// Is reports whether one of the errors matches the target. It lets errors.Is look into each error, Unwrap() []error being followed by errors.Is only from Go 1.20.
+func (j joinedError) Is(target error) bool {
+	for _, err := range j {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// As finds the first error matching the target, see errors.As.
+func (j joinedError) As(target interface{}) bool {
+	for _, err := range j {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// join returns nil when there is no error, the error itself when there is only one, and a joinedError otherwise.
+func join(errs []error) error {
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	default:
+		return joinedError(errs)
+	}
+}
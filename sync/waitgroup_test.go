@@ -0,0 +1,68 @@
+// Copyright The Perses Authors
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+// http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitGroup(t *testing.T) {
+	failure := fmt.Errorf("failure")
+	g := &WaitGroup{}
+	g.Go(func() error { return nil })
+	g.Go(func() error { return failure })
+	g.Go(func() error { panic("boom") })
+	err := g.Wait(context.Background())
+	assert.ErrorIs(t, err, failure)
+	assert.Contains(t, err.Error(), "panic: boom")
+
+	assert.NoError(t, (&WaitGroup{}).Wait(context.Background()))
+}
+
+func TestWaitGroup_ContextDone(t *testing.T) {
+	block := make(chan struct{})
+	defer close(block)
+	g := &WaitGroup{}
+	g.Go(func() error {
+		<-block
+		return nil
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, g.Wait(ctx), context.DeadlineExceeded)
+}
+
+type codeError struct {
+	code int
+}
+
+func (c *codeError) Error() string {
+	return fmt.Sprintf("code %d", c.code)
+}
+
+func TestJoinedError(t *testing.T) {
+	failure := fmt.Errorf("failure")
+	err := join([]error{fmt.Errorf("wrapped: %w", failure), &codeError{code: 42}})
+	assert.ErrorIs(t, err, failure)
+	var coded *codeError
+	assert.True(t, errors.As(err, &coded))
+	assert.Equal(t, 42, coded.code)
+	assert.False(t, errors.Is(err, context.Canceled))
+}